@@ -6,10 +6,17 @@
 //   |-> TEST_NAME.log
 //   |-> summary.log
 //   |-> report.xml
+//   |-> report.html
 // where:
 // - `TEST_NAME.log` is a log for each test run that only includes the relevant logs for that test.
 // - `summary.log` is a summary of all the tests in the suite, including PASS/FAIL information.
 // - `report.xml` is the test summary in junit XML format to be consumed by a CI engine.
+// - `report.html` is a self-contained HTML report with the result and duration of every test and its log, with the
+//   errors, panics and test stages highlighted, for humans to triage test runs.
+//
+// With `--json`, the input is the output of `go test -json` instead. Since every event names the test it belongs to,
+// this avoids the parsing heuristics described below and attributes the output of parallel tests exactly. The junit
+// report then also counts skipped tests and nests subtests in the testcase of their parent.
 //
 // Certain tradeoffs were made in the decision to implement this functionality as a separate parsing command, as opposed
 // to being built into the logger module as part of `Logf`. Specifically, this implementation avoids the difficulties of
@@ -49,13 +56,14 @@ import (
 
 var logger = logging.GetLogger("terratest_log_parser")
 
-const customUsageText = `Usage: terratest_log_parser [--help] [--log-level=info] [--testlog=LOG_INPUT] [--outputdir=OUTPUT_DIR]
+const customUsageText = `Usage: terratest_log_parser [--help] [--log-level=info] [--json] [--testlog=LOG_INPUT] [--outputdir=OUTPUT_DIR]
 
 A tool for parsing parallel terratest output to produce a test summary and to break out the interleaved logs by test for better debuggability.
 
 Options:
    --log-level LEVEL  Set the log level to LEVEL. Must be one of: [panic fatal error warning info debug]
                       (default: "info")
+   --json             Parse the output of go test -json instead of go test -v.
    --testlog value    Path to file containing test log. If unset will use stdin.
    --outputdir value  Path to directory to output test output to. If unset will use the current directory.
    --help, -h         show help
@@ -95,7 +103,11 @@ func run(cliContext *cli.Context) error {
 		logger.Fatalf("Error extracting absolute path of output directory: %s", err)
 	}
 
-	parser.SpawnParsers(logger, file, outputDir)
+	if cliContext.Bool("json") {
+		parser.SpawnJSONParsers(logger, file, outputDir)
+	} else {
+		parser.SpawnParsers(logger, file, outputDir)
+	}
 
 	return nil
 }
@@ -132,8 +144,13 @@ func main() {
 		Value: logrus.InfoLevel.String(),
 		Usage: fmt.Sprintf("Set the log level to `LEVEL`. Must be one of: %v", logrus.AllLevels),
 	}
+	jsonFlag := cli.BoolFlag{
+		Name:  "json",
+		Usage: "Parse the output of go test -json instead of go test -v.",
+	}
 	app.Flags = []cli.Flag{
 		logLevelFlag,
+		jsonFlag,
 		logInputFlag,
 		outputDirFlag,
 	}
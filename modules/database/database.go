@@ -63,10 +63,13 @@ func DBConnectionE(t testing.TestingT, dbType string, dbConfig DBConfig) (*sql.D
 }
 
 // DBConnectionWithContextE connects to the database using database configuration and database type, i.e. mssql.
-// Returns the database or an error.
+// Returns the database or an error. The password is registered with logger.RegisterSecrets, so that it is redacted from
+// the logs of all the tests in the process.
 func DBConnectionWithContextE(t testing.TestingT, ctx context.Context, dbType string, dbConfig *DBConfig) (*sql.DB, error) {
 	t.Helper()
 
+	logger.RegisterSecrets(dbConfig.Password)
+
 	var config string
 
 	switch dbType {
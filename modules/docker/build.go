@@ -56,6 +56,10 @@ type BuildOptions struct {
 	// included in the Architectures list.
 	Load bool
 
+	// Backend selects how to talk to the Docker daemon. With BackendEngineAPI, the build context is sent to the daemon
+	// directly and the docker CLI is not needed, but Architectures, OtherOptions and EnableBuildKit are not supported.
+	Backend Backend
+
 	// Whether ot not to enable buildkit. You can find more information about buildkit here https://docs.docker.com/build/buildkit/#getting-started.
 	EnableBuildKit bool
 }
@@ -86,6 +90,10 @@ func BuildE(t testing.TestingT, path string, options *BuildOptions) error {
 func BuildContextE(t testing.TestingT, ctx context.Context, path string, options *BuildOptions) error {
 	options.Logger.Logf(t, "Running 'docker build' in %s", path)
 
+	if options.Backend == BackendEngineAPI {
+		return buildWithEngineAPIE(t, ctx, path, options)
+	}
+
 	env := make(map[string]string)
 	if options.Env != nil {
 		env = options.Env
@@ -199,7 +207,7 @@ func GitCloneAndBuildContextE(
 
 	defer func() {
 		if removeErr := os.RemoveAll(workingDir); removeErr != nil {
-			dockerBuildOpts.Logger.Logf(t, "WARNING: failed to remove temp dir %s: %v", workingDir, removeErr)
+			dockerBuildOpts.Logger.Warnf(t, "failed to remove temp dir %s: %v", workingDir, removeErr)
 		}
 	}()
 
@@ -0,0 +1,536 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// DefaultContainerWaitTimeout is the default time to wait for a container to become ready.
+	DefaultContainerWaitTimeout = 1 * time.Minute
+
+	// DefaultContainerWaitInterval is the default time to sleep between readiness checks.
+	DefaultContainerWaitInterval = 1 * time.Second
+
+	// containerProbeTimeout is the timeout of a single TCP or HTTP readiness probe.
+	containerProbeTimeout = 5 * time.Second
+)
+
+// ErrPortNotPublished is returned when a container port is not published on the host.
+var ErrPortNotPublished = errors.New("container port is not published")
+
+// ContainerOptions defines options that can be passed to StartContainerContextE.
+type ContainerOptions struct {
+	// WaitFor lists the strategies that must all report the container as ready before StartContainerContextE returns.
+	WaitFor []WaitStrategy
+
+	// RunOptions to start the container with. The container is always run detached.
+	RunOptions
+
+	// Maximum time to wait for the container to become ready. Defaults to DefaultContainerWaitTimeout.
+	WaitTimeout time.Duration
+
+	// Time to sleep between readiness checks. Defaults to DefaultContainerWaitInterval.
+	WaitInterval time.Duration
+
+	// StopTimeout is the number of seconds to wait for the container to stop before killing it during cleanup. Defaults
+	// to the daemon default.
+	StopTimeout int
+
+	// If set to true, the container is not stopped and removed when the test finishes.
+	KeepContainer bool
+}
+
+// Container is a handle to a running container started by StartContainerContextE.
+type Container struct {
+	options *ContainerOptions
+
+	// ID of the container
+	ID string
+
+	// Image the container was started from
+	Image string
+}
+
+// WaitStrategy decides when a container is ready to be used by a test.
+type WaitStrategy interface {
+	// CheckReadyContextE returns nil if the container is ready, or an error explaining why it is not. Returning a
+	// retry.FatalError stops waiting immediately.
+	CheckReadyContextE(t testing.TestingT, ctx context.Context, container *Container) error
+
+	// String describes the strategy for log messages.
+	String() string
+}
+
+// StartContainerContextE runs the given image in the background with the given options, waits until all the
+// options.WaitFor strategies report the container as ready, and returns a handle to the container. If t supports
+// Cleanup, such as *testing.T, the container is stopped and removed when the test finishes, unless
+// options.KeepContainer is set. The ctx parameter supports cancellation and timeouts.
+func StartContainerContextE(t testing.TestingT, ctx context.Context, image string, options *ContainerOptions) (*Container, error) {
+	runOptions := options.RunOptions
+	runOptions.Detach = true
+
+	id, err := RunAndGetIDContextE(t, ctx, image, &runOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	container := &Container{ID: id, Image: image, options: options}
+
+	if c, ok := t.(testing.Cleaner); ok && !options.KeepContainer {
+		c.Cleanup(func() {
+			// The test context is already canceled when cleanup functions run
+			if err := container.TerminateContextE(t, context.Background()); err != nil {
+				options.Logger.Warnf(t, "failed to remove container %s: %v", id, err)
+			}
+		})
+	}
+
+	if err := container.WaitUntilReadyContextE(t, ctx, options.WaitFor...); err != nil {
+		return container, err
+	}
+
+	return container, nil
+}
+
+// StartContainerContext runs the given image in the background with the given options, waits until all the
+// options.WaitFor strategies report the container as ready, and returns a handle to the container. If t supports
+// Cleanup, the container is stopped and removed when the test finishes. This will fail the test if there are any
+// errors. The ctx parameter supports cancellation and timeouts.
+func StartContainerContext(t testing.TestingT, ctx context.Context, image string, options *ContainerOptions) *Container {
+	t.Helper()
+
+	container, err := StartContainerContextE(t, ctx, image, options)
+	require.NoError(t, err)
+
+	return container
+}
+
+// WaitUntilReadyContextE waits until all the given strategies report the container as ready, checking them in order.
+// It uses the WaitTimeout and WaitInterval from the container's options. The ctx parameter supports cancellation and
+// timeouts.
+func (container *Container) WaitUntilReadyContextE(t testing.TestingT, ctx context.Context, strategies ...WaitStrategy) error {
+	timeout := container.options.WaitTimeout
+	if timeout == 0 {
+		timeout = DefaultContainerWaitTimeout
+	}
+
+	interval := container.options.WaitInterval
+	if interval == 0 {
+		interval = DefaultContainerWaitInterval
+	}
+
+	maxRetries := int(timeout / interval)
+
+	for _, strategy := range strategies {
+		description := fmt.Sprintf("Waiting for container %s: %s", container.ID, strategy)
+
+		_, err := retry.DoWithRetryContextE(t, ctx, description, maxRetries, interval, func() (string, error) {
+			return "", strategy.CheckReadyContextE(t, ctx, container)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InspectContextE returns the inspect data of the container. The ctx parameter supports cancellation and timeouts.
+func (container *Container) InspectContextE(t testing.TestingT, ctx context.Context) (*ContainerInspect, error) {
+	return InspectWithOptionsContextE(t, ctx, container.ID, &InspectOptions{Backend: container.options.Backend})
+}
+
+// InspectContext returns the inspect data of the container. This will fail the test if there are any errors. The ctx
+// parameter supports cancellation and timeouts.
+func (container *Container) InspectContext(t testing.TestingT, ctx context.Context) *ContainerInspect {
+	t.Helper()
+
+	inspect, err := container.InspectContextE(t, ctx)
+	require.NoError(t, err)
+
+	return inspect
+}
+
+// HostPortContextE returns the host port that the given container port is published on. The returned error matches
+// ErrPortNotPublished if the port is not published. The ctx parameter supports cancellation and timeouts.
+func (container *Container) HostPortContextE(t testing.TestingT, ctx context.Context, containerPort uint16) (uint16, error) {
+	inspect, err := container.InspectContextE(t, ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	hostPort := inspect.GetExposedHostPort(containerPort)
+	if hostPort == 0 {
+		return 0, fmt.Errorf("%w: %d", ErrPortNotPublished, containerPort)
+	}
+
+	return hostPort, nil
+}
+
+// HostPortContext returns the host port that the given container port is published on. This will fail the test if
+// the port is not published. The ctx parameter supports cancellation and timeouts.
+func (container *Container) HostPortContext(t testing.TestingT, ctx context.Context, containerPort uint16) uint16 {
+	t.Helper()
+
+	hostPort, err := container.HostPortContextE(t, ctx, containerPort)
+	require.NoError(t, err)
+
+	return hostPort
+}
+
+// EndpointContextE returns the host:port address that the given container port can be reached on from the test. The
+// ctx parameter supports cancellation and timeouts.
+func (container *Container) EndpointContextE(t testing.TestingT, ctx context.Context, containerPort uint16) (string, error) {
+	hostPort, err := container.HostPortContextE(t, ctx, containerPort)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(GetDockerHost(), strconv.Itoa(int(hostPort))), nil
+}
+
+// EndpointContext returns the host:port address that the given container port can be reached on from the test. This
+// will fail the test if the port is not published. The ctx parameter supports cancellation and timeouts.
+func (container *Container) EndpointContext(t testing.TestingT, ctx context.Context, containerPort uint16) string {
+	t.Helper()
+
+	endpoint, err := container.EndpointContextE(t, ctx, containerPort)
+	require.NoError(t, err)
+
+	return endpoint
+}
+
+// ExecContextE runs the given command in the container and returns its combined stdout and stderr. The ctx parameter
+// supports cancellation and timeouts.
+func (container *Container) ExecContextE(t testing.TestingT, ctx context.Context, command ...string) (string, error) {
+	container.options.Logger.Logf(t, "Running %v in container %s", command, container.ID)
+
+	if container.options.Backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return "", err
+		}
+
+		return client.ExecContextE(ctx, container.ID, command)
+	}
+
+	cmd := &shell.Command{
+		Command: "docker",
+		Args:    append([]string{"exec", container.ID}, command...),
+		Logger:  container.options.Logger,
+	}
+
+	return shell.RunCommandContextAndGetOutputE(t, ctx, cmd)
+}
+
+// ExecContext runs the given command in the container and returns its combined stdout and stderr. This will fail the
+// test if the command fails. The ctx parameter supports cancellation and timeouts.
+func (container *Container) ExecContext(t testing.TestingT, ctx context.Context, command ...string) string {
+	t.Helper()
+
+	out, err := container.ExecContextE(t, ctx, command...)
+	require.NoError(t, err)
+
+	return out
+}
+
+// LogsContextE returns the combined stdout and stderr of the container so far. The ctx parameter supports
+// cancellation and timeouts.
+func (container *Container) LogsContextE(t testing.TestingT, ctx context.Context) (string, error) {
+	if container.options.Backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return "", err
+		}
+
+		return client.ContainerLogsContextE(ctx, container.ID, container.options.Tty)
+	}
+
+	cmd := &shell.Command{
+		Command: "docker",
+		Args:    []string{"logs", container.ID},
+		// Logs are often polled by wait strategies, don't print the output.
+		Logger: logger.Discard,
+	}
+
+	return shell.RunCommandContextAndGetOutputE(t, ctx, cmd)
+}
+
+// LogsContext returns the combined stdout and stderr of the container so far. This will fail the test if there are
+// any errors. The ctx parameter supports cancellation and timeouts.
+func (container *Container) LogsContext(t testing.TestingT, ctx context.Context) string {
+	t.Helper()
+
+	out, err := container.LogsContextE(t, ctx)
+	require.NoError(t, err)
+
+	return out
+}
+
+// CopyToContextE copies the local file or directory at src into the directory destDir in the container. The ctx
+// parameter supports cancellation and timeouts.
+func (container *Container) CopyToContextE(t testing.TestingT, ctx context.Context, src string, destDir string) error {
+	container.options.Logger.Logf(t, "Copying %s to %s in container %s", src, destDir, container.ID)
+
+	if container.options.Backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return err
+		}
+
+		return client.CopyToContainerContextE(ctx, container.ID, src, destDir)
+	}
+
+	cmd := &shell.Command{
+		Command: "docker",
+		Args:    []string{"cp", src, container.ID + ":" + withTrailingSlash(destDir)},
+		Logger:  container.options.Logger,
+	}
+
+	return shell.RunCommandContextE(t, ctx, cmd)
+}
+
+// CopyToContext copies the local file or directory at src into the directory destDir in the container. This will fail
+// the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func (container *Container) CopyToContext(t testing.TestingT, ctx context.Context, src string, destDir string) {
+	t.Helper()
+	require.NoError(t, container.CopyToContextE(t, ctx, src, destDir))
+}
+
+// CopyFromContextE copies the file or directory at src in the container into the local directory destDir, which must
+// exist. The ctx parameter supports cancellation and timeouts.
+func (container *Container) CopyFromContextE(t testing.TestingT, ctx context.Context, src string, destDir string) error {
+	container.options.Logger.Logf(t, "Copying %s from container %s to %s", src, container.ID, destDir)
+
+	if container.options.Backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return err
+		}
+
+		return client.CopyFromContainerContextE(ctx, container.ID, src, destDir)
+	}
+
+	cmd := &shell.Command{
+		Command: "docker",
+		Args:    []string{"cp", container.ID + ":" + src, withTrailingSlash(destDir)},
+		Logger:  container.options.Logger,
+	}
+
+	return shell.RunCommandContextE(t, ctx, cmd)
+}
+
+// CopyFromContext copies the file or directory at src in the container into the local directory destDir. This will
+// fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func (container *Container) CopyFromContext(t testing.TestingT, ctx context.Context, src string, destDir string) {
+	t.Helper()
+	require.NoError(t, container.CopyFromContextE(t, ctx, src, destDir))
+}
+
+// TerminateContextE stops and removes the container along with its anonymous volumes. The ctx parameter supports
+// cancellation and timeouts.
+func (container *Container) TerminateContextE(t testing.TestingT, ctx context.Context) error {
+	stopOptions := &StopOptions{Logger: container.options.Logger, Time: container.options.StopTimeout, Backend: container.options.Backend}
+	if _, err := StopContextE(t, ctx, []string{container.ID}, stopOptions); err != nil {
+		return err
+	}
+
+	if container.options.Backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return err
+		}
+
+		return client.RemoveContainerContextE(ctx, container.ID, true)
+	}
+
+	cmd := &shell.Command{
+		Command: "docker",
+		Args:    []string{"rm", "--force", "--volumes", container.ID},
+		Logger:  container.options.Logger,
+	}
+
+	return shell.RunCommandContextE(t, ctx, cmd)
+}
+
+// withTrailingSlash makes 'docker cp' copy into the given directory rather than to a file of that name.
+func withTrailingSlash(dir string) string {
+	if dir == "" || dir[len(dir)-1] != '/' {
+		return dir + "/"
+	}
+
+	return dir
+}
+
+// WaitForHealthy returns a WaitStrategy that waits until the container's health check reports it as healthy. The image
+// or RunOptions must define a health check.
+func WaitForHealthy() WaitStrategy {
+	return healthyWaitStrategy{}
+}
+
+// WaitForLog returns a WaitStrategy that waits until the container's output matches the given regular expression.
+func WaitForLog(regex *regexp.Regexp) WaitStrategy {
+	return logWaitStrategy{regex: regex}
+}
+
+// WaitForPort returns a WaitStrategy that waits until the host port that the given container port is published on
+// accepts TCP connections.
+func WaitForPort(containerPort uint16) WaitStrategy {
+	return portWaitStrategy{containerPort: containerPort}
+}
+
+// WaitForHTTP returns a WaitStrategy that waits until a GET request for the given path, on the host port that the
+// given container port is published on, returns 200 OK.
+func WaitForHTTP(containerPort uint16, path string) WaitStrategy {
+	return httpWaitStrategy{containerPort: containerPort, path: path}
+}
+
+type healthyWaitStrategy struct{}
+
+func (healthyWaitStrategy) String() string {
+	return "health check status is healthy"
+}
+
+func (healthyWaitStrategy) CheckReadyContextE(t testing.TestingT, ctx context.Context, container *Container) error {
+	inspect, err := container.InspectContextE(t, ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := checkContainerRunning(inspect); err != nil {
+		return err
+	}
+
+	if inspect.Health.Status != "healthy" {
+		return fmt.Errorf("container health check status is %q", inspect.Health.Status)
+	}
+
+	return nil
+}
+
+type logWaitStrategy struct {
+	regex *regexp.Regexp
+}
+
+func (strategy logWaitStrategy) String() string {
+	return "output matches " + strategy.regex.String()
+}
+
+func (strategy logWaitStrategy) CheckReadyContextE(t testing.TestingT, ctx context.Context, container *Container) error {
+	logs, err := container.LogsContextE(t, ctx)
+	if err != nil {
+		return err
+	}
+
+	if !strategy.regex.MatchString(logs) {
+		return fmt.Errorf("container output does not match %s", strategy.regex)
+	}
+
+	return nil
+}
+
+type portWaitStrategy struct {
+	containerPort uint16
+}
+
+func (strategy portWaitStrategy) String() string {
+	return fmt.Sprintf("port %d accepts connections", strategy.containerPort)
+}
+
+func (strategy portWaitStrategy) CheckReadyContextE(t testing.TestingT, ctx context.Context, container *Container) error {
+	endpoint, err := readyEndpointContextE(t, ctx, container, strategy.containerPort)
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{Timeout: containerProbeTimeout}).DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+type httpWaitStrategy struct {
+	path          string
+	containerPort uint16
+}
+
+func (strategy httpWaitStrategy) String() string {
+	return fmt.Sprintf("GET %s on port %d returns 200", strategy.path, strategy.containerPort)
+}
+
+func (strategy httpWaitStrategy) CheckReadyContextE(t testing.TestingT, ctx context.Context, container *Container) error {
+	endpoint, err := readyEndpointContextE(t, ctx, container, strategy.containerPort)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, containerProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+endpoint+strategy.path, nil)
+	if err != nil {
+		return retry.FatalError{Underlying: err}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", req.URL, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// readyEndpointContextE returns the endpoint of the given container port for a wait strategy, failing fast if the
+// container has exited or the port is not published.
+func readyEndpointContextE(t testing.TestingT, ctx context.Context, container *Container, containerPort uint16) (string, error) {
+	inspect, err := container.InspectContextE(t, ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkContainerRunning(inspect); err != nil {
+		return "", err
+	}
+
+	hostPort := inspect.GetExposedHostPort(containerPort)
+	if hostPort == 0 {
+		return "", retry.FatalError{Underlying: fmt.Errorf("%w: %d", ErrPortNotPublished, containerPort)}
+	}
+
+	return net.JoinHostPort(GetDockerHost(), strconv.Itoa(int(hostPort))), nil
+}
+
+// checkContainerRunning returns a retry.FatalError if the container is no longer running, as it will never become
+// ready.
+func checkContainerRunning(inspect *ContainerInspect) error {
+	if inspect.Running {
+		return nil
+	}
+
+	if inspect.Status == "created" || inspect.Status == "restarting" {
+		return fmt.Errorf("container is %s", inspect.Status)
+	}
+
+	return retry.FatalError{Underlying: fmt.Errorf("container is %s with exit code %d", inspect.Status, inspect.ExitCode)}
+}
@@ -0,0 +1,68 @@
+package docker_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/docker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartContainer(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	options := &docker.ContainerOptions{
+		RunOptions: docker.RunOptions{
+			OtherOptions: []string{"--publish", "80"},
+		},
+		WaitFor: []docker.WaitStrategy{
+			docker.WaitForPort(80),
+			docker.WaitForHTTP(80, "/"),
+		},
+	}
+
+	container := docker.StartContainerContext(t, ctx, dockerInspectTestImage, options)
+
+	require.NotZero(t, container.HostPortContext(t, ctx, 80))
+
+	_, err := container.HostPortContextE(t, ctx, 443)
+	require.ErrorIs(t, err, docker.ErrPortNotPublished)
+
+	out := container.ExecContext(t, ctx, "cat", "/etc/nginx/conf.d/default.conf")
+	require.Contains(t, out, "listen")
+
+	localDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "hello.txt"), []byte("Hello, World!"), 0o644))
+
+	container.CopyToContext(t, ctx, filepath.Join(localDir, "hello.txt"), "/tmp")
+	require.Equal(t, "Hello, World!", container.ExecContext(t, ctx, "cat", "/tmp/hello.txt"))
+
+	copyDir := t.TempDir()
+	container.CopyFromContext(t, ctx, "/tmp/hello.txt", copyDir)
+
+	contents, err := os.ReadFile(filepath.Join(copyDir, "hello.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "Hello, World!", string(contents))
+}
+
+func TestStartContainerWaitForLog(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	options := &docker.ContainerOptions{
+		RunOptions: docker.RunOptions{
+			Entrypoint: "sh",
+			Command:    []string{"-c", "sleep 2 && echo ready && sleep 60"},
+		},
+		WaitFor: []docker.WaitStrategy{docker.WaitForLog(regexp.MustCompile("ready"))},
+	}
+
+	container := docker.StartContainerContext(t, ctx, "alpine:3.7", options)
+
+	require.Contains(t, container.LogsContext(t, ctx), "ready")
+}
@@ -73,13 +73,20 @@ func RunDockerComposeContextE(t testing.TestingT, ctx context.Context, options *
 }
 
 func runDockerComposeE(t testing.TestingT, ctx context.Context, stdout bool, options *Options, args ...string) (string, error) {
-	var cmd *shell.Command
+	cmd := dockerComposeCommand(t, options, args...)
 
-	projectName := options.ProjectName
-	if len(projectName) == 0 {
-		projectName = strings.ToLower(t.Name())
+	if stdout {
+		return shell.RunCommandContextAndGetStdOut(t, ctx, cmd), nil
 	}
 
+	return shell.RunCommandContextAndGetOutputE(t, ctx, cmd)
+}
+
+// dockerComposeCommand returns the command to run docker compose with the given arguments and options, using the
+// compose plugin if it is installed and the standalone docker-compose binary otherwise.
+func dockerComposeCommand(t testing.TestingT, options *Options, args ...string) *shell.Command {
+	projectName := dockerComposeProjectName(t, options)
+
 	dockerComposeVersionCmd := icmd.Command("docker", "compose", "version")
 	result := icmd.RunCmd(dockerComposeVersionCmd)
 
@@ -93,30 +100,35 @@ func runDockerComposeE(t testing.TestingT, ctx context.Context, stdout bool, opt
 	}
 
 	if result.ExitCode == 0 {
-		cmd = &shell.Command{
+		return &shell.Command{
 			Command:    "docker",
-			Args:       append([]string{"compose", "--project-name", generateValidDockerComposeProjectName(projectName)}, args...),
-			WorkingDir: options.WorkingDir,
-			Env:        options.EnvVars,
-			Logger:     options.Logger,
-		}
-	} else {
-		cmd = &shell.Command{
-			Command: "docker-compose",
-			// We append --project-name to ensure containers from multiple different tests using Docker Compose don't end
-			// up in the same project and end up conflicting with each other.
-			Args:       append([]string{"--project-name", generateValidDockerComposeProjectName(projectName)}, args...),
+			Args:       append([]string{"compose", "--project-name", projectName}, args...),
 			WorkingDir: options.WorkingDir,
 			Env:        options.EnvVars,
 			Logger:     options.Logger,
 		}
 	}
 
-	if stdout {
-		return shell.RunCommandContextAndGetStdOut(t, ctx, cmd), nil
+	return &shell.Command{
+		Command: "docker-compose",
+		// We append --project-name to ensure containers from multiple different tests using Docker Compose don't end
+		// up in the same project and end up conflicting with each other.
+		Args:       append([]string{"--project-name", projectName}, args...),
+		WorkingDir: options.WorkingDir,
+		Env:        options.EnvVars,
+		Logger:     options.Logger,
 	}
+}
 
-	return shell.RunCommandContextAndGetOutputE(t, ctx, cmd)
+// dockerComposeProjectName returns the sanitized project name to use for the given options, defaulting to the name
+// of the test.
+func dockerComposeProjectName(t testing.TestingT, options *Options) string {
+	projectName := options.ProjectName
+	if len(projectName) == 0 {
+		projectName = strings.ToLower(t.Name())
+	}
+
+	return generateValidDockerComposeProjectName(projectName)
 }
 
 // generateValidDockerComposeProjectName generates a valid project name for docker-compose.
@@ -0,0 +1,328 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// ErrComposeServiceNotFound is returned when a service has no containers in a compose project.
+var ErrComposeServiceNotFound = errors.New("compose service not found")
+
+// ComposeProject is a handle to a Docker Compose project. All commands are run with the project name from Options,
+// sanitized the same way as RunDockerComposeContextE, so they can be mixed with calls to RunDockerComposeContextE.
+// ComposeProject requires Docker Compose v2.
+type ComposeProject struct {
+	options *Options
+
+	// Name is the sanitized project name
+	Name string
+}
+
+// ComposeContainer is a single container of a compose service, as reported by 'docker compose ps'.
+type ComposeContainer struct {
+	// ID of the container
+	ID string `json:"ID"`
+
+	// Name of the container
+	Name string `json:"Name"`
+
+	// Service the container belongs to
+	Service string `json:"Service"`
+
+	// State of the container, such as running or exited
+	State string `json:"State"`
+
+	// Health status of the container, empty if it has no health check
+	Health string `json:"Health"`
+
+	// Ports published by the container
+	Publishers []ComposePublisher `json:"Publishers"`
+
+	// Exit code of the container, if it has exited
+	ExitCode int `json:"ExitCode"`
+}
+
+// ComposePublisher is a single port published by a compose service container.
+type ComposePublisher struct {
+	URL           string `json:"URL"`
+	Protocol      string `json:"Protocol"`
+	TargetPort    uint16 `json:"TargetPort"`
+	PublishedPort uint16 `json:"PublishedPort"`
+}
+
+// ComposeService is a compose service together with its containers.
+type ComposeService struct {
+	// Name of the service
+	Name string
+
+	// Containers of the service, one per replica
+	Containers []ComposeContainer
+}
+
+// PublishedPort returns the host port that the given target port of the service's first container is published on, or
+// 0 if it is not published.
+func (service ComposeService) PublishedPort(targetPort uint16) uint16 {
+	if len(service.Containers) == 0 {
+		return 0
+	}
+
+	for _, publisher := range service.Containers[0].Publishers {
+		if publisher.TargetPort == targetPort && publisher.PublishedPort != 0 {
+			return publisher.PublishedPort
+		}
+	}
+
+	return 0
+}
+
+// ContainerIDs returns the IDs of the service's containers.
+func (service ComposeService) ContainerIDs() []string {
+	ids := make([]string, 0, len(service.Containers))
+	for _, container := range service.Containers {
+		ids = append(ids, container.ID)
+	}
+
+	return ids
+}
+
+// NewComposeProject returns a handle to the compose project described by the given options. If t supports Cleanup,
+// such as *testing.T, the project is torn down with 'docker compose down --volumes' when the test finishes.
+func NewComposeProject(t testing.TestingT, options *Options) *ComposeProject {
+	project := &ComposeProject{Name: dockerComposeProjectName(t, options), options: options}
+
+	if c, ok := t.(testing.Cleaner); ok {
+		c.Cleanup(func() {
+			// The test context is already canceled when cleanup functions run
+			if err := project.DownContextE(t, context.Background()); err != nil {
+				options.Logger.Warnf(t, "failed to tear down compose project %s: %v", project.Name, err)
+			}
+		})
+	}
+
+	return project
+}
+
+// StartComposeProjectContextE returns a handle to the compose project described by the given options and brings up the
+// given services, or all services if none are given, waiting until they are running and healthy. If t supports
+// Cleanup, the project is torn down when the test finishes. The ctx parameter supports cancellation and timeouts.
+func StartComposeProjectContextE(t testing.TestingT, ctx context.Context, options *Options, services ...string) (*ComposeProject, error) {
+	project := NewComposeProject(t, options)
+
+	return project, project.UpContextE(t, ctx, services...)
+}
+
+// StartComposeProjectContext returns a handle to the compose project described by the given options and brings up the
+// given services, or all services if none are given, waiting until they are running and healthy. If t supports
+// Cleanup, the project is torn down when the test finishes. This will fail the test if there are any errors. The ctx
+// parameter supports cancellation and timeouts.
+func StartComposeProjectContext(t testing.TestingT, ctx context.Context, options *Options, services ...string) *ComposeProject {
+	t.Helper()
+
+	project, err := StartComposeProjectContextE(t, ctx, options, services...)
+	require.NoError(t, err)
+
+	return project
+}
+
+// UpContextE runs 'docker compose up --detach --wait' for the given services, or all services if none are given, which
+// waits until the services are running and, if they have a health check, healthy. The ctx parameter supports
+// cancellation and timeouts.
+func (project *ComposeProject) UpContextE(t testing.TestingT, ctx context.Context, services ...string) error {
+	_, err := project.runE(t, ctx, append([]string{"up", "--detach", "--wait"}, services...)...)
+
+	return err
+}
+
+// UpContext runs 'docker compose up --detach --wait' for the given services, or all services if none are given. This
+// will fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) UpContext(t testing.TestingT, ctx context.Context, services ...string) {
+	t.Helper()
+	require.NoError(t, project.UpContextE(t, ctx, services...))
+}
+
+// DownContextE runs 'docker compose down --volumes --remove-orphans' to remove the project's containers, networks and
+// volumes. The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) DownContextE(t testing.TestingT, ctx context.Context) error {
+	_, err := project.runE(t, ctx, "down", "--volumes", "--remove-orphans")
+
+	return err
+}
+
+// DownContext runs 'docker compose down --volumes --remove-orphans'. This will fail the test if there are any errors.
+// The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) DownContext(t testing.TestingT, ctx context.Context) {
+	t.Helper()
+	require.NoError(t, project.DownContextE(t, ctx))
+}
+
+// ServicesContextE lists the services of the project that have containers, including stopped ones, with their
+// container IDs and published ports. The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) ServicesContextE(t testing.TestingT, ctx context.Context) ([]ComposeService, error) {
+	cmd := dockerComposeCommand(t, project.options, "ps", "--all", "--format", "json")
+	// ps is a short-running command that is often polled, don't print the output.
+	cmd.Logger = logger.Discard
+
+	out, err := shell.RunCommandContextAndGetStdOutE(t, ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := parseComposePsOutput(out)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []ComposeService
+
+	index := map[string]int{}
+
+	for _, container := range containers {
+		i, ok := index[container.Service]
+		if !ok {
+			i = len(services)
+			index[container.Service] = i
+			services = append(services, ComposeService{Name: container.Service})
+		}
+
+		services[i].Containers = append(services[i].Containers, container)
+	}
+
+	return services, nil
+}
+
+// ServicesContext lists the services of the project that have containers, with their container IDs and published
+// ports. This will fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) ServicesContext(t testing.TestingT, ctx context.Context) []ComposeService {
+	t.Helper()
+
+	services, err := project.ServicesContextE(t, ctx)
+	require.NoError(t, err)
+
+	return services
+}
+
+// ServiceContextE returns the given service of the project. The returned error matches ErrComposeServiceNotFound if
+// the service has no containers. The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) ServiceContextE(t testing.TestingT, ctx context.Context, name string) (*ComposeService, error) {
+	services, err := project.ServicesContextE(t, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range services {
+		if services[i].Name == name {
+			return &services[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s in project %s", ErrComposeServiceNotFound, name, project.Name)
+}
+
+// ServiceContext returns the given service of the project. This will fail the test if the service has no containers.
+// The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) ServiceContext(t testing.TestingT, ctx context.Context, name string) *ComposeService {
+	t.Helper()
+
+	service, err := project.ServiceContextE(t, ctx, name)
+	require.NoError(t, err)
+
+	return service
+}
+
+// LogsContextE returns the output of the given service's containers. The ctx parameter supports cancellation and
+// timeouts.
+func (project *ComposeProject) LogsContextE(t testing.TestingT, ctx context.Context, service string) (string, error) {
+	cmd := dockerComposeCommand(t, project.options, "logs", "--no-color", "--no-log-prefix", service)
+	cmd.Logger = logger.Discard
+
+	return shell.RunCommandContextAndGetOutputE(t, ctx, cmd)
+}
+
+// LogsContext returns the output of the given service's containers. This will fail the test if there are any errors.
+// The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) LogsContext(t testing.TestingT, ctx context.Context, service string) string {
+	t.Helper()
+
+	out, err := project.LogsContextE(t, ctx, service)
+	require.NoError(t, err)
+
+	return out
+}
+
+// ExecContextE runs the given command in the first container of the given service and returns its stdout and stderr.
+// The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) ExecContextE(t testing.TestingT, ctx context.Context, service string, command ...string) (string, error) {
+	return project.runE(t, ctx, append([]string{"exec", "-T", service}, command...)...)
+}
+
+// ExecContext runs the given command in the first container of the given service and returns its stdout and stderr.
+// This will fail the test if the command fails. The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) ExecContext(t testing.TestingT, ctx context.Context, service string, command ...string) string {
+	t.Helper()
+
+	out, err := project.ExecContextE(t, ctx, service, command...)
+	require.NoError(t, err)
+
+	return out
+}
+
+// ScaleContextE scales the given service to the given number of replicas and waits until they are running and healthy.
+// The service must not publish a fixed host port for more than one replica to start. The ctx parameter supports
+// cancellation and timeouts.
+func (project *ComposeProject) ScaleContextE(t testing.TestingT, ctx context.Context, service string, replicas int) error {
+	_, err := project.runE(t, ctx, "up", "--detach", "--wait", "--no-recreate", "--scale", service+"="+strconv.Itoa(replicas), service)
+
+	return err
+}
+
+// ScaleContext scales the given service to the given number of replicas and waits until they are running and healthy.
+// This will fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func (project *ComposeProject) ScaleContext(t testing.TestingT, ctx context.Context, service string, replicas int) {
+	t.Helper()
+	require.NoError(t, project.ScaleContextE(t, ctx, service, replicas))
+}
+
+// runE runs docker compose for the project with the given arguments and returns stdout/stderr.
+func (project *ComposeProject) runE(t testing.TestingT, ctx context.Context, args ...string) (string, error) {
+	return shell.RunCommandContextAndGetOutputE(t, ctx, dockerComposeCommand(t, project.options, args...))
+}
+
+// parseComposePsOutput parses the output of 'docker compose ps --format json', which is a JSON array in Compose
+// versions before 2.21 and one JSON object per line in later versions.
+func parseComposePsOutput(out string) ([]ComposeContainer, error) {
+	out = strings.TrimSpace(out)
+
+	var containers []ComposeContainer
+
+	if strings.HasPrefix(out, "[") {
+		err := json.Unmarshal([]byte(out), &containers)
+		return containers, err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var container ComposeContainer
+		if err := json.Unmarshal([]byte(line), &container); err != nil {
+			return nil, err
+		}
+
+		containers = append(containers, container)
+	}
+
+	return containers, scanner.Err()
+}
@@ -0,0 +1,36 @@
+package docker_test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/docker"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeProject(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	options := &docker.Options{
+		WorkingDir:  "../../test/fixtures/docker-compose-project",
+		ProjectName: "compose-project-" + random.UniqueID(),
+	}
+
+	project := docker.StartComposeProjectContext(t, ctx, options)
+
+	web := project.ServiceContext(t, ctx, "web")
+	require.Len(t, web.Containers, 1)
+	require.Equal(t, "healthy", web.Containers[0].Health)
+	require.NotZero(t, web.PublishedPort(80))
+
+	require.Contains(t, project.LogsContext(t, ctx, "worker"), "worker started")
+	require.Contains(t, project.ExecContext(t, ctx, "worker", "echo", "hello"), "hello")
+
+	project.ScaleContext(t, ctx, "worker", 2)
+	require.Len(t, project.ServiceContext(t, ctx, "worker").ContainerIDs(), 2)
+
+	_, err := project.ServiceContextE(t, ctx, "does-not-exist")
+	require.ErrorIs(t, err, docker.ErrComposeServiceNotFound)
+}
@@ -0,0 +1,204 @@
+package docker
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// writeTarArchive writes the file or directory at the given path as a tar archive. Entries are named after their path
+// relative to root, prefixed with prefix if it is not empty; the root itself is only included when there is a prefix.
+// Files for which exclude returns true are skipped, as are excluded directories unless walkExcludedDirs is true.
+func writeTarArchive(root string, prefix string, out io.Writer, exclude func(relPath string) bool, walkExcludedDirs bool) error {
+	tarWriter := tar.NewWriter(out)
+
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+
+		relPath = filepath.ToSlash(relPath)
+
+		name := path.Join(prefix, relPath)
+		if relPath == "." {
+			if prefix == "" {
+				return nil
+			}
+
+			name = prefix
+		}
+
+		if exclude != nil && relPath != "." && exclude(relPath) {
+			if entry.IsDir() && !walkExcludedDirs {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+
+		header.Name = name
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyFileTo(file, tarWriter)
+	})
+	if err != nil {
+		return err
+	}
+
+	return tarWriter.Close()
+}
+
+// extractTarArchive extracts the given tar archive into the directory dest, preserving file modes. Entries that would
+// be extracted outside of dest, either by their name or by following a symlink extracted earlier, are rejected.
+// Directories are created writable so that their contents can be extracted, and only get their mode once the whole
+// archive has been extracted.
+func extractTarArchive(in io.Reader, dest string) error {
+	root, err := os.OpenRoot(dest)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = root.Close() }()
+
+	type dirMode struct {
+		name string
+		mode os.FileMode
+	}
+
+	var dirModes []dirMode
+
+	tarReader := tar.NewReader(in)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("refusing to extract %s outside of %s", header.Name, dest)
+		}
+
+		// All file system operations go through root, which refuses to follow symlinks that lead outside of dest
+		name, err := filepath.Rel(dest, target)
+		if err != nil {
+			return err
+		}
+
+		mode := header.FileInfo().Mode()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(name, os.ModePerm); err != nil {
+				return err
+			}
+
+			dirModes = append(dirModes, dirMode{name: name, mode: mode.Perm()})
+		case tar.TypeSymlink:
+			if err := root.Symlink(header.Linkname, name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := root.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+				return err
+			}
+
+			if err := writeRootFileFrom(root, name, mode.Perm(), tarReader); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Apply the modes of nested directories before those of their parents, which may not be writable
+	for i := len(dirModes) - 1; i >= 0; i-- {
+		if err := root.Chmod(dirModes[i].name, dirModes[i].mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyFileTo copies the contents of the given file to out.
+func copyFileTo(file string, out io.Writer) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = in.Close() }()
+
+	_, err = io.Copy(out, in)
+
+	return err
+}
+
+// writeFileFrom creates or truncates the given file with the given mode and copies the contents of in to it.
+func writeFileFrom(file string, mode os.FileMode, in io.Reader) error {
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	return copyAndClose(out, in)
+}
+
+// writeRootFileFrom creates or truncates the given file in root with the given mode and copies the contents of in to it.
+func writeRootFileFrom(root *os.Root, file string, mode os.FileMode, in io.Reader) error {
+	out, err := root.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	return copyAndClose(out, in)
+}
+
+// copyAndClose copies the contents of in to out and closes out.
+func copyAndClose(out *os.File, in io.Reader) error {
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
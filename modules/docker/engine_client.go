@@ -0,0 +1,411 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Backend selects how the functions in this package talk to the Docker daemon.
+type Backend int
+
+const (
+	// BackendCLI shells out to the docker CLI and parses its output. This is the default.
+	BackendCLI Backend = iota
+	// BackendEngineAPI talks to the Docker Engine API directly over the socket configured in DOCKER_HOST, so the docker
+	// CLI does not need to be installed.
+	BackendEngineAPI
+)
+
+const (
+	// defaultDockerSocket is the socket the Docker daemon listens on when DOCKER_HOST is not set.
+	defaultDockerSocket = "/var/run/docker.sock"
+
+	// stdcopyHeaderSize is the size of the header that prefixes each frame of a multiplexed container output stream.
+	stdcopyHeaderSize = 8
+)
+
+var (
+	// ErrDaemonUnavailable is returned when the Docker daemon cannot be reached.
+	ErrDaemonUnavailable = errors.New("docker daemon unavailable")
+
+	// ErrImageNotFound is returned when an image does not exist locally or in its registry.
+	ErrImageNotFound = errors.New("image not found")
+
+	// ErrUnsupportedByEngineAPI is returned when an option can only be used with the docker CLI backend.
+	ErrUnsupportedByEngineAPI = errors.New("option not supported by the Docker Engine API backend")
+
+	// ErrUnsupportedDockerHost is returned when DOCKER_HOST uses a scheme the Engine API client cannot connect to.
+	ErrUnsupportedDockerHost = errors.New("unsupported DOCKER_HOST")
+)
+
+// DaemonUnavailableError is returned when a request to the Docker Engine API fails because the daemon cannot be
+// reached. It matches ErrDaemonUnavailable with errors.Is.
+type DaemonUnavailableError struct {
+	Err  error
+	Host string
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err DaemonUnavailableError) Error() string {
+	return fmt.Sprintf("docker daemon at %s is unavailable: %v", err.Host, err.Err)
+}
+
+// Unwrap returns the underlying error for use with errors.Is and errors.As.
+func (err DaemonUnavailableError) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether the error matches ErrDaemonUnavailable.
+func (err DaemonUnavailableError) Is(target error) bool {
+	return target == ErrDaemonUnavailable
+}
+
+// EngineAPIError is returned when the Docker Engine API responds with an error status. Errors for missing images and
+// containers match ErrImageNotFound and ErrNoContainerFound with errors.Is.
+type EngineAPIError struct {
+	Method     string
+	Path       string
+	Message    string
+	StatusCode int
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err EngineAPIError) Error() string {
+	return fmt.Sprintf("docker engine API %s %s returned %d: %s", err.Method, err.Path, err.StatusCode, err.Message)
+}
+
+// Is reports whether the error matches ErrImageNotFound or ErrNoContainerFound.
+func (err EngineAPIError) Is(target error) bool {
+	if err.StatusCode != http.StatusNotFound {
+		return false
+	}
+
+	message := strings.ToLower(err.Message)
+
+	switch target {
+	case ErrImageNotFound:
+		return strings.Contains(message, "no such image") || (strings.Contains(message, "not found") && strings.Contains(message, "image"))
+	case ErrNoContainerFound:
+		return strings.Contains(message, "no such container")
+	default:
+		return false
+	}
+}
+
+// EngineStreamError is returned when a streaming Docker Engine API operation, such as a build, pull or push, reports an
+// error in its progress stream.
+type EngineStreamError struct {
+	Operation string
+	Message   string
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err EngineStreamError) Error() string {
+	return fmt.Sprintf("docker %s failed: %s", err.Operation, err.Message)
+}
+
+// Is reports whether the error matches ErrImageNotFound, for pulls of images that do not exist.
+func (err EngineStreamError) Is(target error) bool {
+	message := strings.ToLower(err.Message)
+
+	return target == ErrImageNotFound && (strings.Contains(message, "not found") || strings.Contains(message, "does not exist"))
+}
+
+// ContainerExitError is returned by the Engine API backend when a container that is run in the foreground exits with a
+// non-zero exit code.
+type ContainerExitError struct {
+	ID       string
+	Output   string
+	ExitCode int
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err ContainerExitError) Error() string {
+	return fmt.Sprintf("container %s exited with code %d: %s", err.ID, err.ExitCode, err.Output)
+}
+
+// EngineClient is a minimal client for the Docker Engine API. It is used by the functions in this package when their
+// options select BackendEngineAPI, and can also be used directly.
+type EngineClient struct {
+	httpClient *http.Client
+	host       string
+	baseURL    string
+}
+
+// NewEngineClientE creates an EngineClient for the daemon configured in the DOCKER_HOST environment variable, or the
+// default local socket if it is not set. TLS is configured from DOCKER_TLS_VERIFY and DOCKER_CERT_PATH like the docker
+// CLI does.
+func NewEngineClientE() (*EngineClient, error) {
+	return NewEngineClientForHostE(os.Getenv("DOCKER_HOST"))
+}
+
+// NewEngineClientForHostE creates an EngineClient for the daemon at the given host, in DOCKER_HOST format (for example
+// unix:///var/run/docker.sock or tcp://localhost:2375). An empty host uses the default local socket.
+func NewEngineClientForHostE(host string) (*EngineClient, error) {
+	if host == "" {
+		host = "unix://" + defaultDockerSocket
+	}
+
+	scheme, address, found := strings.Cut(host, "://")
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDockerHost, host)
+	}
+
+	transport := &http.Transport{}
+	client := &EngineClient{host: host}
+
+	switch scheme {
+	case "unix":
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", address)
+		}
+		// The host name is ignored when dialing a unix socket, but must be a valid host for the HTTP request.
+		client.baseURL = "http://docker"
+	case "tcp", "http", "https":
+		tlsConfig, err := engineTLSConfigFromEnvE()
+		if err != nil {
+			return nil, err
+		}
+
+		if tlsConfig != nil || scheme == "https" {
+			transport.TLSClientConfig = tlsConfig
+			client.baseURL = "https://" + address
+		} else {
+			client.baseURL = "http://" + address
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDockerHost, host)
+	}
+
+	client.httpClient = &http.Client{Transport: transport}
+
+	return client, nil
+}
+
+// engineTLSConfigFromEnvE returns the TLS configuration for a TCP daemon from DOCKER_TLS_VERIFY and DOCKER_CERT_PATH, or
+// nil if TLS is not enabled.
+func engineTLSConfigFromEnvE() (*tls.Config, error) {
+	if os.Getenv("DOCKER_TLS_VERIFY") == "" {
+		return nil, nil //nolint:nilnil // no TLS configuration is a valid result
+	}
+
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+
+		certPath = filepath.Join(home, ".docker")
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caCert)
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// PingContextE checks that the daemon is reachable. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) PingContextE(ctx context.Context) error {
+	resp, err := client.do(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// do sends a request to the Engine API and returns the response. Errors connecting to the daemon are returned as
+// DaemonUnavailableError and error responses as EngineAPIError; in both cases the response body is already closed.
+func (client *EngineClient) do(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	body io.Reader,
+	headers map[string]string,
+) (*http.Response, error) {
+	target := client.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, DaemonUnavailableError{Host: client.host, Err: err}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer func() { _ = resp.Body.Close() }()
+
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+
+		return nil, EngineAPIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+
+	return resp, nil
+}
+
+// doJSON sends a request with an optional JSON body and decodes the JSON response into out, if out is not nil.
+func (client *EngineClient) doJSON(ctx context.Context, method string, path string, query url.Values, in any, out any) error {
+	var body io.Reader
+
+	headers := map[string]string{}
+
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = strings.NewReader(string(data))
+		headers["Content-Type"] = "application/json"
+	}
+
+	resp, err := client.do(ctx, method, path, query, body, headers)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// engineJSONMessage is a single progress message in the stream returned by the build, pull and push endpoints.
+type engineJSONMessage struct {
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+	Stream string `json:"stream"`
+	Status string `json:"status"`
+	ID     string `json:"id"`
+	Error  string `json:"error"`
+}
+
+// readJSONMessages reads a progress stream, logging each message, and returns an EngineStreamError if the stream
+// reports an error.
+func readJSONMessages(t testing.TestingT, logger *logger.Logger, operation string, stream io.Reader) error {
+	decoder := json.NewDecoder(stream)
+
+	for {
+		var message engineJSONMessage
+
+		err := decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case message.Error != "":
+			return EngineStreamError{Operation: operation, Message: message.Error}
+		case message.ErrorDetail != nil && message.ErrorDetail.Message != "":
+			return EngineStreamError{Operation: operation, Message: message.ErrorDetail.Message}
+		case strings.TrimSpace(message.Stream) != "":
+			logger.Logf(t, "%s", strings.TrimRight(message.Stream, "\n"))
+		case message.Status != "" && message.ID != "":
+			logger.Logf(t, "%s: %s", message.ID, message.Status)
+		case message.Status != "":
+			logger.Logf(t, "%s", message.Status)
+		}
+	}
+}
+
+// demuxContainerOutput copies a multiplexed container output stream, as returned by the logs and attach endpoints for
+// containers without a TTY, into the given stdout and stderr writers. Both may be the same writer to get the combined
+// output.
+func demuxContainerOutput(stream io.Reader, stdout io.Writer, stderr io.Writer) error {
+	reader := bufio.NewReader(stream)
+	header := make([]byte, stdcopyHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(header[4:])
+
+		target := stdout
+		if header[0] == 2 { //nolint:mnd // stream type 2 is stderr in the Docker multiplexing protocol
+			target = stderr
+		}
+
+		if _, err := io.CopyN(target, reader, int64(size)); err != nil {
+			return err
+		}
+	}
+}
+
+// encodeRegistryAuth encodes credentials in the format expected by the X-Registry-Auth header.
+func encodeRegistryAuth(username string, password string, identityToken string) (string, error) {
+	data, err := json.Marshal(map[string]string{
+		"username":      username,
+		"password":      password,
+		"identitytoken": identityToken,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(data), nil
+}
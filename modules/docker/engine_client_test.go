@@ -0,0 +1,215 @@
+package docker_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/docker"
+	"github.com/stretchr/testify/require"
+)
+
+const engineInspectResponse = `{
+  "Id": "abc123",
+  "Name": "/engine-test",
+  "Created": "2024-01-02T03:04:05.123456789Z",
+  "State": {"Status": "running", "Running": true},
+  "Config": {"Image": "nginx:1.17-alpine", "Labels": {"app": "web"}},
+  "HostConfig": {"Memory": 67108864, "NanoCpus": 500000000, "PidsLimit": 100},
+  "NetworkSettings": {
+    "Ports": {"80/tcp": [{"HostIp": "0.0.0.0", "HostPort": "8080"}]},
+    "Networks": {"bridge": {"NetworkID": "net1", "IPAddress": "172.17.0.2", "Aliases": ["web"]}}
+  },
+  "Mounts": [{"Type": "volume", "Name": "data", "Source": "/var/lib/docker/volumes/data", "Destination": "/data", "RW": true}]
+}`
+
+func newFakeEngine(t *testing.T, handler http.HandlerFunc) *docker.EngineClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := docker.NewEngineClientForHostE("tcp://" + server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestEngineClientInspectContainer(t *testing.T) {
+	t.Parallel()
+
+	client := newFakeEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/containers/abc123/json", r.URL.Path)
+		_, _ = w.Write([]byte(engineInspectResponse))
+	})
+
+	c, err := client.InspectContainerContextE(t, t.Context(), "abc123")
+	require.NoError(t, err)
+
+	require.Equal(t, "engine-test", c.Name)
+	require.Equal(t, "nginx:1.17-alpine", c.Image)
+	require.Equal(t, map[string]string{"app": "web"}, c.Labels)
+	require.Equal(t, uint16(8080), c.GetExposedHostPort(80))
+	require.Equal(t, "172.17.0.2", c.Networks["bridge"].IPAddress)
+	require.Equal(t, []string{"web"}, c.Networks["bridge"].Aliases)
+	require.Equal(t, []docker.Mount{{Type: "volume", Name: "data", Source: "/var/lib/docker/volumes/data", Destination: "/data", RW: true}}, c.Mounts)
+	require.Equal(t, docker.ContainerResources{Memory: 67108864, NanoCPUs: 500000000, PidsLimit: 100}, c.Resources)
+}
+
+func TestEngineClientTypedErrors(t *testing.T) {
+	t.Parallel()
+
+	client := newFakeEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+
+		if strings.HasPrefix(r.URL.Path, "/images/") {
+			_, _ = w.Write([]byte(`{"message": "No such image: does-not-exist:latest"}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"message": "No such container: does-not-exist"}`))
+	})
+
+	err := client.RemoveImageContextE(t.Context(), "does-not-exist:latest")
+	require.ErrorIs(t, err, docker.ErrImageNotFound)
+	require.NotErrorIs(t, err, docker.ErrNoContainerFound)
+
+	_, err = client.InspectContainerContextE(t, t.Context(), "does-not-exist")
+	require.ErrorIs(t, err, docker.ErrNoContainerFound)
+	require.NotErrorIs(t, err, docker.ErrImageNotFound)
+}
+
+func TestEngineClientDaemonUnavailable(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client, err := docker.NewEngineClientForHostE("tcp://" + server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	err = client.PingContextE(t.Context())
+	require.ErrorIs(t, err, docker.ErrDaemonUnavailable)
+}
+
+func TestEngineClientCreateContainerWithOtherOptions(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]any
+
+	client := newFakeEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/containers/create", r.URL.Path)
+		require.Equal(t, "engine-test", r.URL.Query().Get("name"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"Id": "abc123"}`))
+	})
+
+	options := &docker.RunOptions{
+		Name:         "engine-test",
+		OtherOptions: []string{"-p=13031:80", "--label", "app=web"},
+	}
+
+	id, err := client.CreateContainerContextE(t, t.Context(), "nginx:1.17-alpine", options)
+	require.NoError(t, err)
+	require.Equal(t, "abc123", id)
+
+	hostConfig := body["HostConfig"].(map[string]any)
+	require.Equal(t, map[string]any{"80/tcp": []any{map[string]any{"HostIp": "", "HostPort": "13031"}}}, hostConfig["PortBindings"])
+	require.Equal(t, map[string]any{"app": "web"}, body["Labels"])
+
+	options.OtherOptions = []string{"--memory=1g"}
+	_, err = client.CreateContainerContextE(t, t.Context(), "nginx:1.17-alpine", options)
+	require.ErrorIs(t, err, docker.ErrUnsupportedByEngineAPI)
+}
+
+func newTarArchive(t *testing.T, headers ...*tar.Header) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	tarWriter := tar.NewWriter(&buf)
+
+	for _, header := range headers {
+		require.NoError(t, tarWriter.WriteHeader(header))
+
+		if header.Typeflag == tar.TypeReg {
+			_, err := tarWriter.Write([]byte(header.Name))
+			require.NoError(t, err)
+		}
+	}
+
+	require.NoError(t, tarWriter.Close())
+
+	return buf.Bytes()
+}
+
+func TestEngineClientCopyFromContainer(t *testing.T) {
+	t.Parallel()
+
+	archive := newTarArchive(t,
+		&tar.Header{Typeflag: tar.TypeDir, Name: "app/", Mode: 0o555},
+		&tar.Header{Typeflag: tar.TypeDir, Name: "app/bin/", Mode: 0o755},
+		&tar.Header{Typeflag: tar.TypeReg, Name: "app/bin/run", Mode: 0o755, Size: int64(len("app/bin/run"))},
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "app/current", Linkname: "bin"},
+	)
+
+	client := newFakeEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/containers/abc123/archive", r.URL.Path)
+		_, _ = w.Write(archive)
+	})
+
+	destDir := t.TempDir()
+	require.NoError(t, client.CopyFromContainerContextE(t.Context(), "abc123", "/app", destDir))
+
+	contents, err := os.ReadFile(filepath.Join(destDir, "app", "current", "run"))
+	require.NoError(t, err)
+	require.Equal(t, "app/bin/run", string(contents))
+
+	info, err := os.Stat(filepath.Join(destDir, "app"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o555), info.Mode().Perm())
+
+	// Let t.TempDir clean up the read-only directory
+	require.NoError(t, os.Chmod(filepath.Join(destDir, "app"), 0o755)) //nolint:gosec // the directory must be writable to remove it
+}
+
+func TestEngineClientCopyFromContainerRejectsSymlinkEscape(t *testing.T) {
+	t.Parallel()
+
+	outsideDir := t.TempDir()
+
+	archive := newTarArchive(t,
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "etc", Linkname: outsideDir},
+		&tar.Header{Typeflag: tar.TypeReg, Name: "etc/passwd", Mode: 0o644, Size: int64(len("etc/passwd"))},
+	)
+
+	client := newFakeEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	})
+
+	require.Error(t, client.CopyFromContainerContextE(t.Context(), "abc123", "/etc", t.TempDir()))
+
+	_, err := os.Stat(filepath.Join(outsideDir, "passwd"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRunWithEngineAPI(t *testing.T) {
+	t.Parallel()
+
+	options := &docker.RunOptions{
+		Command:              []string{"-c", `echo "Hello, $NAME!"`},
+		Entrypoint:           "sh",
+		EnvironmentVariables: []string{"NAME=World"},
+		Remove:               true,
+		Backend:              docker.BackendEngineAPI,
+	}
+
+	out := docker.RunContext(t, t.Context(), "alpine:3.7", options)
+	require.Contains(t, out, "Hello, World!")
+}
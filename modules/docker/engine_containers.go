@@ -0,0 +1,451 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// engineContainerConfig is the body of a container create request. Only the fields that RunOptions can set are
+// included.
+type engineContainerConfig struct {
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Image        string              `json:"Image"`
+	User         string              `json:"User,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	HostConfig   engineHostConfig    `json:"HostConfig"`
+	Tty          bool                `json:"Tty"`
+	AttachStdout bool                `json:"AttachStdout"`
+	AttachStderr bool                `json:"AttachStderr"`
+}
+
+// engineHostConfig is the HostConfig part of a container create request.
+type engineHostConfig struct {
+	PortBindings    map[string][]enginePortBinding `json:"PortBindings,omitempty"`
+	Init            *bool                          `json:"Init,omitempty"`
+	NetworkMode     string                         `json:"NetworkMode,omitempty"`
+	Binds           []string                       `json:"Binds,omitempty"`
+	Privileged      bool                           `json:"Privileged"`
+	PublishAllPorts bool                           `json:"PublishAllPorts"`
+	AutoRemove      bool                           `json:"AutoRemove"`
+}
+
+// enginePortBinding is a single host binding of a container port.
+type enginePortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// InspectContainerContextE returns the inspect data of the given container. The returned error matches
+// ErrNoContainerFound if the container does not exist. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) InspectContainerContextE(t testing.TestingT, ctx context.Context, id string) (*ContainerInspect, error) {
+	var container inspectOutput
+	if err := client.doJSON(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, nil, &container); err != nil {
+		return nil, err
+	}
+
+	return transformContainer(t, &container)
+}
+
+// CreateContainerContextE creates, but does not start, a container from the given image with the given options and
+// returns its ID. If the image does not exist locally, it is pulled first. Only the OtherOptions documented on
+// RunOptions.Backend are supported. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) CreateContainerContextE(t testing.TestingT, ctx context.Context, image string, options *RunOptions) (string, error) {
+	config, err := engineContainerConfigFromRunOptions(image, options)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	if options.Name != "" {
+		query.Set("name", options.Name)
+	}
+
+	if options.Platform != "" {
+		query.Set("platform", options.Platform)
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+
+	err = client.doJSON(ctx, http.MethodPost, "/containers/create", query, config, &created)
+	if errors.Is(err, ErrImageNotFound) {
+		if err := client.PullImageContextE(t, ctx, options.Logger, image, options.Platform); err != nil {
+			return "", err
+		}
+
+		err = client.doJSON(ctx, http.MethodPost, "/containers/create", query, config, &created)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+// StartContainerContextE starts the given container. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) StartContainerContextE(ctx context.Context, id string) error {
+	return client.doJSON(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/start", nil, nil, nil)
+}
+
+// WaitContainerContextE blocks until the given container stops and returns its exit code. The ctx parameter supports
+// cancellation and timeouts.
+func (client *EngineClient) WaitContainerContextE(ctx context.Context, id string) (int, error) {
+	var result struct {
+		Error *struct {
+			Message string `json:"Message"`
+		} `json:"Error"`
+		StatusCode int `json:"StatusCode"`
+	}
+
+	if err := client.doJSON(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/wait", nil, nil, &result); err != nil {
+		return 0, err
+	}
+
+	if result.Error != nil && result.Error.Message != "" {
+		return result.StatusCode, fmt.Errorf("error waiting for container %s: %s", id, result.Error.Message)
+	}
+
+	return result.StatusCode, nil
+}
+
+// ContainerLogsContextE returns the stdout and stderr of the given container, interleaved in the order they were
+// written. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) ContainerLogsContextE(ctx context.Context, id string, tty bool) (string, error) {
+	query := url.Values{"stdout": {"1"}, "stderr": {"1"}}
+
+	resp, err := client.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs", query, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var out strings.Builder
+
+	// Containers with a TTY have a single raw output stream, all others have a multiplexed stdout and stderr stream.
+	if tty {
+		_, err = io.Copy(&out, resp.Body)
+
+		return out.String(), err
+	}
+
+	err = demuxContainerOutput(resp.Body, &out, &out)
+
+	return out.String(), err
+}
+
+// StopContainerContextE stops the given container, killing it if it does not stop within timeoutSeconds. A
+// timeoutSeconds of 0 uses the daemon default. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) StopContainerContextE(ctx context.Context, id string, timeoutSeconds int) error {
+	query := url.Values{}
+	if timeoutSeconds != 0 {
+		query.Set("t", strconv.Itoa(timeoutSeconds))
+	}
+
+	// The daemon responds with 304 Not Modified if the container was already stopped, which is not an error.
+	return client.doJSON(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/stop", query, nil, nil)
+}
+
+// RemoveContainerContextE removes the given container along with its anonymous volumes. If force is true, a running
+// container is killed first. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) RemoveContainerContextE(ctx context.Context, id string, force bool) error {
+	query := url.Values{"v": {"1"}, "force": {strconv.FormatBool(force)}}
+
+	return client.doJSON(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id), query, nil, nil)
+}
+
+// runWithEngineAPIE implements RunContextE and RunAndGetIDContextE for BackendEngineAPI. It returns the ID of the
+// container and, for containers that are not detached, their output once they exit.
+func runWithEngineAPIE(t testing.TestingT, ctx context.Context, image string, options *RunOptions) (string, string, error) {
+	client, err := NewEngineClientE()
+	if err != nil {
+		return "", "", err
+	}
+
+	id, err := client.CreateContainerContextE(t, ctx, image, options)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := client.StartContainerContextE(ctx, id); err != nil {
+		return id, "", err
+	}
+
+	if options.Detach {
+		return id, id, nil
+	}
+
+	exitCode, err := client.WaitContainerContextE(ctx, id)
+	if err != nil {
+		return id, "", err
+	}
+
+	output, err := client.ContainerLogsContextE(ctx, id, options.Tty)
+	if err != nil {
+		return id, "", err
+	}
+
+	for line := range strings.Lines(output) {
+		options.Logger.Logf(t, "%s", strings.TrimRight(line, "\n"))
+	}
+
+	// AutoRemove is not used for containers that run in the foreground, as the container could be removed before its
+	// logs are read.
+	if options.Remove {
+		if err := client.RemoveContainerContextE(ctx, id, false); err != nil {
+			return id, output, err
+		}
+	}
+
+	if exitCode != 0 {
+		return id, output, ContainerExitError{ID: id, ExitCode: exitCode, Output: output}
+	}
+
+	return id, output, nil
+}
+
+// engineContainerConfigFromRunOptions converts RunOptions into a container create request.
+func engineContainerConfigFromRunOptions(image string, options *RunOptions) (*engineContainerConfig, error) {
+	config := &engineContainerConfig{
+		Image:        image,
+		Cmd:          options.Command,
+		Env:          options.EnvironmentVariables,
+		User:         options.User,
+		Tty:          options.Tty,
+		AttachStdout: !options.Detach,
+		AttachStderr: !options.Detach,
+		HostConfig: engineHostConfig{
+			Binds:      options.Volumes,
+			Privileged: options.Privileged,
+			// Foreground containers are removed by runWithEngineAPIE once their logs are read.
+			AutoRemove: options.Remove && options.Detach,
+		},
+	}
+
+	if options.Entrypoint != "" {
+		config.Entrypoint = []string{options.Entrypoint}
+	}
+
+	if options.Init {
+		config.HostConfig.Init = &options.Init
+	}
+
+	if err := applyEngineRunOtherOptions(options.OtherOptions, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// applyEngineRunOtherOptions applies the subset of 'docker run' flags in OtherOptions that the Engine API backend
+// supports: --publish, --publish-all, --label, --network, --env, --volume and --workdir, in their long and short
+// forms. Any other flag returns an error matching ErrUnsupportedByEngineAPI.
+func applyEngineRunOtherOptions(otherOptions []string, config *engineContainerConfig) error {
+	for i := 0; i < len(otherOptions); i++ {
+		flag, value, hasValue := strings.Cut(otherOptions[i], "=")
+
+		if flag == "-P" || flag == "--publish-all" {
+			config.HostConfig.PublishAllPorts = true
+			continue
+		}
+
+		if !hasValue {
+			if i+1 >= len(otherOptions) {
+				return fmt.Errorf("%w: missing value for %s", ErrUnsupportedByEngineAPI, flag)
+			}
+
+			i++
+			value = otherOptions[i]
+		}
+
+		switch flag {
+		case "-p", "--publish":
+			if err := addEnginePortBinding(value, config); err != nil {
+				return err
+			}
+		case "-l", "--label":
+			if config.Labels == nil {
+				config.Labels = map[string]string{}
+			}
+
+			key, labelValue, _ := strings.Cut(value, "=")
+			config.Labels[key] = labelValue
+		case "--network", "--net":
+			config.HostConfig.NetworkMode = value
+		case "-e", "--env":
+			config.Env = append(config.Env, value)
+		case "-v", "--volume":
+			config.HostConfig.Binds = append(config.HostConfig.Binds, value)
+		case "-w", "--workdir":
+			config.WorkingDir = value
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedByEngineAPI, otherOptions[i])
+		}
+	}
+
+	return nil
+}
+
+// addEnginePortBinding parses a port publishing spec in the '[ip:][hostPort:]containerPort[/protocol]' format used by
+// 'docker run --publish' and adds it to the container create request.
+func addEnginePortBinding(spec string, config *engineContainerConfig) error {
+	ports, protocol, found := strings.Cut(spec, "/")
+	if !found {
+		protocol = "tcp"
+	}
+
+	var binding enginePortBinding
+
+	parts := strings.Split(ports, ":")
+	containerPort := parts[len(parts)-1]
+
+	switch len(parts) {
+	case 1:
+	case 2: //nolint:mnd // hostPort:containerPort
+		binding.HostPort = parts[0]
+	case 3: //nolint:mnd // ip:hostPort:containerPort
+		binding.HostIP = parts[0]
+		binding.HostPort = parts[1]
+	default:
+		return fmt.Errorf("%w: invalid port spec %s", ErrUnsupportedByEngineAPI, spec)
+	}
+
+	if _, err := strconv.ParseUint(containerPort, 10, 16); err != nil {
+		return fmt.Errorf("%w: invalid port spec %s", ErrUnsupportedByEngineAPI, spec)
+	}
+
+	key := containerPort + "/" + protocol
+
+	if config.ExposedPorts == nil {
+		config.ExposedPorts = map[string]struct{}{}
+		config.HostConfig.PortBindings = map[string][]enginePortBinding{}
+	}
+
+	config.ExposedPorts[key] = struct{}{}
+	config.HostConfig.PortBindings[key] = append(config.HostConfig.PortBindings[key], binding)
+
+	return nil
+}
+
+// stopWithEngineAPIE implements StopContextE for BackendEngineAPI.
+func stopWithEngineAPIE(t testing.TestingT, ctx context.Context, containers []string, options *StopOptions) (string, error) {
+	client, err := NewEngineClientE()
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+
+	for _, id := range containers {
+		if err := client.StopContainerContextE(ctx, id, options.Time); err != nil {
+			return out.String(), err
+		}
+
+		options.Logger.Logf(t, "%s", id)
+		out.WriteString(id + "\n")
+	}
+
+	return out.String(), nil
+}
+
+// ExecContextE runs the given command in the given running container and returns its combined stdout and stderr. If
+// the command exits with a non-zero exit code, a ContainerExitError is returned. The ctx parameter supports
+// cancellation and timeouts.
+func (client *EngineClient) ExecContextE(ctx context.Context, id string, command []string) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+
+	execConfig := map[string]any{"AttachStdout": true, "AttachStderr": true, "Cmd": command}
+	if err := client.doJSON(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/exec", nil, execConfig, &created); err != nil {
+		return "", err
+	}
+
+	resp, err := client.do(
+		ctx,
+		http.MethodPost,
+		"/exec/"+url.PathEscape(created.ID)+"/start",
+		nil,
+		strings.NewReader(`{"Detach": false, "Tty": false}`),
+		map[string]string{"Content-Type": "application/json"},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+
+	err = demuxContainerOutput(resp.Body, &out, &out)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return out.String(), err
+	}
+
+	var inspect struct {
+		ExitCode int `json:"ExitCode"`
+	}
+
+	if err := client.doJSON(ctx, http.MethodGet, "/exec/"+url.PathEscape(created.ID)+"/json", nil, nil, &inspect); err != nil {
+		return out.String(), err
+	}
+
+	if inspect.ExitCode != 0 {
+		return out.String(), ContainerExitError{ID: id, ExitCode: inspect.ExitCode, Output: out.String()}
+	}
+
+	return out.String(), nil
+}
+
+// CopyToContainerContextE copies the local file or directory at src into the directory destDir of the given container.
+// The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) CopyToContainerContextE(ctx context.Context, id string, src string, destDir string) error {
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(writeTarArchive(src, filepath.Base(src), writer, nil, false))
+	}()
+
+	query := url.Values{"path": {destDir}}
+
+	resp, err := client.do(ctx, http.MethodPut, "/containers/"+url.PathEscape(id)+"/archive", query, reader, map[string]string{"Content-Type": "application/x-tar"})
+
+	// Unblock the tar writer if the request ended before the whole archive was sent
+	_ = reader.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// CopyFromContainerContextE copies the file or directory at src in the given container into the local directory
+// destDir. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) CopyFromContainerContextE(ctx context.Context, id string, src string, destDir string) error {
+	query := url.Values{"path": {src}}
+
+	resp, err := client.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/archive", query, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	return extractTarArchive(resp.Body, destDir)
+}
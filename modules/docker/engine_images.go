@@ -0,0 +1,321 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// engineImageSummary is a single entry of the image list endpoint.
+type engineImageSummary struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+	Created     int64    `json:"Created"`
+	Size        int64    `json:"Size"`
+	SharedSize  int64    `json:"SharedSize"`
+	Containers  int64    `json:"Containers"`
+}
+
+// PullImageContextE pulls the given image, optionally for a specific platform such as linux/arm64. The returned error
+// matches ErrImageNotFound if the image does not exist. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) PullImageContextE(t testing.TestingT, ctx context.Context, logger *logger.Logger, image string, platform string) error {
+	logger.Logf(t, "Pulling image %s", image)
+
+	repository, tag := splitImageReference(image)
+
+	query := url.Values{"fromImage": {repository}, "tag": {tag}}
+	if platform != "" {
+		query.Set("platform", platform)
+	}
+
+	auth, err := registryAuthHeader(image)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.do(ctx, http.MethodPost, "/images/create", query, nil, map[string]string{"X-Registry-Auth": auth})
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	return readJSONMessages(t, logger, "pull", resp.Body)
+}
+
+// PushImageContextE pushes the given tag to its registry, using the credentials from the docker config file and
+// credential helpers. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) PushImageContextE(t testing.TestingT, ctx context.Context, logger *logger.Logger, tag string) error {
+	logger.Logf(t, "Pushing image %s", tag)
+
+	repository, imageTag := splitImageReference(tag)
+
+	auth, err := registryAuthHeader(tag)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"tag": {imageTag}}
+
+	resp, err := client.do(ctx, http.MethodPost, "/images/"+repository+"/push", query, nil, map[string]string{"X-Registry-Auth": auth})
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	return readJSONMessages(t, logger, "push", resp.Body)
+}
+
+// RemoveImageContextE removes the given image. The returned error matches ErrImageNotFound if the image does not
+// exist. The ctx parameter supports cancellation and timeouts.
+func (client *EngineClient) RemoveImageContextE(ctx context.Context, image string) error {
+	return client.doJSON(ctx, http.MethodDelete, "/images/"+image, nil, nil, nil)
+}
+
+// ListImagesContextE lists the images in the daemon, with one entry per tag like 'docker images'. The ctx parameter
+// supports cancellation and timeouts.
+func (client *EngineClient) ListImagesContextE(ctx context.Context) ([]Image, error) {
+	var summaries []engineImageSummary
+	if err := client.doJSON(ctx, http.MethodGet, "/images/json", nil, nil, &summaries); err != nil {
+		return nil, err
+	}
+
+	images := []Image{}
+
+	for _, summary := range summaries {
+		id := strings.TrimPrefix(summary.ID, "sha256:")
+		if len(id) > 12 { //nolint:mnd // 'docker images' shows the first 12 characters of the ID
+			id = id[:12]
+		}
+
+		var digest string
+		if len(summary.RepoDigests) > 0 {
+			_, digest, _ = strings.Cut(summary.RepoDigests[0], "@")
+		}
+
+		image := Image{
+			ID:          id,
+			CreatedAt:   time.Unix(summary.Created, 0).Format("2006-01-02 15:04:05 -0700 MST"),
+			VirtualSize: strconv.FormatInt(summary.Size, 10) + "B",
+			SharedSize:  strconv.FormatInt(summary.SharedSize, 10) + "B",
+			UniqueSize:  strconv.FormatInt(summary.Size-max(summary.SharedSize, 0), 10) + "B",
+			Containers:  strconv.FormatInt(summary.Containers, 10),
+			Digest:      digest,
+		}
+
+		if len(summary.RepoTags) == 0 {
+			image.Repository, image.Tag = "<none>", "<none>"
+			images = append(images, image)
+
+			continue
+		}
+
+		for _, repoTag := range summary.RepoTags {
+			image.Repository, image.Tag = splitImageReference(repoTag)
+			images = append(images, image)
+		}
+	}
+
+	return images, nil
+}
+
+// buildWithEngineAPIE implements BuildContextE for BackendEngineAPI.
+func buildWithEngineAPIE(t testing.TestingT, ctx context.Context, path string, options *BuildOptions) error {
+	switch {
+	case len(options.Architectures) > 0:
+		return fmt.Errorf("%w: Architectures", ErrUnsupportedByEngineAPI)
+	case len(options.OtherOptions) > 0:
+		return fmt.Errorf("%w: OtherOptions", ErrUnsupportedByEngineAPI)
+	case options.EnableBuildKit:
+		return fmt.Errorf("%w: EnableBuildKit", ErrUnsupportedByEngineAPI)
+	}
+
+	client, err := NewEngineClientE()
+	if err != nil {
+		return err
+	}
+
+	buildArgs := map[string]string{}
+
+	for _, arg := range options.BuildArgs {
+		key, value, found := strings.Cut(arg, "=")
+		if !found {
+			// Like 'docker build', a build arg without a value takes its value from the environment
+			value = os.Getenv(key)
+		}
+
+		buildArgs[key] = value
+	}
+
+	buildArgsJSON, err := json.Marshal(buildArgs)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"t": options.Tags, "buildargs": {string(buildArgsJSON)}, "rm": {"1"}}
+	if options.Target != "" {
+		query.Set("target", options.Target)
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(writeBuildContextTar(path, writer))
+	}()
+
+	resp, err := client.do(ctx, http.MethodPost, "/build", query, reader, map[string]string{"Content-Type": "application/x-tar"})
+
+	// Unblock the tar writer if the request ended before the whole context was sent
+	_ = reader.Close()
+
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := readJSONMessages(t, options.Logger, "build", resp.Body); err != nil {
+		return err
+	}
+
+	if !options.Push {
+		return nil
+	}
+
+	errorsOccurred := new(multierror.Error)
+
+	for _, tag := range options.Tags {
+		if err := client.PushImageContextE(t, ctx, options.Logger, tag); err != nil {
+			options.Logger.Logf(t, "ERROR: error pushing tag %s", tag)
+
+			errorsOccurred = multierror.Append(errorsOccurred, err)
+		}
+	}
+
+	return errorsOccurred.ErrorOrNil()
+}
+
+// writeBuildContextTar writes the build context at the given path as a tar archive, skipping the files excluded by a
+// .dockerignore file in its root. Only simple .dockerignore patterns are supported: each pattern is matched with
+// filepath.Match against the path of a file and each of its parent directories, and patterns starting with ! re-include
+// files.
+func writeBuildContextTar(path string, out io.Writer) error {
+	patterns, err := readDockerignore(path)
+	if err != nil {
+		return err
+	}
+
+	return writeTarArchive(path, "", out, func(relPath string) bool {
+		return isDockerignored(relPath, patterns)
+	}, hasDockerignoreException(patterns))
+}
+
+// readDockerignore returns the patterns in the .dockerignore file at the root of the build context, if there is one.
+func readDockerignore(path string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(path, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, strings.TrimPrefix(filepath.ToSlash(filepath.Clean(line)), "/"))
+	}
+
+	return patterns, nil
+}
+
+// isDockerignored returns whether the given path, relative to the build context, is excluded by the patterns. Later
+// patterns take precedence over earlier ones.
+func isDockerignored(relPath string, patterns []string) bool {
+	ignored := false
+
+	for _, pattern := range patterns {
+		exception := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		for candidate := relPath; candidate != "."; candidate = filepath.ToSlash(filepath.Dir(candidate)) {
+			if matched, _ := filepath.Match(pattern, candidate); matched {
+				ignored = !exception
+				break
+			}
+		}
+	}
+
+	return ignored
+}
+
+// hasDockerignoreException returns whether any of the patterns re-includes files, in which case ignored directories
+// still need to be walked.
+func hasDockerignoreException(patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitImageReference splits an image reference into its repository and its tag or digest, defaulting to the latest
+// tag.
+func splitImageReference(image string) (string, string) {
+	if repository, digest, found := strings.Cut(image, "@"); found {
+		return repository, digest
+	}
+
+	lastSlash := strings.LastIndex(image, "/")
+	if lastColon := strings.LastIndex(image, ":"); lastColon > lastSlash {
+		return image[:lastColon], image[lastColon+1:]
+	}
+
+	return image, "latest"
+}
+
+// registryAuthHeader returns the X-Registry-Auth header for the registry of the given image, using the credentials
+// from the docker config file and credential helpers.
+func registryAuthHeader(image string) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	authenticator, err := authn.DefaultKeychain.Resolve(ref.Context())
+	if err != nil {
+		return "", err
+	}
+
+	config, err := authenticator.Authorization()
+	if err != nil {
+		return "", err
+	}
+
+	return encodeRegistryAuth(config.Username, config.Password, config.IdentityToken)
+}
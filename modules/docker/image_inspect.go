@@ -0,0 +1,583 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+var (
+	// ErrImageTooLarge is returned when an image is larger than the allowed maximum size.
+	ErrImageTooLarge = errors.New("image is too large")
+
+	// ErrMissingPlatforms is returned when a multi-arch manifest does not contain every expected platform.
+	ErrMissingPlatforms = errors.New("image manifest is missing platforms")
+)
+
+// metadataInstructions are the Dockerfile instructions that only change the image config and never create a layer.
+var metadataInstructions = []string{
+	"ARG", "CMD", "ENTRYPOINT", "ENV", "EXPOSE", "HEALTHCHECK", "LABEL", "MAINTAINER", "ONBUILD", "SHELL", "STOPSIGNAL",
+	"USER", "VOLUME",
+}
+
+// InspectImageOptions defines options that can be passed to the image inspection functions.
+type InspectImageOptions struct {
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+
+	// Backend selects how to talk to the Docker daemon when inspecting local images.
+	Backend Backend
+
+	// If set to true, registries are accessed over plain HTTP when inspecting manifests. Use this for local test
+	// registries without TLS.
+	Insecure bool
+}
+
+// ImageInspect is the typed inspect data of a local image, as returned by InspectImageContextE.
+type ImageInspect struct {
+	// Time the image was created
+	Created time.Time
+
+	// Labels set on the image config, for convenience. The same as Config.Labels.
+	Labels map[string]string
+
+	// ID of the image
+	ID string
+
+	// Architecture the image was built for, such as amd64 or arm64
+	Architecture string
+
+	// Operating system the image was built for, such as linux
+	OS string
+
+	// Variant of the architecture, such as v8 for arm64, if any
+	Variant string
+
+	// Tags referring to the image
+	RepoTags []string
+
+	// Digests the image was pushed or pulled with
+	RepoDigests []string
+
+	// Layers of the image, from the base layer up
+	Layers []ImageLayer
+
+	// History of the image, from the oldest to the newest instruction, including instructions that did not create a
+	// layer
+	History []ImageHistoryEntry
+
+	// Config of the image
+	Config ImageConfig
+
+	// Total size of the image in bytes
+	Size int64
+}
+
+// ImageConfig is the config of an image, which is used as the defaults for containers created from it.
+type ImageConfig struct {
+	// Labels set on the image
+	Labels map[string]string
+
+	// User that commands are run as
+	User string
+
+	// Working directory that commands are run in
+	WorkingDir string
+
+	// Signal sent to stop containers
+	StopSignal string
+
+	// Default entrypoint
+	Entrypoint []string
+
+	// Default command
+	Cmd []string
+
+	// Environment variables, in KEY=VALUE format
+	Env []string
+
+	// Exposed ports, such as 80/tcp, sorted
+	ExposedPorts []string
+
+	// Volumes, sorted
+	Volumes []string
+}
+
+// ImageLayer is a single layer of an image.
+type ImageLayer struct {
+	// Digest of the uncompressed layer contents (the diff ID)
+	Digest string
+
+	// Instruction that created the layer, if it could be determined from the image history
+	CreatedBy string
+
+	// Size of the layer in bytes, if it could be determined from the image history
+	Size int64
+}
+
+// ImageHistoryEntry is a single instruction in the history of an image.
+type ImageHistoryEntry struct {
+	// Time the instruction was run
+	Created time.Time
+
+	// Instruction, such as "RUN /bin/sh -c apk add curl"
+	CreatedBy string
+
+	// Comment, if any
+	Comment string
+
+	// Size in bytes of the layer created by the instruction, 0 if it did not create a layer
+	Size int64
+}
+
+// ImageManifest is a single entry of an image manifest list, as pushed to a registry.
+type ImageManifest struct {
+	// Digest of the manifest
+	Digest string
+
+	// Media type of the manifest
+	MediaType string
+
+	// Platform of the manifest, such as linux/arm64/v8
+	Platform string
+
+	// Operating system of the manifest
+	OS string
+
+	// Architecture of the manifest
+	Architecture string
+
+	// Variant of the architecture, if any
+	Variant string
+
+	// Size of the manifest in bytes
+	Size int64
+}
+
+// imageInspectOutput defines the fields returned by 'docker image inspect', in JSON format. Not all fields are
+// included here, only the ones that we might need.
+type imageInspectOutput struct {
+	Config struct {
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Volumes      map[string]struct{} `json:"Volumes"`
+		Labels       map[string]string   `json:"Labels"`
+		User         string              `json:"User"`
+		WorkingDir   string              `json:"WorkingDir"`
+		StopSignal   string              `json:"StopSignal"`
+		Entrypoint   []string            `json:"Entrypoint"`
+		Cmd          []string            `json:"Cmd"`
+		Env          []string            `json:"Env"`
+	} `json:"Config"`
+	ID           string   `json:"Id"`
+	Created      string   `json:"Created"`
+	Architecture string   `json:"Architecture"`
+	OS           string   `json:"Os"`
+	Variant      string   `json:"Variant"`
+	RepoTags     []string `json:"RepoTags"`
+	RepoDigests  []string `json:"RepoDigests"`
+	RootFS       struct {
+		Layers []string `json:"Layers"`
+	} `json:"RootFS"`
+	Size int64 `json:"Size"`
+}
+
+// imageHistoryOutput is a single entry of the image history endpoint.
+type imageHistoryOutput struct {
+	CreatedBy string `json:"CreatedBy"`
+	Comment   string `json:"Comment"`
+	Created   int64  `json:"Created"`
+	Size      int64  `json:"Size"`
+}
+
+// imageHistoryCLIOutput is a single line of 'docker history --human=false --format "{{json .}}"'.
+type imageHistoryCLIOutput struct {
+	CreatedAt string `json:"CreatedAt"`
+	CreatedBy string `json:"CreatedBy"`
+	Comment   string `json:"Comment"`
+	Size      string `json:"Size"`
+}
+
+// InspectImageContextE returns the typed inspect data of the given local image, including its config, layers and
+// history. The returned error matches ErrImageNotFound if the image does not exist when using BackendEngineAPI. The
+// ctx parameter supports cancellation and timeouts.
+func InspectImageContextE(t testing.TestingT, ctx context.Context, image string, options *InspectImageOptions) (*ImageInspect, error) {
+	inspect, err := imageInspectE(t, ctx, image, options.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := imageHistoryE(t, ctx, image, options.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return transformImage(inspect, history)
+}
+
+// InspectImageContext returns the typed inspect data of the given local image, including its config, layers and
+// history. This will fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func InspectImageContext(t testing.TestingT, ctx context.Context, image string, options *InspectImageOptions) *ImageInspect {
+	t.Helper()
+
+	inspect, err := InspectImageContextE(t, ctx, image, options)
+	require.NoError(t, err)
+
+	return inspect
+}
+
+// InspectImageManifestsContextE returns the manifests of the given image in its registry, with the platform of each.
+// For multi-arch images, such as those built with BuildOptions.Architectures and pushed, there is one entry per
+// platform; for single platform images there is a single entry. Credentials are taken from the docker config file
+// and credential helpers. The ctx parameter supports cancellation and timeouts.
+func InspectImageManifestsContextE(t testing.TestingT, ctx context.Context, image string, options *InspectImageOptions) ([]ImageManifest, error) {
+	options.Logger.Logf(t, "Inspecting manifests of image %s", image)
+
+	var nameOptions []name.Option
+	if options.Insecure {
+		nameOptions = append(nameOptions, name.Insecure)
+	}
+
+	ref, err := name.ParseReference(image, nameOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageManifestsE(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+}
+
+// imageManifestsE returns the manifests of the given image in its registry, with the platform of each.
+func imageManifestsE(ref name.Reference, remoteOptions ...remote.Option) ([]ImageManifest, error) {
+	descriptor, err := remote.Get(ref, remoteOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if descriptor.MediaType.IsIndex() {
+		index, err := descriptor.ImageIndex()
+		if err != nil {
+			return nil, err
+		}
+
+		indexManifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, err
+		}
+
+		manifests := make([]ImageManifest, 0, len(indexManifest.Manifests))
+		for _, manifest := range indexManifest.Manifests {
+			manifests = append(manifests, newImageManifest(manifest.Digest.String(), string(manifest.MediaType), manifest.Size, manifest.Platform))
+		}
+
+		return manifests, nil
+	}
+
+	img, err := descriptor.Image()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return []ImageManifest{newImageManifest(descriptor.Digest.String(), string(descriptor.MediaType), descriptor.Size, config.Platform())}, nil
+}
+
+// InspectImageManifestsContext returns the manifests of the given image in its registry, with the platform of each.
+// This will fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func InspectImageManifestsContext(t testing.TestingT, ctx context.Context, image string, options *InspectImageOptions) []ImageManifest {
+	t.Helper()
+
+	manifests, err := InspectImageManifestsContextE(t, ctx, image, options)
+	require.NoError(t, err)
+
+	return manifests
+}
+
+// RequireMaxImageSizeContextE returns an error matching ErrImageTooLarge if the given local image is larger than
+// maxSizeBytes. The ctx parameter supports cancellation and timeouts.
+func RequireMaxImageSizeContextE(t testing.TestingT, ctx context.Context, image string, maxSizeBytes int64, options *InspectImageOptions) error {
+	inspect, err := imageInspectE(t, ctx, image, options.Backend)
+	if err != nil {
+		return err
+	}
+
+	if inspect.Size > maxSizeBytes {
+		return fmt.Errorf("%w: %s is %d bytes, the maximum is %d bytes", ErrImageTooLarge, image, inspect.Size, maxSizeBytes)
+	}
+
+	return nil
+}
+
+// RequireMaxImageSizeContext fails the test if the given local image is larger than maxSizeBytes. The ctx parameter
+// supports cancellation and timeouts.
+func RequireMaxImageSizeContext(t testing.TestingT, ctx context.Context, image string, maxSizeBytes int64, options *InspectImageOptions) {
+	t.Helper()
+	require.NoError(t, RequireMaxImageSizeContextE(t, ctx, image, maxSizeBytes, options))
+}
+
+// RequireImagePlatformsContextE returns an error matching ErrMissingPlatforms if the manifest of the given image in
+// its registry does not contain every one of the given platforms, such as the BuildOptions.Architectures the image was
+// built with. Platforms are in os/arch[/variant] format; a platform without a variant matches any variant. The ctx
+// parameter supports cancellation and timeouts.
+func RequireImagePlatformsContextE(t testing.TestingT, ctx context.Context, image string, platforms []string, options *InspectImageOptions) error {
+	manifests, err := InspectImageManifestsContextE(t, ctx, image, options)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+
+	for _, platform := range platforms {
+		found := slices.ContainsFunc(manifests, func(manifest ImageManifest) bool {
+			return manifest.Platform == platform || manifest.OS+"/"+manifest.Architecture == platform
+		})
+		if !found {
+			missing = append(missing, platform)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s does not contain %s", ErrMissingPlatforms, image, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// RequireImagePlatformsContext fails the test if the manifest of the given image in its registry does not contain
+// every one of the given platforms. The ctx parameter supports cancellation and timeouts.
+func RequireImagePlatformsContext(t testing.TestingT, ctx context.Context, image string, platforms []string, options *InspectImageOptions) {
+	t.Helper()
+	require.NoError(t, RequireImagePlatformsContextE(t, ctx, image, platforms, options))
+}
+
+// newImageManifest converts a manifest descriptor into an ImageManifest.
+func newImageManifest(digest string, mediaType string, size int64, platform *v1.Platform) ImageManifest {
+	manifest := ImageManifest{Digest: digest, MediaType: mediaType, Size: size}
+
+	if platform != nil {
+		manifest.OS = platform.OS
+		manifest.Architecture = platform.Architecture
+		manifest.Variant = platform.Variant
+		manifest.Platform = platform.OS + "/" + platform.Architecture
+
+		if platform.Variant != "" {
+			manifest.Platform += "/" + platform.Variant
+		}
+	}
+
+	return manifest
+}
+
+// imageInspectE returns the raw inspect data of the given local image.
+func imageInspectE(t testing.TestingT, ctx context.Context, image string, backend Backend) (*imageInspectOutput, error) {
+	if backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return nil, err
+		}
+
+		var inspect imageInspectOutput
+		if err := client.doJSON(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil, &inspect); err != nil {
+			return nil, err
+		}
+
+		return &inspect, nil
+	}
+
+	cmd := &shell.Command{
+		Command: "docker",
+		Args:    []string{"image", "inspect", image},
+		// inspect is a short-running command, don't print the output.
+		Logger: logger.Discard,
+	}
+
+	out, err := shell.RunCommandContextAndGetStdOutE(t, ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []imageInspectOutput
+	if err := json.Unmarshal([]byte(out), &images); err != nil {
+		return nil, err
+	}
+
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, image)
+	}
+
+	return &images[0], nil
+}
+
+// imageHistoryE returns the history of the given local image, from the oldest to the newest instruction.
+func imageHistoryE(t testing.TestingT, ctx context.Context, image string, backend Backend) ([]ImageHistoryEntry, error) {
+	var history []ImageHistoryEntry
+
+	if backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []imageHistoryOutput
+		if err := client.doJSON(ctx, http.MethodGet, "/images/"+image+"/history", nil, nil, &entries); err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			history = append(history, ImageHistoryEntry{
+				Created:   time.Unix(entry.Created, 0).UTC(),
+				CreatedBy: entry.CreatedBy,
+				Comment:   entry.Comment,
+				Size:      entry.Size,
+			})
+		}
+	} else {
+		cmd := &shell.Command{
+			Command: "docker",
+			Args:    []string{"history", "--no-trunc", "--human=false", "--format", "{{ json . }}", image},
+			Logger:  logger.Discard,
+		}
+
+		out, err := shell.RunCommandContextAndGetStdOutE(t, ctx, cmd)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(strings.NewReader(out))
+		for scanner.Scan() {
+			var entry imageHistoryCLIOutput
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				return nil, err
+			}
+
+			size, err := strconv.ParseInt(entry.Size, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			// The creation time is informational, so a format change should not make inspection fail.
+			created, _ := time.Parse(time.RFC3339, entry.CreatedAt)
+
+			history = append(history, ImageHistoryEntry{Created: created, CreatedBy: entry.CreatedBy, Comment: entry.Comment, Size: size})
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Both the CLI and the API list the newest instruction first
+	slices.Reverse(history)
+
+	return history, nil
+}
+
+// transformImage converts the raw image inspect data and history into an ImageInspect.
+func transformImage(inspect *imageInspectOutput, history []ImageHistoryEntry) (*ImageInspect, error) {
+	created, err := time.Parse(time.RFC3339Nano, inspect.Created)
+	if err != nil {
+		return nil, err
+	}
+
+	config := ImageConfig{
+		Labels:       inspect.Config.Labels,
+		User:         inspect.Config.User,
+		WorkingDir:   inspect.Config.WorkingDir,
+		StopSignal:   inspect.Config.StopSignal,
+		Entrypoint:   inspect.Config.Entrypoint,
+		Cmd:          inspect.Config.Cmd,
+		Env:          inspect.Config.Env,
+		ExposedPorts: sortedSetKeys(inspect.Config.ExposedPorts),
+		Volumes:      sortedSetKeys(inspect.Config.Volumes),
+	}
+
+	return &ImageInspect{
+		Created:      created,
+		Labels:       inspect.Config.Labels,
+		ID:           inspect.ID,
+		Architecture: inspect.Architecture,
+		OS:           inspect.OS,
+		Variant:      inspect.Variant,
+		RepoTags:     inspect.RepoTags,
+		RepoDigests:  inspect.RepoDigests,
+		Layers:       imageLayers(inspect.RootFS.Layers, history),
+		History:      history,
+		Config:       config,
+		Size:         inspect.Size,
+	}, nil
+}
+
+// imageLayers matches the layer digests of an image with the history entries that created them. The history does not
+// say which instructions created a layer, so instructions that have a size, or that are not metadata-only
+// instructions, are assumed to have created one. If that does not account for every layer, only the digests are
+// returned.
+func imageLayers(digests []string, history []ImageHistoryEntry) []ImageLayer {
+	layers := make([]ImageLayer, 0, len(digests))
+	for _, digest := range digests {
+		layers = append(layers, ImageLayer{Digest: digest})
+	}
+
+	var creators []ImageHistoryEntry
+
+	for _, entry := range history {
+		if entry.Size > 0 || !isMetadataInstruction(entry.CreatedBy) {
+			creators = append(creators, entry)
+		}
+	}
+
+	if len(creators) != len(layers) {
+		return layers
+	}
+
+	for i := range layers {
+		layers[i].CreatedBy = creators[i].CreatedBy
+		layers[i].Size = creators[i].Size
+	}
+
+	return layers
+}
+
+// isMetadataInstruction returns whether the given history instruction only changes the image config.
+func isMetadataInstruction(createdBy string) bool {
+	instruction := strings.TrimSpace(createdBy)
+
+	// Instructions from the classic builder are prefixed with "/bin/sh -c #(nop) "
+	if _, after, found := strings.Cut(instruction, "#(nop)"); found {
+		instruction = strings.TrimSpace(after)
+	}
+
+	keyword, _, _ := strings.Cut(instruction, " ")
+
+	return slices.Contains(metadataInstructions, strings.ToUpper(keyword))
+}
+
+// sortedSetKeys returns the keys of a JSON set, as used by the image config for ports and volumes, in sorted order.
+func sortedSetKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
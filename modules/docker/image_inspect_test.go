@@ -0,0 +1,78 @@
+package docker_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/docker"
+)
+
+func TestInspectImage(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	options := &docker.InspectImageOptions{}
+
+	docker.RunContext(t, ctx, dockerInspectTestImage, &docker.RunOptions{Remove: true, Command: []string{"true"}})
+
+	image := docker.InspectImageContext(t, ctx, dockerInspectTestImage, options)
+
+	require.Equal(t, "linux", image.OS)
+	require.Contains(t, image.Config.ExposedPorts, "80/tcp")
+	require.Equal(t, []string{"nginx", "-g", "daemon off;"}, image.Config.Cmd)
+	require.NotEmpty(t, image.Layers)
+	require.NotEmpty(t, image.History)
+
+	for _, layer := range image.Layers {
+		require.True(t, strings.HasPrefix(layer.Digest, "sha256:"))
+	}
+
+	docker.RequireMaxImageSizeContext(t, ctx, dockerInspectTestImage, image.Size, options)
+	require.ErrorIs(t, docker.RequireMaxImageSizeContextE(t, ctx, dockerInspectTestImage, image.Size-1, options), docker.ErrImageTooLarge)
+}
+
+func TestInspectImageManifests(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+
+	index := v1.ImageIndex(empty.Index)
+
+	for _, platform := range []v1.Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64", Variant: "v8"}} {
+		img, err := random.Image(1024, 1)
+		require.NoError(t, err)
+
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: &platform}})
+	}
+
+	image := strings.TrimPrefix(server.URL, "http://") + "/multiarch:latest"
+
+	ref, err := name.ParseReference(image, name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(ref, index))
+
+	ctx := t.Context()
+	options := &docker.InspectImageOptions{Insecure: true}
+
+	manifests := docker.InspectImageManifestsContext(t, ctx, image, options)
+	require.Len(t, manifests, 2)
+	require.Equal(t, "linux/amd64", manifests[0].Platform)
+	require.Equal(t, "linux/arm64/v8", manifests[1].Platform)
+
+	docker.RequireImagePlatformsContext(t, ctx, image, []string{"linux/amd64", "linux/arm64"}, options)
+
+	err = docker.RequireImagePlatformsContextE(t, ctx, image, []string{"linux/amd64", "linux/s390x"}, options)
+	require.ErrorIs(t, err, docker.ErrMissingPlatforms)
+	require.Contains(t, err.Error(), "linux/s390x")
+}
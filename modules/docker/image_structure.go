@@ -0,0 +1,578 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// maxSymlinkHops is the maximum number of symlinks followed when resolving a path in an image filesystem.
+const maxSymlinkHops = 40
+
+// ImageStructureExpectations declares what an image is expected to contain, in the spirit of container-structure-test.
+// Every expectation is checked and all the failures are reported together by VerifyImageStructureContextE.
+type ImageStructureExpectations struct {
+	// Metadata expectations for the image config. Leave nil to skip metadata checks.
+	Metadata *ImageMetadataExpectation
+
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+
+	// Files expected to exist, or not exist, in the image filesystem. The filesystem is read without running the image,
+	// so this works for images without a shell.
+	Files []ImageFileExpectation
+
+	// Commands to run in containers of the image, with expectations on their output and exit code.
+	Commands []ImageCommandExpectation
+
+	// Maximum number of layers in the image. Zero means no limit.
+	MaxLayers int
+
+	// Maximum size of the image in bytes. Zero means no limit.
+	MaxSizeBytes int64
+
+	// Backend selects how to talk to the Docker daemon.
+	Backend Backend
+}
+
+// ImageFileExpectation declares what is expected of a single path in an image filesystem.
+type ImageFileExpectation struct {
+	// Path of the file or directory in the image
+	Path string
+
+	// Permissions the path is expected to have, in the format of fs.FileMode.String(), such as "-rwxr-xr-x" or
+	// "drwxr-xr-x". Leave empty to skip the check.
+	Permissions string
+
+	// Owner the path is expected to have, as numeric "uid:gid", such as "0:0". Leave empty to skip the check.
+	Owner string
+
+	// Regular expressions that the contents of the file must match.
+	ExpectedContents []string
+
+	// Regular expressions that the contents of the file must not match.
+	ExcludedContents []string
+
+	// If set to true, the path is expected to not exist and all other expectations are ignored.
+	ShouldNotExist bool
+}
+
+// ImageMetadataExpectation declares what is expected of an image's config. Nil and empty fields are not checked.
+type ImageMetadataExpectation struct {
+	// Env variables that must be set to the given values. Other variables may also be set.
+	Env map[string]string
+
+	// Labels that must be set to the given values. Other labels may also be set.
+	Labels map[string]string
+
+	// Expected user, such as "nobody" or "1000:1000"
+	User string
+
+	// Expected working directory
+	WorkingDir string
+
+	// Expected entrypoint, compared exactly
+	Entrypoint []string
+
+	// Expected default command, compared exactly
+	Cmd []string
+
+	// Ports that must be exposed, such as "80/tcp". Other ports may also be exposed.
+	ExposedPorts []string
+}
+
+// ImageCommandExpectation declares a command to run in a container of the image and what its output must look like.
+type ImageCommandExpectation struct {
+	// Name of the check, used in failure messages
+	Name string
+
+	// Override the image's ENTRYPOINT for the command
+	Entrypoint string
+
+	// Command to run, passed to the container as its COMMAND
+	Command []string
+
+	// Environment variables to set for the command
+	EnvironmentVariables []string
+
+	// Regular expressions that the combined stdout and stderr must match
+	ExpectedOutput []string
+
+	// Regular expressions that the combined stdout and stderr must not match
+	ExcludedOutput []string
+
+	// Expected exit code of the command
+	ExitCode int
+}
+
+// ImageStructureMismatch is returned by VerifyImageStructureContextE when an image does not meet its expectations.
+type ImageStructureMismatch struct {
+	Image    string
+	Failures []string
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err ImageStructureMismatch) Error() string {
+	return fmt.Sprintf("image %s does not match %d expectation(s):\n  - %s", err.Image, len(err.Failures), strings.Join(err.Failures, "\n  - "))
+}
+
+// imageFileEntry is a single entry of an exported image filesystem.
+type imageFileEntry struct {
+	header   *tar.Header
+	contents []byte
+}
+
+// VerifyImageStructureContextE checks the given image against the expectations and returns an
+// ImageStructureMismatch listing every expectation that was not met. It returns other errors if the image cannot be
+// inspected, exported or run. The ctx parameter supports cancellation and timeouts.
+func VerifyImageStructureContextE(t testing.TestingT, ctx context.Context, image string, expectations *ImageStructureExpectations) error {
+	expectations.Logger.Logf(t, "Verifying structure of image %s", image)
+
+	inspect, err := InspectImageContextE(t, ctx, image, &InspectImageOptions{Logger: expectations.Logger, Backend: expectations.Backend})
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+
+	failures = append(failures, checkImageMetadata(inspect, expectations)...)
+
+	if len(expectations.Files) > 0 {
+		files, err := exportImageFilesE(t, ctx, image, expectations)
+		if err != nil {
+			return err
+		}
+
+		for _, expectation := range expectations.Files {
+			failures = append(failures, checkImageFile(files, expectation)...)
+		}
+	}
+
+	for _, expectation := range expectations.Commands {
+		commandFailures, err := checkImageCommandE(t, ctx, image, expectations, expectation)
+		if err != nil {
+			return err
+		}
+
+		failures = append(failures, commandFailures...)
+	}
+
+	if len(failures) > 0 {
+		return ImageStructureMismatch{Image: image, Failures: failures}
+	}
+
+	return nil
+}
+
+// VerifyImageStructureContext checks the given image against the expectations. This will fail the test with every
+// expectation that was not met. The ctx parameter supports cancellation and timeouts.
+func VerifyImageStructureContext(t testing.TestingT, ctx context.Context, image string, expectations *ImageStructureExpectations) {
+	t.Helper()
+	require.NoError(t, VerifyImageStructureContextE(t, ctx, image, expectations))
+}
+
+// checkImageMetadata returns the metadata, layer count and size expectations that the image does not meet.
+func checkImageMetadata(inspect *ImageInspect, expectations *ImageStructureExpectations) []string {
+	var failures []string
+
+	if expectations.MaxLayers > 0 && len(inspect.Layers) > expectations.MaxLayers {
+		failures = append(failures, fmt.Sprintf("image has %d layers, more than the maximum of %d", len(inspect.Layers), expectations.MaxLayers))
+	}
+
+	if expectations.MaxSizeBytes > 0 && inspect.Size > expectations.MaxSizeBytes {
+		failures = append(failures, fmt.Sprintf("image is %d bytes, more than the maximum of %d", inspect.Size, expectations.MaxSizeBytes))
+	}
+
+	metadata := expectations.Metadata
+	if metadata == nil {
+		return failures
+	}
+
+	config := inspect.Config
+
+	if metadata.Entrypoint != nil && !slices.Equal(metadata.Entrypoint, config.Entrypoint) {
+		failures = append(failures, fmt.Sprintf("entrypoint is %q, expected %q", config.Entrypoint, metadata.Entrypoint))
+	}
+
+	if metadata.Cmd != nil && !slices.Equal(metadata.Cmd, config.Cmd) {
+		failures = append(failures, fmt.Sprintf("cmd is %q, expected %q", config.Cmd, metadata.Cmd))
+	}
+
+	if metadata.User != "" && metadata.User != config.User {
+		failures = append(failures, fmt.Sprintf("user is %q, expected %q", config.User, metadata.User))
+	}
+
+	if metadata.WorkingDir != "" && metadata.WorkingDir != config.WorkingDir {
+		failures = append(failures, fmt.Sprintf("working dir is %q, expected %q", config.WorkingDir, metadata.WorkingDir))
+	}
+
+	for _, port := range metadata.ExposedPorts {
+		if !strings.Contains(port, "/") {
+			port += "/tcp"
+		}
+
+		if !slices.Contains(config.ExposedPorts, port) {
+			failures = append(failures, fmt.Sprintf("port %s is not exposed", port))
+		}
+	}
+
+	env := map[string]string{}
+
+	for _, envVar := range config.Env {
+		key, value, _ := strings.Cut(envVar, "=")
+		env[key] = value
+	}
+
+	for _, key := range sortedKeys(metadata.Env) {
+		if value, ok := env[key]; !ok || value != metadata.Env[key] {
+			failures = append(failures, fmt.Sprintf("env %s is %q, expected %q", key, value, metadata.Env[key]))
+		}
+	}
+
+	for _, key := range sortedKeys(metadata.Labels) {
+		if value, ok := config.Labels[key]; !ok || value != metadata.Labels[key] {
+			failures = append(failures, fmt.Sprintf("label %s is %q, expected %q", key, value, metadata.Labels[key]))
+		}
+	}
+
+	return failures
+}
+
+// exportImageFilesE exports the filesystem of the given image and returns the entries that the file expectations
+// refer to, either directly or through symlinks, keyed by their absolute path.
+func exportImageFilesE(t testing.TestingT, ctx context.Context, image string, expectations *ImageStructureExpectations) (map[string]*imageFileEntry, error) {
+	exportDir, err := os.MkdirTemp("", "terratest-image-structure-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = os.RemoveAll(exportDir) }()
+
+	exportFile := filepath.Join(exportDir, "rootfs.tar")
+
+	if err := exportImageFilesystemE(t, ctx, image, exportFile, expectations); err != nil {
+		return nil, err
+	}
+
+	// The archive is read twice: once to index every entry, so that symlinks can be resolved, and once to load the
+	// contents of only the files that have contents expectations.
+	files := map[string]*imageFileEntry{}
+
+	err = readTarFileEntries(exportFile, func(name string, header *tar.Header, _ io.Reader) error {
+		files[name] = &imageFileEntry{header: header}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	withContents := map[string]bool{}
+
+	for _, expectation := range expectations.Files {
+		if len(expectation.ExpectedContents) == 0 && len(expectation.ExcludedContents) == 0 {
+			continue
+		}
+
+		if resolved, ok := resolveImagePath(files, path.Clean("/"+expectation.Path)); ok {
+			withContents[resolved] = true
+		}
+	}
+
+	if len(withContents) == 0 {
+		return files, nil
+	}
+
+	err = readTarFileEntries(exportFile, func(name string, header *tar.Header, contents io.Reader) error {
+		if !withContents[name] || header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		data, readErr := io.ReadAll(contents)
+		files[name].contents = data
+
+		return readErr
+	})
+
+	return files, err
+}
+
+// readTarFileEntries calls fn for every entry of the given tar file, with the entry's absolute path.
+func readTarFileEntries(file string, fn func(name string, header *tar.Header, contents io.Reader) error) error {
+	archive, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = archive.Close() }()
+
+	tarReader := tar.NewReader(archive)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := fn(path.Clean("/"+header.Name), header, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// exportImageFilesystemE writes the filesystem of the given image to the given tar file, by creating a container from
+// the image without starting it.
+func exportImageFilesystemE(t testing.TestingT, ctx context.Context, image string, exportFile string, expectations *ImageStructureExpectations) error {
+	// The entrypoint is never run, it is only set so that images without an entrypoint or command can be created.
+	const placeholderEntrypoint = "/terratest-image-structure"
+
+	if expectations.Backend == BackendEngineAPI {
+		client, err := NewEngineClientE()
+		if err != nil {
+			return err
+		}
+
+		id, err := client.CreateContainerContextE(t, ctx, image, &RunOptions{Entrypoint: placeholderEntrypoint, Logger: expectations.Logger})
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = client.RemoveContainerContextE(context.Background(), id, true) }()
+
+		resp, err := client.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/export", nil, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		return writeFileFrom(exportFile, 0o600, resp.Body) //nolint:mnd // owner read/write only
+	}
+
+	createCmd := &shell.Command{
+		Command: "docker",
+		Args:    []string{"create", "--entrypoint", placeholderEntrypoint, image},
+		Logger:  expectations.Logger,
+	}
+
+	id, err := shell.RunCommandContextAndGetStdOutE(t, ctx, createCmd)
+	if err != nil {
+		return err
+	}
+
+	id = strings.TrimSpace(id)
+
+	defer func() {
+		rmCmd := &shell.Command{Command: "docker", Args: []string{"rm", "--force", id}, Logger: logger.Discard}
+		_ = shell.RunCommandContextE(t, context.Background(), rmCmd)
+	}()
+
+	exportCmd := &shell.Command{
+		Command: "docker",
+		Args:    []string{"export", "--output", exportFile, id},
+		Logger:  expectations.Logger,
+	}
+
+	return shell.RunCommandContextE(t, ctx, exportCmd)
+}
+
+// resolveImagePath resolves the symlinks in every component of the given absolute path in an exported image
+// filesystem, and returns the resolved path and whether it exists.
+func resolveImagePath(files map[string]*imageFileEntry, name string) (string, bool) {
+	components := strings.Split(strings.TrimPrefix(path.Clean(name), "/"), "/")
+	resolved := "/"
+
+	for hops := 0; len(components) > 0; {
+		component := components[0]
+		components = components[1:]
+
+		if component == "" || component == "." {
+			continue
+		}
+
+		candidate := path.Join(resolved, component)
+
+		// The root directory has no entry of its own in the exported filesystem, but is reached by climbing up with ".."
+		if candidate == "/" {
+			resolved = candidate
+			continue
+		}
+
+		entry, ok := files[candidate]
+		if !ok {
+			return candidate, false
+		}
+
+		if entry.header.Typeflag != tar.TypeSymlink {
+			resolved = candidate
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return candidate, false
+		}
+
+		target := entry.header.Linkname
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+
+		components = append(strings.Split(target, "/"), components...)
+	}
+
+	return resolved, true
+}
+
+// checkImageFile returns the expectations on a single path that the exported image filesystem does not meet.
+func checkImageFile(files map[string]*imageFileEntry, expectation ImageFileExpectation) []string {
+	name := path.Clean("/" + expectation.Path)
+
+	// The path itself is looked up without resolving a final symlink, so that expectations can be made on symlinks.
+	parent, parentExists := resolveImagePath(files, path.Dir(name))
+	entry, exists := files[path.Join(parent, path.Base(name))]
+	exists = exists && parentExists
+
+	if expectation.ShouldNotExist {
+		if exists {
+			return []string{fmt.Sprintf("file %s exists, expected it to not exist", name)}
+		}
+
+		return nil
+	}
+
+	if !exists {
+		return []string{fmt.Sprintf("file %s does not exist", name)}
+	}
+
+	var failures []string
+
+	mode := entry.header.FileInfo().Mode()
+	if expectation.Permissions != "" && mode.String() != expectation.Permissions {
+		failures = append(failures, fmt.Sprintf("file %s has permissions %s, expected %s", name, mode, expectation.Permissions))
+	}
+
+	owner := strconv.Itoa(entry.header.Uid) + ":" + strconv.Itoa(entry.header.Gid)
+	if expectation.Owner != "" && owner != expectation.Owner {
+		failures = append(failures, fmt.Sprintf("file %s is owned by %s, expected %s", name, owner, expectation.Owner))
+	}
+
+	if len(expectation.ExpectedContents) == 0 && len(expectation.ExcludedContents) == 0 {
+		return failures
+	}
+
+	resolved, ok := resolveImagePath(files, name)
+	if resolvedEntry, found := files[resolved]; !ok || !found || resolvedEntry.header.FileInfo().Mode()&fs.ModeType != 0 {
+		return append(failures, fmt.Sprintf("file %s is not a regular file, cannot check its contents", name))
+	}
+
+	return append(failures, checkOutputRegexes("file "+name, string(files[resolved].contents), expectation.ExpectedContents, expectation.ExcludedContents)...)
+}
+
+// checkImageCommandE runs a command in a container of the image and returns the expectations it does not meet.
+func checkImageCommandE(
+	t testing.TestingT,
+	ctx context.Context,
+	image string,
+	expectations *ImageStructureExpectations,
+	expectation ImageCommandExpectation,
+) ([]string, error) {
+	options := &RunOptions{
+		Entrypoint:           expectation.Entrypoint,
+		Command:              expectation.Command,
+		EnvironmentVariables: expectation.EnvironmentVariables,
+		Logger:               expectations.Logger,
+		Backend:              expectations.Backend,
+		Remove:               true,
+	}
+
+	out, err := RunContextE(t, ctx, image, options)
+
+	exitCode := 0
+
+	if err != nil {
+		var exitErr ContainerExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode
+		} else {
+			code, codeErr := shell.GetExitCodeForRunCommandError(err)
+			if codeErr != nil || code == 0 {
+				// The command could not be run at all
+				return nil, err
+			}
+
+			exitCode = code
+		}
+	}
+
+	name := "command " + expectation.Name
+	if expectation.Name == "" {
+		name = fmt.Sprintf("command %q", expectation.Command)
+	}
+
+	var failures []string
+
+	if exitCode != expectation.ExitCode {
+		failures = append(failures, fmt.Sprintf("%s exited with code %d, expected %d", name, exitCode, expectation.ExitCode))
+	}
+
+	return append(failures, checkOutputRegexes(name, out, expectation.ExpectedOutput, expectation.ExcludedOutput)...), nil
+}
+
+// checkOutputRegexes returns a failure for every expected regex that does not match the output and every excluded
+// regex that does.
+func checkOutputRegexes(name string, output string, expected []string, excluded []string) []string {
+	var failures []string
+
+	for _, expression := range expected {
+		regex, err := regexp.Compile(expression)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: invalid regex %q: %v", name, expression, err))
+		} else if !regex.MatchString(output) {
+			failures = append(failures, fmt.Sprintf("%s does not match %q", name, expression))
+		}
+	}
+
+	for _, expression := range excluded {
+		regex, err := regexp.Compile(expression)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: invalid regex %q: %v", name, expression, err))
+		} else if regex.MatchString(output) {
+			failures = append(failures, fmt.Sprintf("%s matches excluded %q", name, expression))
+		}
+	}
+
+	return failures
+}
+
+// sortedKeys returns the keys of the given map in sorted order, for deterministic failure messages.
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
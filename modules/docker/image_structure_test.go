@@ -0,0 +1,60 @@
+package docker_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/docker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyImageStructure(t *testing.T) {
+	t.Parallel()
+
+	expectations := &docker.ImageStructureExpectations{
+		Metadata: &docker.ImageMetadataExpectation{
+			Cmd:          []string{"nginx", "-g", "daemon off;"},
+			ExposedPorts: []string{"80/tcp"},
+			Env:          map[string]string{"NGINX_VERSION": "1.17.10"},
+		},
+		Files: []docker.ImageFileExpectation{
+			{Path: "/etc/nginx/nginx.conf", Permissions: "-rw-r--r--", Owner: "0:0", ExpectedContents: []string{`worker_processes\s+1;`}},
+			{Path: "/usr/sbin/nginx", Permissions: "-rwxr-xr-x"},
+			{Path: "/bin/sh"},
+			{Path: "/etc/apache2", ShouldNotExist: true},
+		},
+		Commands: []docker.ImageCommandExpectation{
+			{Name: "nginx version", Command: []string{"nginx", "-v"}, ExpectedOutput: []string{`nginx/1\.17`}},
+			{Name: "false", Entrypoint: "false", ExitCode: 1},
+		},
+		MaxLayers:    10,
+		MaxSizeBytes: 100 * 1024 * 1024,
+	}
+
+	docker.VerifyImageStructureContext(t, t.Context(), dockerInspectTestImage, expectations)
+}
+
+func TestVerifyImageStructureReportsAllFailures(t *testing.T) {
+	t.Parallel()
+
+	expectations := &docker.ImageStructureExpectations{
+		Metadata: &docker.ImageMetadataExpectation{
+			User:         "nobody",
+			ExposedPorts: []string{"443/tcp"},
+		},
+		Files: []docker.ImageFileExpectation{
+			{Path: "/does/not/exist"},
+			{Path: "/etc/nginx/nginx.conf", ExcludedContents: []string{"worker_processes"}},
+		},
+		Commands: []docker.ImageCommandExpectation{
+			{Name: "nginx version", Command: []string{"nginx", "-v"}, ExpectedOutput: []string{`apache`}},
+		},
+		MaxLayers: 1,
+	}
+
+	err := docker.VerifyImageStructureContextE(t, t.Context(), dockerInspectTestImage, expectations)
+
+	var mismatch docker.ImageStructureMismatch
+	require.True(t, errors.As(err, &mismatch))
+	require.Len(t, mismatch.Failures, 6)
+}
@@ -42,6 +42,21 @@ type ContainerInspect struct {
 	// Volume bindings made to the container
 	Binds []VolumeBind
 
+	// Labels set on the container
+	Labels map[string]string
+
+	// Networks the container is attached to, keyed by network name
+	Networks map[string]ContainerNetwork
+
+	// Mounts of the container, including volumes, bind mounts and tmpfs mounts
+	Mounts []Mount
+
+	// Image the container was created from, as given when it was created
+	Image string
+
+	// Resource limits applied to the container
+	Resources ContainerResources
+
 	// Health check
 	Health HealthCheck
 
@@ -65,6 +80,51 @@ type VolumeBind struct {
 	Destination string
 }
 
+// ContainerNetwork represents the container's endpoint in a single network
+type ContainerNetwork struct {
+	NetworkID  string
+	IPAddress  string
+	Gateway    string
+	MacAddress string
+	Aliases    []string
+}
+
+// Mount represents a single mount of the container
+type Mount struct {
+	// Type of the mount, such as bind, volume or tmpfs
+	Type string
+
+	// Name of the volume, for volume mounts
+	Name string
+
+	Source      string
+	Destination string
+
+	// Mode of the mount, such as "z" or "ro", if any
+	Mode string
+
+	// Whether the mount is writable
+	RW bool
+}
+
+// ContainerResources represents the resource limits applied to the container. Zero values mean no limit.
+type ContainerResources struct {
+	// Memory limit in bytes
+	Memory int64
+
+	// Total memory limit (memory + swap) in bytes
+	MemorySwap int64
+
+	// CPU quota in units of 1e-9 CPUs
+	NanoCPUs int64
+
+	// Relative CPU weight
+	CPUShares int64
+
+	// Maximum number of processes
+	PidsLimit int64
+}
+
 // HealthCheck represents the current health history of the container
 type HealthCheck struct {
 	// Health check status
@@ -104,10 +164,34 @@ type inspectOutput struct {
 			HostIP   string `json:"HostIp"`
 			HostPort string `json:"HostPort"`
 		} `json:"Ports"`
+		Networks map[string]struct {
+			NetworkID  string   `json:"NetworkID"`
+			IPAddress  string   `json:"IPAddress"`
+			Gateway    string   `json:"Gateway"`
+			MacAddress string   `json:"MacAddress"`
+			Aliases    []string `json:"Aliases"`
+		} `json:"Networks"`
 	} `json:"NetworkSettings"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+		Image  string            `json:"Image"`
+	} `json:"Config"`
 	HostConfig struct {
-		Binds []string `json:"Binds"`
+		PidsLimit  *int64   `json:"PidsLimit"`
+		Binds      []string `json:"Binds"`
+		Memory     int64    `json:"Memory"`
+		MemorySwap int64    `json:"MemorySwap"`
+		NanoCpus   int64    `json:"NanoCpus"`
+		CPUShares  int64    `json:"CpuShares"`
 	} `json:"HostConfig"`
+	Mounts []struct {
+		Type        string `json:"Type"`
+		Name        string `json:"Name"`
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		Mode        string `json:"Mode"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
 	ID      string `json:"Id"`
 	Created string `json:"Created"`
 	Name    string `json:"Name"`
@@ -185,6 +269,41 @@ func InspectContextE(t testing.TestingT, ctx context.Context, id string) (*Conta
 	return transformContainer(t, container)
 }
 
+// InspectOptions defines options that can be passed to [InspectWithOptionsContextE].
+type InspectOptions struct {
+	// Backend selects how to talk to the Docker daemon. With BackendEngineAPI, the docker CLI is not needed.
+	Backend Backend
+}
+
+// InspectWithOptionsContext inspects the given container with the given options and returns a [ContainerInspect]
+// struct. This will fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func InspectWithOptionsContext(t testing.TestingT, ctx context.Context, id string, options *InspectOptions) *ContainerInspect {
+	t.Helper()
+
+	out, err := InspectWithOptionsContextE(t, ctx, id, options)
+	require.NoError(t, err)
+
+	return out
+}
+
+// InspectWithOptionsContextE inspects the given container with the given options and returns a [ContainerInspect]
+// struct, along with any errors. The returned error matches [ErrNoContainerFound] if the container does not exist. The
+// ctx parameter supports cancellation and timeouts.
+func InspectWithOptionsContextE(t testing.TestingT, ctx context.Context, id string, options *InspectOptions) (*ContainerInspect, error) {
+	t.Helper()
+
+	if options.Backend != BackendEngineAPI {
+		return InspectContextE(t, ctx, id)
+	}
+
+	client, err := NewEngineClientE()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.InspectContainerContextE(t, ctx, id)
+}
+
 // transformContainer converts 'docker inspect' output JSON into a more friendly and testable format
 func transformContainer(t testing.TestingT, container *inspectOutput) (*ContainerInspect, error) {
 	t.Helper()
@@ -213,6 +332,16 @@ func transformContainer(t testing.TestingT, container *inspectOutput) (*Containe
 		Error:    container.State.Error,
 		Ports:    ports,
 		Binds:    volumes,
+		Labels:   container.Config.Labels,
+		Networks: transformContainerNetworks(container),
+		Mounts:   transformContainerMounts(container),
+		Image:    container.Config.Image,
+		Resources: ContainerResources{
+			Memory:     container.HostConfig.Memory,
+			MemorySwap: container.HostConfig.MemorySwap,
+			NanoCPUs:   container.HostConfig.NanoCpus,
+			CPUShares:  container.HostConfig.CPUShares,
+		},
 		Health: HealthCheck{
 			Status:        container.State.Health.Status,
 			FailingStreak: container.State.Health.FailingStreak,
@@ -220,9 +349,48 @@ func transformContainer(t testing.TestingT, container *inspectOutput) (*Containe
 		},
 	}
 
+	if container.HostConfig.PidsLimit != nil {
+		inspect.Resources.PidsLimit = *container.HostConfig.PidsLimit
+	}
+
 	return &inspect, nil
 }
 
+// transformContainerNetworks converts Docker's network settings into a map of network name to ContainerNetwork
+func transformContainerNetworks(container *inspectOutput) map[string]ContainerNetwork {
+	networks := make(map[string]ContainerNetwork, len(container.NetworkSettings.Networks))
+
+	for name, network := range container.NetworkSettings.Networks {
+		networks[name] = ContainerNetwork{
+			NetworkID:  network.NetworkID,
+			IPAddress:  network.IPAddress,
+			Gateway:    network.Gateway,
+			MacAddress: network.MacAddress,
+			Aliases:    network.Aliases,
+		}
+	}
+
+	return networks
+}
+
+// transformContainerMounts converts Docker's mounts into a list of Mount
+func transformContainerMounts(container *inspectOutput) []Mount {
+	mounts := make([]Mount, 0, len(container.Mounts))
+
+	for _, mount := range container.Mounts {
+		mounts = append(mounts, Mount{
+			Type:        mount.Type,
+			Name:        mount.Name,
+			Source:      mount.Source,
+			Destination: mount.Destination,
+			Mode:        mount.Mode,
+			RW:          mount.RW,
+		})
+	}
+
+	return mounts
+}
+
 // transformContainerPorts converts Docker's ports from the following json into a more testable format
 //
 //	{
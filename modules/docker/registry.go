@@ -0,0 +1,476 @@
+package docker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// DefaultRegistryImage is the image used to run a registry in RegistryContainer mode.
+	DefaultRegistryImage = "registry:2"
+
+	// registryContainerPort is the port the registry image listens on.
+	registryContainerPort = 5000
+
+	// registryConfigDir is where the htpasswd file and TLS certificate are mounted in the registry container.
+	registryConfigDir = "/terratest-registry"
+
+	// registryCertificateValidity is how long the generated self-signed registry certificate is valid for.
+	registryCertificateValidity = 24 * time.Hour
+)
+
+// ErrRegistryCredentialsMismatch is returned when only one of RegistryOptions.Username and RegistryOptions.Password is
+// set.
+var ErrRegistryCredentialsMismatch = errors.New("registry username and password must be set together")
+
+// RegistryMode selects how a local registry is run.
+type RegistryMode int
+
+const (
+	// RegistryInProcess serves the registry from the test process. It needs no Docker daemon, so it is the fastest
+	// way to test pushing and pulling with a registry client. A Docker daemon can only push to it if the daemon runs on
+	// the same host as the test, as the registry only listens on the loopback interface.
+	RegistryInProcess RegistryMode = iota
+
+	// RegistryContainer runs the registry image in a container, so any Docker daemon can push to it. The htpasswd
+	// file and TLS certificate are bind mounted from a temporary directory, so the daemon must run on the same host as
+	// the test when using basic auth or TLS.
+	RegistryContainer
+)
+
+// RegistryOptions defines options that can be passed to StartRegistryContextE.
+type RegistryOptions struct {
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+
+	// Image to run in RegistryContainer mode. Defaults to DefaultRegistryImage.
+	Image string
+
+	// If Username and Password are set, the registry requires basic auth with these credentials.
+	Username string
+	Password string
+
+	// Mode selects how the registry is run. Defaults to RegistryInProcess.
+	Mode RegistryMode
+
+	// Backend selects how to talk to the Docker daemon in RegistryContainer mode.
+	Backend Backend
+
+	// If set to true, the registry is served over HTTPS with a generated self-signed certificate, which is returned in
+	// Registry.CACertPEM. Without TLS, Docker daemons only push to the registry if it is on localhost or listed in
+	// their insecure-registries.
+	TLS bool
+}
+
+// Registry is a handle to a local OCI registry started by StartRegistryContextE.
+type Registry struct {
+	options   *RegistryOptions
+	server    *http.Server
+	container *Container
+	configDir string
+
+	// Address is the host:port the registry can be reached on, such as localhost:5000. Prefix repositories with it to
+	// push to the registry.
+	Address string
+
+	// CACertPEM is the PEM-encoded self-signed certificate of the registry if it uses TLS, empty otherwise. To push to
+	// the registry with the docker CLI, copy it to /etc/docker/certs.d/<Address>/ca.crt on the Docker host.
+	CACertPEM []byte
+}
+
+// StartRegistryContextE starts a local OCI registry, with optional basic auth and TLS, and returns a handle to it. If
+// t supports Cleanup, such as *testing.T, the registry is stopped when the test finishes. The ctx parameter supports
+// cancellation and timeouts.
+func StartRegistryContextE(t testing.TestingT, ctx context.Context, options *RegistryOptions) (*Registry, error) {
+	if (options.Username == "") != (options.Password == "") {
+		return nil, ErrRegistryCredentialsMismatch
+	}
+
+	reg := &Registry{options: options}
+
+	if c, ok := t.(testing.Cleaner); ok {
+		c.Cleanup(func() {
+			// The test context is already canceled when cleanup functions run
+			if err := reg.TerminateContextE(t, context.Background()); err != nil {
+				options.Logger.Warnf(t, "failed to stop registry %s: %v", reg.Address, err)
+			}
+		})
+	}
+
+	var err error
+	if options.Mode == RegistryContainer {
+		err = reg.startContainerE(t, ctx)
+	} else {
+		err = reg.startInProcessE()
+	}
+
+	if err != nil {
+		return reg, err
+	}
+
+	options.Logger.Logf(t, "Started registry at %s", reg.Address)
+
+	return reg, nil
+}
+
+// StartRegistryContext starts a local OCI registry, with optional basic auth and TLS, and returns a handle to it. If t
+// supports Cleanup, the registry is stopped when the test finishes. This will fail the test if there are any errors.
+// The ctx parameter supports cancellation and timeouts.
+func StartRegistryContext(t testing.TestingT, ctx context.Context, options *RegistryOptions) *Registry {
+	t.Helper()
+
+	reg, err := StartRegistryContextE(t, ctx, options)
+	require.NoError(t, err)
+
+	return reg
+}
+
+// Reference returns the reference of the given repository and tag in the registry, such as localhost:5000/app:v1.
+func (reg *Registry) Reference(repository string, tag string) string {
+	return reg.Address + "/" + repository + ":" + tag
+}
+
+// RemoteOptions returns the options to access the registry with go-containerregistry's remote package, including
+// the credentials and a transport that trusts the registry's certificate.
+func (reg *Registry) RemoteOptions(ctx context.Context) []remote.Option {
+	var authenticator authn.Authenticator = authn.Anonymous
+	if reg.options.Username != "" {
+		authenticator = &authn.Basic{Username: reg.options.Username, Password: reg.options.Password}
+	}
+
+	remoteOptions := []remote.Option{remote.WithContext(ctx), remote.WithAuth(authenticator)}
+
+	if len(reg.CACertPEM) > 0 {
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM(reg.CACertPEM)
+
+		transport := remote.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS12}
+
+		remoteOptions = append(remoteOptions, remote.WithTransport(transport))
+	}
+
+	return remoteOptions
+}
+
+// ListRepositoriesContextE returns the sorted names of the repositories in the registry. The ctx parameter supports
+// cancellation and timeouts.
+func (reg *Registry) ListRepositoriesContextE(t testing.TestingT, ctx context.Context) ([]string, error) {
+	registryName, err := name.NewRegistry(reg.Address, reg.nameOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories, err := remote.Catalog(ctx, registryName, reg.RemoteOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(repositories)
+
+	return repositories, nil
+}
+
+// ListRepositoriesContext returns the sorted names of the repositories in the registry. This will fail the test if
+// there are any errors. The ctx parameter supports cancellation and timeouts.
+func (reg *Registry) ListRepositoriesContext(t testing.TestingT, ctx context.Context) []string {
+	t.Helper()
+
+	repositories, err := reg.ListRepositoriesContextE(t, ctx)
+	require.NoError(t, err)
+
+	return repositories
+}
+
+// ListTagsContextE returns the sorted tags of the given repository in the registry. The ctx parameter supports
+// cancellation and timeouts.
+func (reg *Registry) ListTagsContextE(t testing.TestingT, ctx context.Context, repository string) ([]string, error) {
+	repo, err := name.NewRepository(reg.Address+"/"+repository, reg.nameOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := remote.List(repo, reg.RemoteOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(tags)
+
+	return tags, nil
+}
+
+// ListTagsContext returns the sorted tags of the given repository in the registry. This will fail the test if there
+// are any errors. The ctx parameter supports cancellation and timeouts.
+func (reg *Registry) ListTagsContext(t testing.TestingT, ctx context.Context, repository string) []string {
+	t.Helper()
+
+	tags, err := reg.ListTagsContextE(t, ctx, repository)
+	require.NoError(t, err)
+
+	return tags
+}
+
+// ListManifestsContextE returns the manifests of the given tag in the registry, one per platform for a multi-arch
+// image. The ctx parameter supports cancellation and timeouts.
+func (reg *Registry) ListManifestsContextE(t testing.TestingT, ctx context.Context, repository string, tag string) ([]ImageManifest, error) {
+	ref, err := name.ParseReference(reg.Reference(repository, tag), reg.nameOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageManifestsE(ref, reg.RemoteOptions(ctx)...)
+}
+
+// ListManifestsContext returns the manifests of the given tag in the registry, one per platform for a multi-arch
+// image. This will fail the test if there are any errors. The ctx parameter supports cancellation and timeouts.
+func (reg *Registry) ListManifestsContext(t testing.TestingT, ctx context.Context, repository string, tag string) []ImageManifest {
+	t.Helper()
+
+	manifests, err := reg.ListManifestsContextE(t, ctx, repository, tag)
+	require.NoError(t, err)
+
+	return manifests
+}
+
+// TerminateContextE stops the registry and removes its contents. The ctx parameter supports cancellation and timeouts.
+func (reg *Registry) TerminateContextE(t testing.TestingT, ctx context.Context) error {
+	var err error
+
+	if reg.server != nil {
+		err = reg.server.Close()
+		reg.server = nil
+	}
+
+	if reg.container != nil {
+		err = errors.Join(err, reg.container.TerminateContextE(t, ctx))
+		reg.container = nil
+	}
+
+	if reg.configDir != "" {
+		err = errors.Join(err, os.RemoveAll(reg.configDir))
+		reg.configDir = ""
+	}
+
+	return err
+}
+
+// nameOptions returns the options to parse references to the registry.
+func (reg *Registry) nameOptions() []name.Option {
+	if len(reg.CACertPEM) == 0 {
+		return []name.Option{name.Insecure}
+	}
+
+	return nil
+}
+
+// startInProcessE serves the registry from the test process on a random loopback port.
+func (reg *Registry) startInProcessE() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	reg.Address = net.JoinHostPort("localhost", fmt.Sprint(listener.Addr().(*net.TCPAddr).Port))
+
+	var handler http.Handler = registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	if reg.options.Username != "" {
+		handler = basicAuthHandler(handler, reg.options.Username, reg.options.Password)
+	}
+
+	reg.server = &http.Server{Handler: handler, ReadHeaderTimeout: containerProbeTimeout}
+
+	if !reg.options.TLS {
+		go func() { _ = reg.server.Serve(listener) }()
+
+		return nil
+	}
+
+	certPEM, keyPEM, err := generateRegistryCertificate("localhost")
+	if err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	reg.CACertPEM = certPEM
+	reg.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+
+	go func() { _ = reg.server.ServeTLS(listener, "", "") }()
+
+	return nil
+}
+
+// startContainerE runs the registry image in a container with a random published port.
+func (reg *Registry) startContainerE(t testing.TestingT, ctx context.Context) error {
+	image := reg.options.Image
+	if image == "" {
+		image = DefaultRegistryImage
+	}
+
+	containerOptions := &ContainerOptions{
+		RunOptions: RunOptions{
+			Logger:       reg.options.Logger,
+			Backend:      reg.options.Backend,
+			OtherOptions: []string{"--publish", fmt.Sprint(registryContainerPort)},
+		},
+		WaitFor: []WaitStrategy{WaitForPort(registryContainerPort)},
+	}
+
+	if reg.options.Username != "" || reg.options.TLS {
+		configDir, err := os.MkdirTemp("", "terratest-registry")
+		if err != nil {
+			return err
+		}
+
+		reg.configDir = configDir
+		containerOptions.Volumes = []string{configDir + ":" + registryConfigDir + ":ro"}
+	}
+
+	if reg.options.Username != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(reg.options.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+
+		htpasswd := reg.options.Username + ":" + string(hash) + "\n"
+		if err := os.WriteFile(filepath.Join(reg.configDir, "htpasswd"), []byte(htpasswd), 0o644); err != nil { //nolint:gosec // the registry container may run as a different user
+			return err
+		}
+
+		containerOptions.EnvironmentVariables = append(containerOptions.EnvironmentVariables,
+			"REGISTRY_AUTH=htpasswd",
+			"REGISTRY_AUTH_HTPASSWD_REALM=terratest",
+			"REGISTRY_AUTH_HTPASSWD_PATH="+registryConfigDir+"/htpasswd",
+		)
+	}
+
+	if reg.options.TLS {
+		certPEM, keyPEM, err := generateRegistryCertificate(GetDockerHost())
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(reg.configDir, "cert.pem"), certPEM, 0o644); err != nil { //nolint:gosec // the registry container may run as a different user
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(reg.configDir, "key.pem"), keyPEM, 0o644); err != nil { //nolint:gosec // the registry container may run as a different user
+			return err
+		}
+
+		reg.CACertPEM = certPEM
+		containerOptions.EnvironmentVariables = append(containerOptions.EnvironmentVariables,
+			"REGISTRY_HTTP_TLS_CERTIFICATE="+registryConfigDir+"/cert.pem",
+			"REGISTRY_HTTP_TLS_KEY="+registryConfigDir+"/key.pem",
+		)
+	}
+
+	container, err := StartContainerContextE(t, ctx, image, containerOptions)
+	if container != nil {
+		reg.container = container
+	}
+
+	if err != nil {
+		return err
+	}
+
+	reg.Address, err = container.EndpointContextE(t, ctx, registryContainerPort)
+
+	return err
+}
+
+// basicAuthHandler wraps the given handler to require basic auth with the given credentials.
+func basicAuthHandler(handler http.Handler, username string, password string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != username || pass != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="terratest"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// generateRegistryCertificate generates a self-signed certificate and key for the given host, which is also valid for
+// localhost and the loopback addresses, and returns them PEM-encoded.
+func generateRegistryCertificate(host string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)) //nolint:mnd // 128 bit serial number
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: host, Organization: []string{"Terratest"}},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(registryCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}, //nolint:mnd // loopback address
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if host != "localhost" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,109 @@
+package docker_test
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/docker"
+)
+
+func TestStartRegistry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		options *docker.RegistryOptions
+	}{
+		{"Plain", &docker.RegistryOptions{}},
+		{"BasicAuthAndTLS", &docker.RegistryOptions{Username: "terratest", Password: "secret", TLS: true}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+			reg := docker.StartRegistryContext(t, ctx, testCase.options)
+			require.Equal(t, testCase.options.TLS, len(reg.CACertPEM) > 0)
+
+			img, err := random.Image(1024, 1)
+			require.NoError(t, err)
+
+			for _, tag := range []string{"v1", "v2"} {
+				ref, err := name.ParseReference(reg.Reference("app", tag))
+				require.NoError(t, err)
+				require.NoError(t, remote.Write(ref, img, reg.RemoteOptions(ctx)...))
+			}
+
+			index := v1.ImageIndex(empty.Index)
+
+			for _, platform := range []v1.Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64", Variant: "v8"}} {
+				platformImg, err := random.Image(1024, 1)
+				require.NoError(t, err)
+
+				index = mutate.AppendManifests(index, mutate.IndexAddendum{Add: platformImg, Descriptor: v1.Descriptor{Platform: &platform}})
+			}
+
+			ref, err := name.ParseReference(reg.Reference("multiarch", "latest"))
+			require.NoError(t, err)
+			require.NoError(t, remote.WriteIndex(ref, index, reg.RemoteOptions(ctx)...))
+
+			require.Equal(t, []string{"app", "multiarch"}, reg.ListRepositoriesContext(t, ctx))
+			require.Equal(t, []string{"v1", "v2"}, reg.ListTagsContext(t, ctx, "app"))
+
+			manifests := reg.ListManifestsContext(t, ctx, "multiarch", "latest")
+			require.Len(t, manifests, 2)
+			require.Equal(t, "linux/amd64", manifests[0].Platform)
+			require.Equal(t, "linux/arm64/v8", manifests[1].Platform)
+
+			manifests = reg.ListManifestsContext(t, ctx, "app", "v1")
+			require.Len(t, manifests, 1)
+		})
+	}
+}
+
+func TestStartRegistryRequiresCredentials(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	options := &docker.RegistryOptions{Username: "terratest", Password: "secret"}
+	reg := docker.StartRegistryContext(t, ctx, options)
+
+	_, err := docker.StartRegistryContextE(t, ctx, &docker.RegistryOptions{Username: "terratest"})
+	require.ErrorIs(t, err, docker.ErrRegistryCredentialsMismatch)
+
+	wrongPassword := &docker.RegistryOptions{Username: "terratest", Password: "wrong"}
+	other := docker.StartRegistryContext(t, ctx, wrongPassword)
+
+	// Point a handle with the wrong credentials at the first registry
+	other.Address = reg.Address
+	_, err = other.ListRepositoriesContextE(t, ctx)
+	require.Error(t, err)
+
+	require.Empty(t, reg.ListRepositoriesContext(t, ctx))
+}
+
+func TestStartRegistryContainer(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	options := &docker.RegistryOptions{Mode: docker.RegistryContainer, Username: "terratest", Password: "secret"}
+	reg := docker.StartRegistryContext(t, ctx, options)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(reg.Reference("app", "v1"), name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img, reg.RemoteOptions(ctx)...))
+
+	require.Equal(t, []string{"app"}, reg.ListRepositoriesContext(t, ctx))
+	require.Equal(t, []string{"v1"}, reg.ListTagsContext(t, ctx, "app"))
+}
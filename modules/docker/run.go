@@ -40,6 +40,11 @@ type RunOptions struct {
 	// solely focus on the most important ones.
 	OtherOptions []string
 
+	// Backend selects how to talk to the Docker daemon. With BackendEngineAPI, the container is created and started
+	// through the Docker Engine API and the docker CLI is not needed. Only the --publish, --publish-all, --label,
+	// --network, --env, --volume and --workdir flags are supported in OtherOptions with this backend.
+	Backend Backend
+
 	// If set to true, pass the --detach flag to 'docker run' to run the container in the background
 	Detach bool
 
@@ -86,6 +91,11 @@ func RunE(t testing.TestingT, image string, options *RunOptions) (string, error)
 func RunContextE(t testing.TestingT, ctx context.Context, image string, options *RunOptions) (string, error) {
 	options.Logger.Logf(t, "Running 'docker run' on image '%s'", image)
 
+	if options.Backend == BackendEngineAPI {
+		_, out, err := runWithEngineAPIE(t, ctx, image, options)
+		return out, err
+	}
+
 	args := formatDockerRunArgs(image, options)
 
 	cmd := &shell.Command{
@@ -128,6 +138,11 @@ func RunAndGetIDE(t testing.TestingT, image string, options *RunOptions) (string
 func RunAndGetIDContextE(t testing.TestingT, ctx context.Context, image string, options *RunOptions) (string, error) {
 	options.Logger.Logf(t, "Running 'docker run' on image '%s', returning stdout", image)
 
+	if options.Backend == BackendEngineAPI {
+		id, _, err := runWithEngineAPIE(t, ctx, image, options)
+		return id, err
+	}
+
 	args := formatDockerRunArgs(image, options)
 
 	cmd := &shell.Command{
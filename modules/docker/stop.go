@@ -17,6 +17,9 @@ type StopOptions struct {
 
 	// Seconds to wait for stop before killing the container (default 10)
 	Time int
+
+	// Backend selects how to talk to the Docker daemon. With BackendEngineAPI, the docker CLI is not needed.
+	Backend Backend
 }
 
 // Stop runs the 'docker stop' command for the given containers and return the stdout/stderr. This method fails
@@ -48,6 +51,10 @@ func StopE(t testing.TestingT, containers []string, options *StopOptions) (strin
 func StopContextE(t testing.TestingT, ctx context.Context, containers []string, options *StopOptions) (string, error) {
 	options.Logger.Logf(t, "Running 'docker stop' on containers '%s'", containers)
 
+	if options.Backend == BackendEngineAPI {
+		return stopWithEngineAPIE(t, ctx, containers, options)
+	}
+
 	args := formatDockerStopArgs(containers, options)
 
 	cmd := &shell.Command{
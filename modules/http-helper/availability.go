@@ -0,0 +1,470 @@
+package http_helper //nolint:staticcheck // package name determined by directory
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// DefaultProbeInterval is the default time between two probes of the same target.
+	DefaultProbeInterval = time.Second
+
+	// DefaultProbeTimeout is the default time after which a probe fails.
+	DefaultProbeTimeout = 5 * time.Second
+
+	// tcpScheme is the URL scheme of targets that are probed by opening a TCP connection.
+	tcpScheme = "tcp"
+)
+
+// ErrNoAvailabilityTargets is returned when an availability monitor is started without any target to probe.
+var ErrNoAvailabilityTargets = errors.New("no target to monitor the availability of")
+
+// AvailabilityMonitorOptions defines what an AvailabilityMonitor probes and how often.
+type AvailabilityMonitorOptions struct {
+	// Logger for outages as they start and end. Defaults to logger.Default.
+	Logger *logger.Logger
+
+	// TLS configuration of the HTTPS probes
+	TLSConfig *tls.Config
+
+	// Targets to probe. HTTP and HTTPS URLs are probed with a GET request, and tcp://host:port URLs by opening a TCP
+	// connection.
+	Targets []string
+
+	// Time between two probes of the same target. Defaults to DefaultProbeInterval.
+	Interval time.Duration
+
+	// Time after which a probe fails. Defaults to DefaultProbeTimeout.
+	Timeout time.Duration
+
+	// Status code an HTTP probe must return to succeed. Defaults to 200.
+	ExpectedStatus int
+}
+
+// AvailabilityMonitor probes targets at a fixed rate in the background, for example while a zero-downtime deployment
+// is in progress, and reports how available they were once stopped.
+type AvailabilityMonitor struct {
+	options *AvailabilityMonitorOptions
+	t       testing.TestingT
+	cancel  context.CancelFunc
+	report  *AvailabilityReport
+	probes  [][]ProbeResult
+	start   time.Time
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+}
+
+// ProbeResult is the result of a single probe of a target.
+type ProbeResult struct {
+	// Time the probe started
+	Time time.Time
+
+	// Error of the probe, or nil if it succeeded
+	Err error
+
+	// How long the probe took
+	Latency time.Duration
+
+	// Status code of HTTP probes, or 0 if no response was received
+	StatusCode int
+}
+
+// AvailabilityReport describes how available the targets of an AvailabilityMonitor were while it was running.
+type AvailabilityReport struct {
+	// Time the monitor was started
+	Start time.Time
+
+	// Time the monitor was stopped
+	End time.Time
+
+	// Availability of each target, in the order of the options
+	Targets []TargetAvailability
+}
+
+// TargetAvailability describes how available a single target was.
+type TargetAvailability struct {
+	// Number of failed probes by error, such as "unexpected status 503" or "connection refused"
+	Errors map[string]int
+
+	// Target that was probed
+	Target string
+
+	// All the probes of the target, in order
+	Probes []ProbeResult
+
+	// Number of probes that succeeded
+	Successes int
+
+	// Fraction of the probes that succeeded, or 1 if there were no probes
+	SuccessRatio float64
+
+	// Longest time between the first probe of a series of failed probes and the next successful probe, or the end of
+	// the monitoring if the target never recovered.
+	LongestOutage time.Duration
+
+	// Percentiles of the latency of the successful probes
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+	LatencyP99 time.Duration
+}
+
+// OutageTooLong is an error that occurs when a target was unavailable for longer than allowed.
+type OutageTooLong struct {
+	Target    string
+	Outage    time.Duration
+	MaxOutage time.Duration
+}
+
+func (err OutageTooLong) Error() string {
+	return fmt.Sprintf("%s was unavailable for %s, which is longer than the allowed %s", err.Target, err.Outage, err.MaxOutage)
+}
+
+// SuccessRatioTooLow is an error that occurs when too few probes of a target succeeded.
+type SuccessRatioTooLow struct {
+	Target          string
+	SuccessRatio    float64
+	MinSuccessRatio float64
+}
+
+func (err SuccessRatioTooLow) Error() string {
+	return fmt.Sprintf("%.4f of the probes of %s succeeded, which is less than the required %.4f", err.SuccessRatio, err.Target, err.MinSuccessRatio)
+}
+
+// StartAvailabilityMonitorContext starts probing the targets in the options in the background until Stop is called or
+// ctx is done. The monitor is stopped when the test finishes if it is still running. This will fail the test if there
+// are no targets, or if a target is not a valid HTTP, HTTPS or TCP URL.
+func StartAvailabilityMonitorContext(t testing.TestingT, ctx context.Context, options *AvailabilityMonitorOptions) *AvailabilityMonitor {
+	t.Helper()
+	monitor, err := StartAvailabilityMonitorContextE(t, ctx, options)
+	require.NoError(t, err)
+
+	return monitor
+}
+
+// StartAvailabilityMonitorContextE starts probing the targets in the options in the background until Stop is called or
+// ctx is done. The monitor is stopped when the test finishes if it is still running. Returns ErrNoAvailabilityTargets
+// if options is nil or has no targets.
+func StartAvailabilityMonitorContextE(t testing.TestingT, ctx context.Context, options *AvailabilityMonitorOptions) (*AvailabilityMonitor, error) {
+	if options == nil || len(options.Targets) == 0 {
+		return nil, ErrNoAvailabilityTargets
+	}
+
+	probes := make([]func(context.Context) ProbeResult, 0, len(options.Targets))
+
+	for _, target := range options.Targets {
+		probe, err := newProbe(target, options)
+		if err != nil {
+			return nil, err
+		}
+
+		probes = append(probes, probe)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	monitor := &AvailabilityMonitor{
+		options: options,
+		t:       t,
+		cancel:  cancel,
+		probes:  make([][]ProbeResult, len(options.Targets)),
+		start:   time.Now(),
+	}
+
+	for i, probe := range probes {
+		monitor.wg.Add(1)
+
+		go monitor.run(ctx, i, probe)
+	}
+
+	monitor.logger().Logf(t, "Started monitoring the availability of %s", strings.Join(options.Targets, ", "))
+
+	if c, ok := t.(testing.Cleaner); ok {
+		c.Cleanup(func() { monitor.Stop() })
+	}
+
+	return monitor, nil
+}
+
+// Stop stops probing the targets, waits for the probes in progress to complete and returns the report. Calling Stop
+// again returns the same report.
+func (monitor *AvailabilityMonitor) Stop() *AvailabilityReport {
+	monitor.cancel()
+	monitor.wg.Wait()
+
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+
+	if monitor.report == nil {
+		monitor.report = &AvailabilityReport{Start: monitor.start, End: time.Now()}
+
+		for i, target := range monitor.options.Targets {
+			monitor.report.Targets = append(monitor.report.Targets, newTargetAvailability(target, monitor.probes[i], monitor.report.End))
+		}
+	}
+
+	return monitor.report
+}
+
+// RequireMaxOutageE returns an OutageTooLong error for every target that was unavailable for longer than maxOutage
+// in a row.
+func (report *AvailabilityReport) RequireMaxOutageE(maxOutage time.Duration) error {
+	var errs []error
+
+	for _, target := range report.Targets {
+		if target.LongestOutage > maxOutage {
+			errs = append(errs, OutageTooLong{Target: target.Target, Outage: target.LongestOutage, MaxOutage: maxOutage})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RequireMaxOutage fails the test if any target was unavailable for longer than maxOutage in a row.
+func (report *AvailabilityReport) RequireMaxOutage(t testing.TestingT, maxOutage time.Duration) {
+	t.Helper()
+	require.NoError(t, report.RequireMaxOutageE(maxOutage))
+}
+
+// RequireSuccessRatioE returns a SuccessRatioTooLow error for every target for which less than minSuccessRatio of the
+// probes succeeded.
+func (report *AvailabilityReport) RequireSuccessRatioE(minSuccessRatio float64) error {
+	var errs []error
+
+	for _, target := range report.Targets {
+		if target.SuccessRatio < minSuccessRatio {
+			errs = append(errs, SuccessRatioTooLow{Target: target.Target, SuccessRatio: target.SuccessRatio, MinSuccessRatio: minSuccessRatio})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RequireSuccessRatio fails the test if less than minSuccessRatio of the probes of any target succeeded.
+func (report *AvailabilityReport) RequireSuccessRatio(t testing.TestingT, minSuccessRatio float64) {
+	t.Helper()
+	require.NoError(t, report.RequireSuccessRatioE(minSuccessRatio))
+}
+
+// run probes a single target at the configured interval until ctx is done, and logs when outages start and end.
+func (monitor *AvailabilityMonitor) run(ctx context.Context, index int, probe func(context.Context) ProbeResult) {
+	defer monitor.wg.Done()
+
+	interval := monitor.options.Interval
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+
+	target := monitor.options.Targets[index]
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	available := true
+
+	for {
+		result := probe(ctx)
+
+		// Probes interrupted by stopping the monitor say nothing about the target
+		if ctx.Err() != nil {
+			return
+		}
+
+		monitor.mutex.Lock()
+		monitor.probes[index] = append(monitor.probes[index], result)
+		monitor.mutex.Unlock()
+
+		if available && result.Err != nil {
+			monitor.logger().Logf(monitor.t, "%s became unavailable: %v", target, result.Err)
+		} else if !available && result.Err == nil {
+			monitor.logger().Logf(monitor.t, "%s is available again", target)
+		}
+
+		available = result.Err == nil
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (monitor *AvailabilityMonitor) logger() *logger.Logger {
+	if monitor.options.Logger != nil {
+		return monitor.options.Logger
+	}
+
+	return logger.Default
+}
+
+// newProbe returns a function that probes the given target once.
+func newProbe(target string, options *AvailabilityMonitorOptions) (func(context.Context) ProbeResult, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+
+	switch parsed.Scheme {
+	case tcpScheme:
+		return func(ctx context.Context) ProbeResult {
+			return probeTCP(ctx, parsed.Host, timeout)
+		}, nil
+	case "http", "https":
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = options.TLSConfig
+		// Open a new connection for every probe, so that requests are spread over the backends of load balancers
+		transport.DisableKeepAlives = true
+
+		expectedStatus := options.ExpectedStatus
+		if expectedStatus == 0 {
+			expectedStatus = http.StatusOK
+		}
+
+		client := &http.Client{Transport: transport, Timeout: timeout}
+
+		return func(ctx context.Context) ProbeResult {
+			return probeHTTP(ctx, client, target, expectedStatus)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q in availability target %s: use http, https or tcp", parsed.Scheme, target)
+	}
+}
+
+// probeHTTP sends a GET request to the target and checks the status code of the response.
+func probeHTTP(ctx context.Context, client *http.Client, target string, expectedStatus int) ProbeResult {
+	result := ProbeResult{Time: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Latency = time.Since(result.Time)
+		result.Err = err
+
+		return result
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	result.Latency = time.Since(result.Time)
+	result.StatusCode = resp.StatusCode
+
+	if resp.StatusCode != expectedStatus {
+		result.Err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return result
+}
+
+// probeTCP opens a TCP connection to the given address.
+func probeTCP(ctx context.Context, address string, timeout time.Duration) ProbeResult {
+	result := ProbeResult{Time: time.Now()}
+
+	conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", address)
+	result.Latency = time.Since(result.Time)
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	_ = conn.Close()
+
+	return result
+}
+
+// newTargetAvailability computes the availability of a target from its probes.
+func newTargetAvailability(target string, probes []ProbeResult, end time.Time) TargetAvailability {
+	availability := TargetAvailability{Target: target, Probes: probes, Errors: map[string]int{}, SuccessRatio: 1}
+
+	var (
+		latencies   []time.Duration
+		outageStart time.Time
+	)
+
+	for _, probe := range probes {
+		if probe.Err == nil {
+			availability.Successes++
+			latencies = append(latencies, probe.Latency)
+
+			if !outageStart.IsZero() {
+				availability.LongestOutage = max(availability.LongestOutage, probe.Time.Sub(outageStart))
+				outageStart = time.Time{}
+			}
+
+			continue
+		}
+
+		availability.Errors[probeErrorKey(probe.Err)]++
+
+		if outageStart.IsZero() {
+			outageStart = probe.Time
+		}
+	}
+
+	if !outageStart.IsZero() {
+		availability.LongestOutage = max(availability.LongestOutage, end.Sub(outageStart))
+	}
+
+	if len(probes) > 0 {
+		availability.SuccessRatio = float64(availability.Successes) / float64(len(probes))
+	}
+
+	slices.Sort(latencies)
+	availability.LatencyP50 = percentile(latencies, 50) //nolint:mnd // percentile
+	availability.LatencyP95 = percentile(latencies, 95) //nolint:mnd // percentile
+	availability.LatencyP99 = percentile(latencies, 99) //nolint:mnd // percentile
+
+	return availability
+}
+
+// probeErrorKey returns the key an error is counted under in the error breakdown. The URL that url.Error adds is
+// removed, as it is the same for all the probes of a target.
+func probeErrorKey(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	return err.Error()
+}
+
+// percentile returns the nearest-rank percentile of the sorted values, or 0 if there are none.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted)))) //nolint:mnd // percent
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
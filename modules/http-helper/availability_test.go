@@ -0,0 +1,105 @@
+package http_helper_test //nolint:staticcheck // package name determined by directory
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httphelper "github.com/gruntwork-io/terratest/modules/http-helper"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+func TestAvailabilityMonitor(t *testing.T) {
+	t.Parallel()
+
+	var down atomic.Bool
+
+	server := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		Routes: []httphelper.MockRoute{{
+			Handler: func(w http.ResponseWriter, _ *http.Request) {
+				if down.Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			},
+		}},
+	})
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	closedAddress := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	monitor := httphelper.StartAvailabilityMonitorContext(t, t.Context(), &httphelper.AvailabilityMonitorOptions{
+		Logger:   logger.Discard,
+		Targets:  []string{server.URL, "tcp://" + strings.TrimPrefix(server.URL, "http://"), "tcp://" + closedAddress},
+		Interval: 20 * time.Millisecond,
+	})
+
+	time.Sleep(200 * time.Millisecond)
+	down.Store(true)
+	time.Sleep(300 * time.Millisecond)
+	down.Store(false)
+	time.Sleep(200 * time.Millisecond)
+
+	report := monitor.Stop()
+	assert.Same(t, report, monitor.Stop())
+	require.Len(t, report.Targets, 3)
+
+	httpTarget := report.Targets[0]
+	assert.Equal(t, server.URL, httpTarget.Target)
+	assert.Less(t, httpTarget.SuccessRatio, 1.0)
+	assert.Greater(t, httpTarget.SuccessRatio, 0.0)
+	assert.GreaterOrEqual(t, httpTarget.LongestOutage, 250*time.Millisecond)
+	assert.Less(t, httpTarget.LongestOutage, 500*time.Millisecond)
+	assert.Equal(t, len(httpTarget.Probes)-httpTarget.Successes, httpTarget.Errors["unexpected status 503"])
+	assert.LessOrEqual(t, httpTarget.LatencyP50, httpTarget.LatencyP95)
+	assert.LessOrEqual(t, httpTarget.LatencyP95, httpTarget.LatencyP99)
+	assert.Positive(t, httpTarget.LatencyP99)
+
+	tcp := report.Targets[1]
+	assert.InDelta(t, 1.0, tcp.SuccessRatio, 0)
+	assert.Zero(t, tcp.LongestOutage)
+
+	closed := report.Targets[2]
+	assert.Zero(t, closed.Successes)
+	assert.Equal(t, closed.LongestOutage, report.End.Sub(closed.Probes[0].Time))
+
+	report.RequireMaxOutage(t, 10*time.Second)
+	report.RequireSuccessRatio(t, 0)
+
+	var outageErr httphelper.OutageTooLong
+	require.ErrorAs(t, report.RequireMaxOutageE(100*time.Millisecond), &outageErr)
+	assert.Equal(t, server.URL, outageErr.Target)
+
+	var ratioErr httphelper.SuccessRatioTooLow
+	require.ErrorAs(t, report.RequireSuccessRatioE(0.999), &ratioErr)
+	assert.Equal(t, server.URL, ratioErr.Target)
+}
+
+func TestAvailabilityMonitorInvalidTarget(t *testing.T) {
+	t.Parallel()
+
+	_, err := httphelper.StartAvailabilityMonitorContextE(t, t.Context(), &httphelper.AvailabilityMonitorOptions{
+		Targets: []string{"udp://localhost:53"},
+	})
+	require.Error(t, err)
+}
+
+func TestAvailabilityMonitorWithoutTargets(t *testing.T) {
+	t.Parallel()
+
+	_, err := httphelper.StartAvailabilityMonitorContextE(t, t.Context(), nil)
+	require.ErrorIs(t, err, httphelper.ErrNoAvailabilityTargets)
+
+	_, err = httphelper.StartAvailabilityMonitorContextE(t, t.Context(), &httphelper.AvailabilityMonitorOptions{})
+	require.ErrorIs(t, err, httphelper.ErrNoAvailabilityTargets)
+}
@@ -53,7 +53,7 @@ func ContinuouslyCheckURLContext(
 				case responses <- GetResponse{StatusCode: statusCode, Body: body}:
 					// do nothing since all we want to do is send the response
 				default:
-					logger.Default.Logf(t, "WARNING: ContinuouslyCheckURLContext responses channel buffer is full")
+					logger.Default.Warnf(t, "ContinuouslyCheckURLContext responses channel buffer is full")
 				}
 
 				logger.Default.Logf(t, "Got response %d and err %v from URL at %s", statusCode, err, url)
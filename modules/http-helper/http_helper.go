@@ -25,6 +25,12 @@ type HttpGetOptions struct { //nolint:staticcheck,revive // preserving existing
 	TlsConfig *tls.Config //nolint:staticcheck,revive // preserving existing field name
 	Url       string      //nolint:staticcheck,revive // preserving existing field name
 	Timeout   int
+
+	// RetryPolicy is used by the retry functions instead of their retries and sleepBetweenRetries arguments, if set.
+	RetryPolicy *retry.Policy
+
+	// Client certificates, proxy, SSH bastion host and host name overrides for the connection to the server
+	ConnectionOptions
 }
 
 // HttpDoOptions defines options for HTTP requests using an arbitrary method.
@@ -37,6 +43,12 @@ type HttpDoOptions struct { //nolint:staticcheck,revive // preserving existing t
 	Method    string
 	Url       string //nolint:staticcheck,revive // preserving existing field name
 	Timeout   int
+
+	// RetryPolicy is used by the retry functions instead of their retries and sleepBetweenRetries arguments, if set.
+	RetryPolicy *retry.Policy
+
+	// Client certificates, proxy, SSH bastion host and host name overrides for the connection to the server
+	ConnectionOptions
 }
 
 // optionsContext returns the context from an HttpGetOptions, defaulting to context.Background() if nil.
@@ -48,6 +60,16 @@ func optionsContext(ctx context.Context) context.Context {
 	return context.Background()
 }
 
+// retryPolicy returns the given policy, or a policy that retries up to retries times with sleepBetweenRetries in
+// between if it is nil.
+func retryPolicy(policy *retry.Policy, retries int, sleepBetweenRetries time.Duration) *retry.Policy {
+	if policy != nil {
+		return policy
+	}
+
+	return retry.ConstantPolicy(retries, sleepBetweenRetries)
+}
+
 // HTTPGetContext performs an HTTP GET on the given URL with an optional custom TLS configuration and returns the HTTP
 // status code and body. The provided context is used for the HTTP request. If there's any error, fail the test.
 func HTTPGetContext(t testing.TestingT, ctx context.Context, url string, tlsConfig *tls.Config) (int, string) {
@@ -103,8 +125,12 @@ func HttpGetWithOptionsE(t testing.TestingT, options HttpGetOptions) (int, strin
 	ctx := optionsContext(options.Context)
 
 	// Set HTTP client transport config
-	tr := http.DefaultTransport.(*http.Transport).Clone()
-	tr.TLSClientConfig = options.TlsConfig
+	tr, closeTransport, err := newTransport(t, options.TlsConfig, &options.ConnectionOptions)
+	if err != nil {
+		return -1, "", err
+	}
+
+	defer closeTransport()
 
 	client := http.Client{
 		// By default, Go does not impose a timeout, so an HTTP connection attempt can hang for a LONG time.
@@ -302,7 +328,9 @@ func HttpGetWithRetryE(t testing.TestingT, url string, tlsConfig *tls.Config, ex
 //
 //nolint:staticcheck,revive // preserving existing function name
 func HttpGetWithRetryWithOptionsE(t testing.TestingT, options HttpGetOptions, expectedStatus int, expectedBody string, retries int, sleepBetweenRetries time.Duration) error {
-	_, err := retry.DoWithRetryE(t, "HTTP GET to URL "+options.Url, retries, sleepBetweenRetries, func() (string, error) {
+	policy := retryPolicy(options.RetryPolicy, retries, sleepBetweenRetries)
+
+	_, err := retry.DoWithPolicyContextE(t, optionsContext(options.Context), "HTTP GET to URL "+options.Url, policy, func() (string, error) {
 		return "", HttpGetWithValidationWithOptionsE(t, options, expectedStatus, expectedBody)
 	})
 
@@ -363,7 +391,9 @@ func HttpGetWithRetryWithCustomValidationE(t testing.TestingT, url string, tlsCo
 //
 //nolint:staticcheck,revive // preserving existing function name
 func HttpGetWithRetryWithCustomValidationWithOptionsE(t testing.TestingT, options HttpGetOptions, retries int, sleepBetweenRetries time.Duration, validateResponse func(int, string) bool) error {
-	_, err := retry.DoWithRetryE(t, "HTTP GET to URL "+options.Url, retries, sleepBetweenRetries, func() (string, error) {
+	policy := retryPolicy(options.RetryPolicy, retries, sleepBetweenRetries)
+
+	_, err := retry.DoWithPolicyContextE(t, optionsContext(options.Context), "HTTP GET to URL "+options.Url, policy, func() (string, error) {
 		return "", HttpGetWithCustomValidationWithOptionsE(t, options, validateResponse)
 	})
 
@@ -468,34 +498,12 @@ func HTTPDoE(
 func HTTPDoWithOptionsE(
 	t testing.TestingT, options HttpDoOptions,
 ) (int, string, error) {
-	logger.Default.Logf(t, "Making an HTTP %s call to URL %s", options.Method, options.Url)
-
-	ctx := optionsContext(options.Context)
-
-	tr := http.DefaultTransport.(*http.Transport).Clone()
-	tr.TLSClientConfig = options.TlsConfig
-
-	client := http.Client{
-		// By default, Go does not impose a timeout, so an HTTP connection attempt can hang for a LONG time.
-		Timeout:   time.Duration(options.Timeout) * time.Second,
-		Transport: tr,
-	}
-
-	req := newRequestWithContext(ctx, options.Method, options.Url, options.Body, options.Headers)
-
-	resp, err := client.Do(req)
+	response, err := doRequestE(t, options)
 	if err != nil {
 		return -1, "", err
 	}
 
-	defer func() { _ = resp.Body.Close() }()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return -1, "", err
-	}
-
-	return resp.StatusCode, strings.TrimSpace(string(respBody)), nil
+	return response.StatusCode, response.Body, nil
 }
 
 // HTTPDoWithRetryContext repeatedly performs the given HTTP method on the given URL until the given status code is
@@ -625,9 +633,11 @@ func HTTPDoWithRetryWithOptionsE(
 
 	options.Body = nil
 
-	out, err := retry.DoWithRetryE( //nolint:staticcheck // deprecated wrapper; use HTTPDoWithRetryContext for context support
-		t, "HTTP "+options.Method+" to URL "+options.Url, retries,
-		sleepBetweenRetries, func() (string, error) {
+	policy := retryPolicy(options.RetryPolicy, retries, sleepBetweenRetries)
+
+	out, err := retry.DoWithPolicyContextE(
+		t, optionsContext(options.Context), "HTTP "+options.Method+" to URL "+options.Url, policy,
+		func() (string, error) {
 			options.Body = bytes.NewReader(data)
 
 			statusCode, out, err := HTTPDoWithOptionsE(t, options)
@@ -751,8 +761,10 @@ func HTTPDoWithValidationRetryWithOptionsE(
 	t testing.TestingT, options HttpDoOptions, expectedStatus int,
 	expectedBody string, retries int, sleepBetweenRetries time.Duration,
 ) error {
-	_, err := retry.DoWithRetryE(t, "HTTP "+options.Method+" to URL "+options.Url, retries, //nolint:staticcheck // deprecated wrapper; use HTTPDoWithValidationRetryContext for context support
-		sleepBetweenRetries, func() (string, error) {
+	policy := retryPolicy(options.RetryPolicy, retries, sleepBetweenRetries)
+
+	_, err := retry.DoWithPolicyContextE(t, optionsContext(options.Context), "HTTP "+options.Method+" to URL "+options.Url, policy,
+		func() (string, error) {
 			return "", HTTPDoWithValidationWithOptionsE(t, options, expectedStatus, expectedBody)
 		})
 
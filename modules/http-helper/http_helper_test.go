@@ -12,6 +12,7 @@ import (
 	"time"
 
 	httphelper "github.com/gruntwork-io/terratest/modules/http-helper"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -183,6 +184,43 @@ func TestEmptyRequestBodyWithRetryWithOptions(t *testing.T) {
 	require.Empty(t, response)
 }
 
+func TestRetryPolicyWithOptions(t *testing.T) {
+	t.Parallel()
+
+	failures := 3
+	ts := getTestServerForFunction(func(w http.ResponseWriter, _ *http.Request) {
+		if failures > 0 {
+			failures--
+
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	})
+	defer ts.Close()
+
+	// The retries and sleepBetweenRetries arguments are ignored in favor of the policy
+	options := httphelper.HttpGetOptions{
+		Url:         ts.URL,
+		Timeout:     10,
+		RetryPolicy: retry.ExponentialPolicy(5, time.Millisecond, 10*time.Millisecond),
+	}
+	httphelper.HttpGetWithRetryWithOptions(t, options, 200, "ok", 0, time.Hour)
+	require.Equal(t, 0, failures)
+
+	failures = 3
+	doOptions := httphelper.HttpDoOptions{
+		Method:      "GET",
+		Url:         ts.URL,
+		Timeout:     10,
+		RetryPolicy: retry.ConstantPolicy(1, time.Millisecond),
+	}
+	_, err := httphelper.HTTPDoWithRetryWithOptionsE(t, doOptions, 200, 10, time.Hour)
+	require.ErrorIs(t, err, retry.MaxRetriesExceeded{Description: "HTTP GET to URL " + ts.URL, MaxRetries: 1})
+}
+
 func bodyCopyHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
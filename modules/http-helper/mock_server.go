@@ -0,0 +1,527 @@
+package http_helper //nolint:staticcheck // package name determined by directory
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// defaultMockFailureStatus is the status code returned for injected failures if the route sets no FailureStatus.
+const defaultMockFailureStatus = http.StatusServiceUnavailable
+
+// ErrMockServerNotRunning is returned when adding a route to a mock server that has been shut down.
+var ErrMockServerNotRunning = errors.New("mock server is not running")
+
+// ErrMockServerNoTLS is returned when issuing a client certificate from a mock server that does not use TLS.
+var ErrMockServerNoTLS = errors.New("mock server does not use TLS")
+
+// MockServerOptions defines the routes and settings of a mock HTTP server started with StartMockServerContext.
+type MockServerOptions struct {
+	// Logger for the requests the server receives. Defaults to logger.Default.
+	Logger *logger.Logger
+
+	// Routes the server responds to. The first route that matches a request is used.
+	Routes []MockRoute
+
+	// If set to true, the server uses HTTPS with a certificate issued by a newly generated CA. Use the TLSConfig method
+	// of the server to get a client configuration that trusts the CA.
+	TLS bool
+
+	// If set to true along with TLS, the server requires clients to present a certificate issued by its CA, for
+	// testing mutual TLS. Use the IssueClientCertificate method of the server to generate one.
+	RequireClientCertificate bool
+}
+
+// MockRoute defines which requests a mock server answers and how.
+type MockRoute struct {
+	// Headers the request must have, by name. An empty value only requires the header to be present.
+	Headers map[string]string
+
+	// Handler responds to the request instead of Response, if set.
+	Handler http.HandlerFunc
+
+	// Method the request must use, such as GET. Matches any method if empty.
+	Method string
+
+	// Path the request must have, excluding the query string. Patterns supported by path.Match, such as
+	// /users/*, are allowed. Matches any path if empty.
+	Path string
+
+	// Response to return for matching requests
+	Response MockResponse
+
+	// Maximum number of requests the route matches, after which it is skipped. Unlimited if 0. Use it with several
+	// routes for the same path to return different responses to successive requests.
+	Times int
+
+	// Time to wait before responding
+	Latency time.Duration
+
+	// Fraction of matching requests, between 0 and 1, that fail with FailureStatus instead of the response.
+	FailureRate float64
+
+	// Status code of injected failures. Defaults to 503 Service Unavailable.
+	FailureStatus int
+
+	// If set to true, injected failures close the connection without sending any response, like a network failure.
+	DropConnection bool
+}
+
+// MockResponse is the response a mock server returns for a route.
+type MockResponse struct {
+	// Headers of the response
+	Headers map[string]string
+
+	// Body of the response
+	Body string
+
+	// BodyTemplate is a text/template that is executed with the MockRequest to produce the body, for example
+	// `{"id": "{{.Query.Get "id"}}"}`. It is used instead of Body if set.
+	BodyTemplate string
+
+	// Status code of the response. Defaults to 200 OK.
+	Status int
+}
+
+// MockRequest is a request received by a mock server.
+type MockRequest struct {
+	// Time the request was received
+	Time time.Time
+
+	Header http.Header
+	Query  url.Values
+	Method string
+	Path   string
+	Body   string
+
+	// Common name of the client certificate presented over mutual TLS, if any
+	ClientCommonName string
+
+	// Index of the route in the order routes were added that answered the request, or -1 if no route matched.
+	Route int
+}
+
+// MockServer is a mock HTTP server that answers requests with configured routes and records every request it receives.
+// It is shut down automatically when the test finishes.
+type MockServer struct {
+	server    *http.Server
+	logger    *logger.Logger
+	t         testing.TestingT
+	tlsConfig *tls.Config
+	ca        *certificateAuthority
+
+	// URL of the server, such as http://localhost:12345
+	URL string
+
+	// CACertPEM is the PEM encoded certificate of the CA that issued the server certificate, if TLS is enabled.
+	CACertPEM []byte
+
+	routes   []*mockRoute
+	requests []MockRequest
+	mutex    sync.Mutex
+	running  bool
+}
+
+// mockRoute is a route with its parsed template and the number of requests it matched.
+type mockRoute struct {
+	template *template.Template
+	MockRoute
+	matched int
+}
+
+// UnexpectedRequestCount is an error that occurs when a mock server did not receive a request as many times as
+// expected.
+type UnexpectedRequestCount struct {
+	Method   string
+	Path     string
+	Expected int
+	Actual   int
+}
+
+func (err UnexpectedRequestCount) Error() string {
+	return fmt.Sprintf("expected mock server to receive %s %s %d times, but received it %d times", err.Method, err.Path, err.Expected, err.Actual)
+}
+
+// StartMockServerContext starts a mock HTTP server on a random local port that answers requests as defined by the
+// given options and records them. The server is shut down when the test finishes. The ctx parameter is used when
+// establishing the network listener. This will fail the test if the server cannot be started.
+func StartMockServerContext(t testing.TestingT, ctx context.Context, options *MockServerOptions) *MockServer {
+	t.Helper()
+	server, err := StartMockServerContextE(t, ctx, options)
+	require.NoError(t, err)
+
+	return server
+}
+
+// StartMockServerContextE starts a mock HTTP server on a random local port that answers requests as defined by the
+// given options and records them. The server is shut down when the test finishes. The ctx parameter is used when
+// establishing the network listener.
+func StartMockServerContextE(t testing.TestingT, ctx context.Context, options *MockServerOptions) (*MockServer, error) {
+	if options == nil {
+		options = &MockServerOptions{}
+	}
+
+	mock := &MockServer{logger: options.Logger, t: t}
+	if mock.logger == nil {
+		mock.logger = logger.Default
+	}
+
+	for _, route := range options.Routes {
+		if err := mock.addRoute(route); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error listening: %w", err)
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mock.server = &http.Server{Handler: http.HandlerFunc(mock.serveHTTP), ReadHeaderTimeout: time.Minute}
+	mock.URL = fmt.Sprintf("http://localhost:%d", port)
+
+	if options.TLS {
+		if err := mock.enableTLS(options.RequireClientCertificate); err != nil {
+			_ = listener.Close()
+			return nil, err
+		}
+
+		mock.URL = fmt.Sprintf("https://localhost:%d", port)
+	}
+
+	mock.running = true
+
+	if options.TLS {
+		go func() { _ = mock.server.ServeTLS(listener, "", "") }()
+	} else {
+		go func() { _ = mock.server.Serve(listener) }()
+	}
+
+	mock.logger.Logf(t, "Started mock HTTP server at %s", mock.URL)
+
+	if c, ok := t.(testing.Cleaner); ok {
+		c.Cleanup(func() { _ = mock.ShutdownContextE(context.Background()) })
+	}
+
+	return mock, nil
+}
+
+// enableTLS generates a CA and a server certificate issued by it, and configures the server to use it. If
+// requireClientCertificate is true, clients must present a certificate issued by the CA.
+func (mock *MockServer) enableTLS(requireClientCertificate bool) error {
+	ca, err := newCertificateAuthority()
+	if err != nil {
+		return err
+	}
+
+	certificate, err := ca.issue([]string{"localhost", "127.0.0.1", "::1"}, false)
+	if err != nil {
+		return err
+	}
+
+	mock.ca = ca
+	mock.CACertPEM = ca.certPEM
+	mock.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	mock.tlsConfig = &tls.Config{RootCAs: ca.certPool(), MinVersion: tls.VersionTLS12}
+
+	if requireClientCertificate {
+		mock.server.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		mock.server.TLSConfig.ClientCAs = ca.certPool()
+	}
+
+	return nil
+}
+
+// IssueClientCertificateE returns a new client certificate with the given common name, issued by the CA of the server,
+// for testing mutual TLS. Returns ErrMockServerNoTLS if TLS is not enabled.
+func (mock *MockServer) IssueClientCertificateE(commonName string) (tls.Certificate, error) {
+	if mock.ca == nil {
+		return tls.Certificate{}, ErrMockServerNoTLS
+	}
+
+	return mock.ca.issue([]string{commonName}, true)
+}
+
+// IssueClientCertificate returns a new client certificate with the given common name, issued by the CA of the server,
+// for testing mutual TLS. This will fail the test if TLS is not enabled.
+func (mock *MockServer) IssueClientCertificate(t testing.TestingT, commonName string) tls.Certificate {
+	t.Helper()
+	certificate, err := mock.IssueClientCertificateE(commonName)
+	require.NoError(t, err)
+
+	return certificate
+}
+
+// TLSConfig returns a client TLS configuration that trusts the CA of the server, or nil if TLS is not enabled.
+func (mock *MockServer) TLSConfig() *tls.Config {
+	if mock.tlsConfig == nil {
+		return nil
+	}
+
+	return mock.tlsConfig.Clone()
+}
+
+// AddRouteE adds a route to the server. It is matched after all the routes added before it.
+func (mock *MockServer) AddRouteE(route MockRoute) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if !mock.running {
+		return ErrMockServerNotRunning
+	}
+
+	return mock.addRoute(route)
+}
+
+// AddRoute adds a route to the server. It is matched after all the routes added before it. This will fail the test if
+// the route is invalid.
+func (mock *MockServer) AddRoute(t testing.TestingT, route MockRoute) {
+	t.Helper()
+	require.NoError(t, mock.AddRouteE(route))
+}
+
+// addRoute validates the given route and appends it to the routes. The mutex must be held once the server is running.
+func (mock *MockServer) addRoute(route MockRoute) error {
+	if route.Path != "" {
+		if _, err := path.Match(route.Path, ""); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", route.Path, err)
+		}
+	}
+
+	parsed := &mockRoute{MockRoute: route}
+
+	if route.Response.BodyTemplate != "" {
+		bodyTemplate, err := template.New(route.Path).Parse(route.Response.BodyTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid body template for route %s %s: %w", route.Method, route.Path, err)
+		}
+
+		parsed.template = bodyTemplate
+	}
+
+	mock.routes = append(mock.routes, parsed)
+
+	return nil
+}
+
+// Requests returns all the requests the server received so far, in order.
+func (mock *MockServer) Requests() []MockRequest {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	return append([]MockRequest(nil), mock.requests...)
+}
+
+// ReceivedCount returns how many requests with the given method and path the server received. An empty method
+// matches any method, and the path may be a pattern supported by path.Match.
+func (mock *MockServer) ReceivedCount(method string, requestPath string) int {
+	count := 0
+
+	for _, request := range mock.Requests() {
+		if (method == "" || method == request.Method) && pathMatches(requestPath, request.Path) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// RequireReceivedE returns an UnexpectedRequestCount error if the server did not receive exactly the given number of
+// requests with the given method and path. An empty method matches any method, and the path may be a pattern
+// supported by path.Match.
+func (mock *MockServer) RequireReceivedE(method string, requestPath string, times int) error {
+	if count := mock.ReceivedCount(method, requestPath); count != times {
+		return UnexpectedRequestCount{Method: method, Path: requestPath, Expected: times, Actual: count}
+	}
+
+	return nil
+}
+
+// RequireReceived fails the test if the server did not receive exactly the given number of requests with the given
+// method and path. An empty method matches any method, and the path may be a pattern supported by path.Match.
+func (mock *MockServer) RequireReceived(t testing.TestingT, method string, requestPath string, times int) {
+	t.Helper()
+	require.NoError(t, mock.RequireReceivedE(method, requestPath, times))
+}
+
+// ShutdownContextE stops the server, waiting for the requests in progress to complete or ctx to be done. It is called
+// automatically when the test finishes.
+func (mock *MockServer) ShutdownContextE(ctx context.Context) error {
+	mock.mutex.Lock()
+	running := mock.running
+	mock.running = false
+	mock.mutex.Unlock()
+
+	if !running {
+		return nil
+	}
+
+	return mock.server.Shutdown(ctx)
+}
+
+// serveHTTP records the request and answers it with the first matching route.
+func (mock *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	// Restore the body that was read for the journal, so that route handlers can read it too
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	request := MockRequest{
+		Time:   time.Now(),
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   string(body),
+		Route:  -1,
+	}
+
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		request.ClientCommonName = r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	mock.mutex.Lock()
+
+	var route *mockRoute
+
+	for i, candidate := range mock.routes {
+		if candidate.matches(r) {
+			route, request.Route = candidate, i
+			candidate.matched++
+
+			break
+		}
+	}
+
+	mock.requests = append(mock.requests, request)
+	mock.mutex.Unlock()
+
+	mock.logger.Logf(mock.t, "Mock server received %s %s", r.Method, r.URL.RequestURI())
+
+	if route == nil {
+		http.Error(w, fmt.Sprintf("no mock route matches %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+
+	route.serve(w, r, request)
+}
+
+// matches returns true if the route has not been used up and the request has its method, path and headers.
+func (route *mockRoute) matches(r *http.Request) bool {
+	if route.Times > 0 && route.matched >= route.Times {
+		return false
+	}
+
+	if route.Method != "" && route.Method != r.Method {
+		return false
+	}
+
+	if route.Path != "" && !pathMatches(route.Path, r.URL.Path) {
+		return false
+	}
+
+	for name, value := range route.Headers {
+		values, found := r.Header[http.CanonicalHeaderKey(name)]
+		if !found || (value != "" && !slices.Contains(values, value)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// serve writes the response of the route, after the configured latency, or an injected failure.
+func (route *mockRoute) serve(w http.ResponseWriter, r *http.Request, request MockRequest) {
+	if route.Latency > 0 {
+		select {
+		case <-time.After(route.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if route.FailureRate > 0 && rand.Float64() < route.FailureRate { //nolint:gosec // failure injection does not need a secure random number
+		route.fail(w)
+		return
+	}
+
+	if route.Handler != nil {
+		route.Handler(w, r)
+		return
+	}
+
+	body := []byte(route.Response.Body)
+
+	if route.template != nil {
+		var buffer bytes.Buffer
+		if err := route.template.Execute(&buffer, request); err != nil {
+			http.Error(w, fmt.Sprintf("error executing body template: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		body = buffer.Bytes()
+	}
+
+	for name, value := range route.Response.Headers {
+		w.Header().Set(name, value)
+	}
+
+	status := route.Response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// fail writes an injected failure, or closes the connection without a response if the route drops connections.
+func (route *mockRoute) fail(w http.ResponseWriter) {
+	if route.DropConnection {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				_ = conn.Close()
+				return
+			}
+		}
+
+		// Connections that cannot be hijacked, such as HTTP/2 streams, are aborted instead
+		panic(http.ErrAbortHandler)
+	}
+
+	status := route.FailureStatus
+	if status == 0 {
+		status = defaultMockFailureStatus
+	}
+
+	http.Error(w, "injected failure", status)
+}
+
+// pathMatches returns true if the request path matches the given path or path.Match pattern.
+func pathMatches(pattern string, requestPath string) bool {
+	if pattern == requestPath {
+		return true
+	}
+
+	matched, err := path.Match(pattern, requestPath)
+
+	return err == nil && matched
+}
@@ -0,0 +1,138 @@
+package http_helper_test //nolint:staticcheck // package name determined by directory
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httphelper "github.com/gruntwork-io/terratest/modules/http-helper"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+func TestMockServer(t *testing.T) {
+	t.Parallel()
+
+	server := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		Routes: []httphelper.MockRoute{
+			{Method: "GET", Path: "/health", Response: httphelper.MockResponse{Body: "ok"}},
+			{Method: "GET", Path: "/flaky", Times: 2, Response: httphelper.MockResponse{Status: 500, Body: "error"}},
+			{Method: "GET", Path: "/flaky", Response: httphelper.MockResponse{Body: "recovered"}},
+			{
+				Method:   "POST",
+				Path:     "/users/*",
+				Headers:  map[string]string{"Authorization": "Bearer token"},
+				Response: httphelper.MockResponse{Status: 201, BodyTemplate: `{{.Method}} {{.Path}} {{.Query.Get "role"}} {{.Body}}`},
+			},
+		},
+	})
+
+	do := func(method string, path string, body string, headers map[string]string) (int, string) {
+		status, respBody, err := httphelper.HTTPDoWithOptionsE(t, httphelper.HttpDoOptions{
+			Method:  method,
+			Url:     server.URL + path,
+			Body:    strings.NewReader(body),
+			Headers: headers,
+			Timeout: 10,
+		})
+		require.NoError(t, err)
+
+		return status, respBody
+	}
+
+	status, body := do("GET", "/health", "", nil)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "ok", body)
+
+	for _, expected := range []string{"error", "error", "recovered", "recovered"} {
+		_, body = do("GET", "/flaky", "", nil)
+		assert.Equal(t, expected, body)
+	}
+
+	status, body = do("POST", "/users/alice?role=admin", "hello", map[string]string{"Authorization": "Bearer token"})
+	assert.Equal(t, 201, status)
+	assert.Equal(t, "POST /users/alice admin hello", body)
+
+	status, _ = do("POST", "/users/bob", "", nil)
+	assert.Equal(t, 404, status)
+
+	server.RequireReceived(t, "GET", "/flaky", 4)
+	server.RequireReceived(t, "POST", "/users/*", 2)
+	server.RequireReceived(t, "", "/health", 1)
+
+	var countErr httphelper.UnexpectedRequestCount
+	require.ErrorAs(t, server.RequireReceivedE("DELETE", "/health", 1), &countErr)
+	assert.Equal(t, 0, countErr.Actual)
+
+	requests := server.Requests()
+	require.Len(t, requests, 7)
+	assert.Equal(t, "hello", requests[5].Body)
+	assert.Equal(t, 3, requests[5].Route)
+	assert.Equal(t, -1, requests[6].Route)
+
+	server.AddRoute(t, httphelper.MockRoute{Path: "/added", Response: httphelper.MockResponse{Body: "added"}})
+	_, body = do("GET", "/added", "", nil)
+	assert.Equal(t, "added", body)
+
+	server.AddRoute(t, httphelper.MockRoute{Method: "POST", Path: "/webhook", Handler: func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("received " + string(payload)))
+	}})
+	_, body = do("POST", "/webhook", "hello", nil)
+	assert.Equal(t, "received hello", body)
+}
+
+func TestMockServerFailureInjection(t *testing.T) {
+	t.Parallel()
+
+	server := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		Routes: []httphelper.MockRoute{
+			{Path: "/slow", Latency: 200 * time.Millisecond, Response: httphelper.MockResponse{Body: "slow"}},
+			{Path: "/unavailable", FailureRate: 1, FailureStatus: 502},
+			{Path: "/dropped", FailureRate: 1, DropConnection: true},
+		},
+	})
+
+	start := time.Now()
+	status, body, err := httphelper.HTTPDoWithOptionsE(t, httphelper.HttpDoOptions{Method: "GET", Url: server.URL + "/slow", Timeout: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "slow", body)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	status, _, err = httphelper.HTTPDoWithOptionsE(t, httphelper.HttpDoOptions{Method: "GET", Url: server.URL + "/unavailable", Timeout: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 502, status)
+
+	_, _, err = httphelper.HTTPDoWithOptionsE(t, httphelper.HttpDoOptions{Method: "GET", Url: server.URL + "/dropped", Timeout: 10})
+	require.Error(t, err)
+}
+
+func TestMockServerTLS(t *testing.T) {
+	t.Parallel()
+
+	server := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		TLS:    true,
+		Routes: []httphelper.MockRoute{{Response: httphelper.MockResponse{Body: "secure"}}},
+	})
+
+	require.True(t, strings.HasPrefix(server.URL, "https://"))
+	assert.Contains(t, string(server.CACertPEM), "BEGIN CERTIFICATE")
+
+	status, body := httphelper.HTTPGetContext(t, t.Context(), server.URL, server.TLSConfig())
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "secure", body)
+
+	_, _, err := httphelper.HTTPGetContextE(t, t.Context(), server.URL, nil)
+	require.Error(t, err)
+
+	require.NoError(t, server.ShutdownContextE(t.Context()))
+	require.ErrorIs(t, server.AddRouteE(httphelper.MockRoute{}), httphelper.ErrMockServerNotRunning)
+}
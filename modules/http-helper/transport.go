@@ -0,0 +1,174 @@
+package http_helper //nolint:staticcheck // package name determined by directory
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ErrProxyAndBastion is returned when both a proxy and an SSH bastion host are configured for the same request.
+var ErrProxyAndBastion = errors.New("a proxy and an SSH bastion host cannot be used together")
+
+// ErrProxyAndResolve is returned when both a proxy and addresses to resolve host names to are configured for the same
+// request, as the proxy, not the client, resolves the host names of the requests sent through it.
+var ErrProxyAndResolve = errors.New("a proxy and resolved host addresses cannot be used together")
+
+// ConnectionOptions defines how HTTP requests connect to the server, for servers that are not directly reachable from
+// where the tests run, or that require client certificates.
+type ConnectionOptions struct {
+	// Resolve maps a host:port to the IP address to connect to instead of looking up the host name in DNS, like the
+	// curl --resolve option. For example, {"app.example.com:443": "10.0.0.5"}. The Host header and the TLS server name
+	// still use the host name. Requests to these hosts do not use the proxy from the environment, and it cannot be
+	// combined with ProxyURL.
+	Resolve map[string]string
+
+	// SSHBastion is a host that connections to the server are opened from, through an SSH tunnel.
+	SSHBastion *ssh.Host
+
+	// ProxyURL is the URL of an HTTP, HTTPS or SOCKS5 proxy to send requests through, such as
+	// socks5://localhost:1080. Credentials can be included in the URL.
+	ProxyURL string
+
+	// ClientCertificates are presented to servers that require mutual TLS, in addition to the certificates of the TLS
+	// configuration.
+	ClientCertificates []tls.Certificate
+}
+
+// newTransport returns an HTTP transport that uses the given TLS configuration and connection options, and a function
+// that closes the SSH connection to the bastion host if one was opened.
+func newTransport(t testing.TestingT, tlsConfig *tls.Config, options *ConnectionOptions) (*http.Transport, func(), error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsConfig
+
+	if len(options.ClientCertificates) > 0 {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		} else {
+			tlsConfig = tlsConfig.Clone()
+		}
+
+		tlsConfig.Certificates = append(tlsConfig.Certificates, options.ClientCertificates...)
+		tr.TLSClientConfig = tlsConfig
+	}
+
+	if options.ProxyURL != "" {
+		if options.SSHBastion != nil {
+			return nil, nil, ErrProxyAndBastion
+		}
+
+		if len(options.Resolve) > 0 {
+			return nil, nil, ErrProxyAndResolve
+		}
+
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+
+		tr.Proxy = http.ProxyURL(proxyURL)
+	} else if len(options.Resolve) > 0 {
+		tr.Proxy = func(req *http.Request) (*url.URL, error) {
+			if _, ok := options.Resolve[hostPort(req.URL)]; ok {
+				return nil, nil //nolint:nilnil // a nil URL means no proxy
+			}
+
+			return http.ProxyFromEnvironment(req)
+		}
+	}
+
+	dial := (&net.Dialer{}).DialContext
+	closeTunnel := func() {}
+
+	if options.SSHBastion != nil {
+		tunnel := &sshTunnel{t: t, host: options.SSHBastion}
+		dial, closeTunnel = tunnel.dialContext, tunnel.close
+
+		// Requests through the bastion host never use the proxy from the environment
+		tr.Proxy = nil
+	}
+
+	if len(options.Resolve) > 0 || options.SSHBastion != nil {
+		tr.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+			if ip, ok := options.Resolve[address]; ok {
+				_, port, err := net.SplitHostPort(address)
+				if err != nil {
+					return nil, err
+				}
+
+				address = net.JoinHostPort(ip, port)
+			}
+
+			return dial(ctx, network, address)
+		}
+	}
+
+	return tr, closeTunnel, nil
+}
+
+// hostPort returns the host and port of the given URL, using the default port of its scheme if it has none.
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// sshTunnel opens connections from an SSH bastion host. The SSH connection is opened on the first dial and reused for
+// all the following ones.
+type sshTunnel struct {
+	t      testing.TestingT
+	host   *ssh.Host
+	client *gossh.Client
+	mutex  sync.Mutex
+}
+
+func (tunnel *sshTunnel) dialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	client, err := tunnel.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.DialContext(ctx, network, address)
+}
+
+// connect returns the SSH client connected to the bastion host, connecting first if needed.
+func (tunnel *sshTunnel) connect(ctx context.Context) (*gossh.Client, error) {
+	tunnel.mutex.Lock()
+	defer tunnel.mutex.Unlock()
+
+	if tunnel.client == nil {
+		client, err := ssh.NewClientContextE(tunnel.t, ctx, tunnel.host)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to SSH bastion host %s: %w", tunnel.host.Hostname, err)
+		}
+
+		tunnel.client = client
+	}
+
+	return tunnel.client, nil
+}
+
+func (tunnel *sshTunnel) close() {
+	tunnel.mutex.Lock()
+	defer tunnel.mutex.Unlock()
+
+	if tunnel.client != nil {
+		_ = tunnel.client.Close()
+		tunnel.client = nil
+	}
+}
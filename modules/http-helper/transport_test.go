@@ -0,0 +1,210 @@
+package http_helper_test //nolint:staticcheck // package name determined by directory
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+
+	httphelper "github.com/gruntwork-io/terratest/modules/http-helper"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+)
+
+func TestHTTPWithClientCertificates(t *testing.T) {
+	t.Parallel()
+
+	server := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger:                   logger.Discard,
+		TLS:                      true,
+		RequireClientCertificate: true,
+		Routes:                   []httphelper.MockRoute{{Response: httphelper.MockResponse{Body: "hello"}}},
+	})
+
+	options := httphelper.HttpDoOptions{Method: "GET", Url: server.URL, TlsConfig: server.TLSConfig(), Timeout: 10}
+
+	_, _, err := httphelper.HTTPDoWithOptionsE(t, options)
+	require.Error(t, err)
+
+	options.ClientCertificates = append(options.ClientCertificates, server.IssueClientCertificate(t, "terratest-client"))
+
+	status, body, err := httphelper.HTTPDoWithOptionsE(t, options)
+	require.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "hello", body)
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "terratest-client", requests[0].ClientCommonName)
+}
+
+func TestHTTPWithProxyAndResolve(t *testing.T) {
+	t.Parallel()
+
+	proxy := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		Routes: []httphelper.MockRoute{{Response: httphelper.MockResponse{Body: "from proxy"}}},
+	})
+	target := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		Routes: []httphelper.MockRoute{{Response: httphelper.MockResponse{Body: "from target"}}},
+	})
+
+	getOptions := httphelper.HttpGetOptions{Url: "http://service.invalid/hello", Timeout: 10}
+	getOptions.ProxyURL = proxy.URL
+
+	status, body, err := httphelper.HttpGetWithOptionsE(t, getOptions)
+	require.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "from proxy", body)
+	proxy.RequireReceived(t, "GET", "/hello", 1)
+
+	targetURL, err := url.Parse(target.URL)
+	require.NoError(t, err)
+
+	doOptions := httphelper.HttpDoOptions{Method: "GET", Url: "http://service.invalid:" + targetURL.Port() + "/hello", Timeout: 10}
+	doOptions.Resolve = map[string]string{"service.invalid:" + targetURL.Port(): "127.0.0.1"}
+
+	status, body, err = httphelper.HTTPDoWithOptionsE(t, doOptions)
+	require.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "from target", body)
+	target.RequireReceived(t, "GET", "/hello", 1)
+
+	doOptions.ProxyURL = proxy.URL
+
+	_, _, err = httphelper.HTTPDoWithOptionsE(t, doOptions)
+	require.ErrorIs(t, err, httphelper.ErrProxyAndResolve)
+	proxy.RequireReceived(t, "GET", "/hello", 1)
+	target.RequireReceived(t, "GET", "/hello", 1)
+}
+
+func TestHTTPWithSSHBastion(t *testing.T) {
+	t.Parallel()
+
+	target := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		Routes: []httphelper.MockRoute{{Response: httphelper.MockResponse{Body: "private"}}},
+	})
+
+	targetURL, err := url.Parse(target.URL)
+	require.NoError(t, err)
+
+	options := httphelper.HttpDoOptions{Method: "GET", Url: "http://private.invalid:" + targetURL.Port(), Timeout: 10}
+	options.SSHBastion = startSSHBastion(t)
+	options.Resolve = map[string]string{"private.invalid:" + targetURL.Port(): "127.0.0.1"}
+
+	status, body, err := httphelper.HTTPDoWithOptionsE(t, options)
+	require.NoError(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "private", body)
+
+	options.ProxyURL = "http://localhost:3128"
+	_, _, err = httphelper.HTTPDoWithOptionsE(t, options)
+	require.ErrorIs(t, err, httphelper.ErrProxyAndBastion)
+}
+
+// startSSHBastion starts an in-process SSH server that only supports opening TCP connections, and returns a host
+// that connects to it with a password.
+func startSSHBastion(t *testing.T) *ssh.Host {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := gossh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+
+	config := &gossh.ServerConfig{
+		PasswordCallback: func(_ gossh.ConnMetadata, password []byte) (*gossh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, errors.New("wrong password")
+			}
+
+			return &gossh.Permissions{}, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveSSHTunnel(conn, config)
+		}
+	}()
+
+	return &ssh.Host{
+		Hostname:    "127.0.0.1",
+		CustomPort:  listener.Addr().(*net.TCPAddr).Port,
+		SshUserName: "terratest",
+		Password:    "secret",
+	}
+}
+
+func serveSSHTunnel(conn net.Conn, config *gossh.ServerConfig) {
+	_, channels, requests, err := gossh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+
+	go gossh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(gossh.UnknownChannelType, "only direct-tcpip is supported")
+			continue
+		}
+
+		var payload struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+
+		if err := gossh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+			_ = newChannel.Reject(gossh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			_ = newChannel.Reject(gossh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			_ = target.Close()
+			continue
+		}
+
+		go gossh.DiscardRequests(channelRequests)
+
+		go func() {
+			_, _ = io.Copy(channel, target)
+			_ = channel.Close()
+		}()
+
+		go func() {
+			_, _ = io.Copy(target, channel)
+			_ = target.Close()
+		}()
+	}
+}
@@ -0,0 +1,605 @@
+package http_helper //nolint:staticcheck // package name determined by directory
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Response is an HTTP response, with its body read, that a ResponseValidator checks.
+type Response struct {
+	// Header of the response
+	Header http.Header
+
+	// TLS connection state of HTTPS responses, or nil for plain HTTP
+	TLS *tls.ConnectionState
+
+	// URL that returned the response, after following redirects
+	URL *url.URL
+
+	// Protocol of the response, such as HTTP/1.1 or HTTP/2.0
+	Proto string
+
+	// Body of the response, with leading and trailing whitespace removed
+	Body string
+
+	// Cookies set by the response
+	Cookies []*http.Cookie
+
+	// Redirects that were followed to get the response, in order
+	Redirects []Redirect
+
+	// Status code of the response
+	StatusCode int
+}
+
+// Redirect is a redirect response that was followed.
+type Redirect struct {
+	// URL that returned the redirect
+	URL string
+
+	// Status code of the redirect, such as 301 or 302
+	StatusCode int
+}
+
+// ResponseValidator checks an HTTP response and returns an error describing what is wrong with it, or nil if it is as
+// expected. Validators can be combined with AllOf.
+type ResponseValidator func(response *Response) error
+
+// ResponseValidationFailed is an error that occurs if one or more response validators fail.
+type ResponseValidationFailed struct {
+	Err    error
+	Url    string //nolint:staticcheck,revive // consistent with ValidationFunctionFailed
+	Status int
+}
+
+func (err ResponseValidationFailed) Error() string {
+	return fmt.Sprintf("Validation failed for URL %s. Response status: %d. Errors:\n%v", err.Url, err.Status, err.Err)
+}
+
+func (err ResponseValidationFailed) Unwrap() error {
+	return err.Err
+}
+
+// HTTPDoWithValidatorsE performs the given HTTP request and checks the response with all the given validators. Returns
+// a ResponseValidationFailed error listing every validator that failed.
+//
+//nolint:gocritic // consistent with the other functions that take HttpDoOptions
+func HTTPDoWithValidatorsE(t testing.TestingT, options HttpDoOptions, validators ...ResponseValidator) (*Response, error) {
+	response, err := doRequestE(t, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := AllOf(validators...)(response); err != nil {
+		return response, ResponseValidationFailed{Url: options.Url, Status: response.StatusCode, Err: err}
+	}
+
+	return response, nil
+}
+
+// HTTPDoWithValidators performs the given HTTP request and checks the response with all the given validators. This
+// will fail the test if the request fails or any validator fails.
+//
+//nolint:gocritic // consistent with the other functions that take HttpDoOptions
+func HTTPDoWithValidators(t testing.TestingT, options HttpDoOptions, validators ...ResponseValidator) *Response {
+	t.Helper()
+	response, err := HTTPDoWithValidatorsE(t, options, validators...)
+	require.NoError(t, err)
+
+	return response
+}
+
+// HTTPDoWithRetryWithValidatorsE repeatedly performs the given HTTP request until the response passes all the given
+// validators or until max retries has been exceeded, for example to wait until a load balancer serves the right
+// certificate. Returns the last response.
+//
+//nolint:gocritic // consistent with the other functions that take HttpDoOptions
+func HTTPDoWithRetryWithValidatorsE(
+	t testing.TestingT, options HttpDoOptions, retries int, sleepBetweenRetries time.Duration,
+	validators ...ResponseValidator,
+) (*Response, error) {
+	var data []byte
+
+	if options.Body != nil {
+		// Cache the body, so we can reuse it for retried requests.
+		b, err := io.ReadAll(options.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		data = b
+	}
+
+	policy := retryPolicy(options.RetryPolicy, retries, sleepBetweenRetries)
+
+	out, err := retry.DoWithPolicyInterfaceContextE(t, optionsContext(options.Context), "HTTP "+options.Method+" to URL "+options.Url, policy,
+		func() (any, error) {
+			options.Body = bytes.NewReader(data)
+			return HTTPDoWithValidatorsE(t, options, validators...)
+		})
+
+	response, _ := out.(*Response)
+
+	return response, err
+}
+
+// HTTPDoWithRetryWithValidators repeatedly performs the given HTTP request until the response passes all the given
+// validators or until max retries has been exceeded, and returns the last response. This will fail the test if max
+// retries has been exceeded.
+//
+//nolint:gocritic // consistent with the other functions that take HttpDoOptions
+func HTTPDoWithRetryWithValidators(
+	t testing.TestingT, options HttpDoOptions, retries int, sleepBetweenRetries time.Duration,
+	validators ...ResponseValidator,
+) *Response {
+	t.Helper()
+	response, err := HTTPDoWithRetryWithValidatorsE(t, options, retries, sleepBetweenRetries, validators...)
+	require.NoError(t, err)
+
+	return response
+}
+
+// doRequestE performs the given HTTP request, reads the whole response and records the redirects followed.
+//
+//nolint:gocritic // consistent with the other functions that take HttpDoOptions
+func doRequestE(t testing.TestingT, options HttpDoOptions) (*Response, error) {
+	logger.Default.Logf(t, "Making an HTTP %s call to URL %s", options.Method, options.Url)
+
+	ctx := optionsContext(options.Context)
+
+	tr, closeTransport, err := newTransport(t, options.TlsConfig, &options.ConnectionOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeTransport()
+
+	var redirects []Redirect
+
+	client := http.Client{
+		// By default, Go does not impose a timeout, so an HTTP connection attempt can hang for a LONG time.
+		Timeout:   time.Duration(options.Timeout) * time.Second,
+		Transport: tr,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			const maxRedirects = 10
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+
+			redirects = append(redirects, Redirect{URL: via[len(via)-1].URL.String(), StatusCode: req.Response.StatusCode})
+
+			return nil
+		},
+	}
+
+	req := newRequestWithContext(ctx, options.Method, options.Url, options.Body, options.Headers)
+	if req == nil {
+		return nil, fmt.Errorf("invalid HTTP request %s %s", options.Method, options.Url)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       strings.TrimSpace(string(respBody)),
+		Cookies:    resp.Cookies(),
+		Proto:      resp.Proto,
+		TLS:        resp.TLS,
+		URL:        resp.Request.URL,
+		Redirects:  redirects,
+	}, nil
+}
+
+// AllOf returns a validator that runs all the given validators and fails with all their errors joined if any of them
+// fails.
+func AllOf(validators ...ResponseValidator) ResponseValidator {
+	return func(response *Response) error {
+		var errs []error
+
+		for _, validator := range validators {
+			if err := validator(response); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+// ExpectStatus returns a validator that checks the status code is one of the given codes.
+func ExpectStatus(codes ...int) ResponseValidator {
+	return func(response *Response) error {
+		if !slices.Contains(codes, response.StatusCode) {
+			return fmt.Errorf("expected status %v but got %d", codes, response.StatusCode)
+		}
+
+		return nil
+	}
+}
+
+// ExpectBody returns a validator that checks the body is exactly the given text, ignoring leading and trailing
+// whitespace.
+func ExpectBody(expected string) ResponseValidator {
+	return func(response *Response) error {
+		if response.Body != strings.TrimSpace(expected) {
+			return fmt.Errorf("expected body %q but got %q", expected, response.Body)
+		}
+
+		return nil
+	}
+}
+
+// ExpectBodyContains returns a validator that checks the body contains the given text.
+func ExpectBodyContains(text string) ResponseValidator {
+	return func(response *Response) error {
+		if !strings.Contains(response.Body, text) {
+			return fmt.Errorf("expected body to contain %q but got %q", text, response.Body)
+		}
+
+		return nil
+	}
+}
+
+// ExpectJSONPath returns a validator that checks the value at the given JSONPath in the JSON body is equal to
+// expected, after both are converted to JSON. The path uses the kubectl JSONPath syntax, such as {.items[0].name}. If
+// the path can match several values, as with a wildcard, a slice, a filter or a range, such as {.items[*].name},
+// expected must be a slice of all the matches, even if there is only one.
+func ExpectJSONPath(path string, expected any) ResponseValidator {
+	return func(response *Response) error {
+		values, err := jsonPathValues(response.Body, path)
+		if err != nil {
+			return err
+		}
+
+		var actual any = values
+		if len(values) == 1 && isSingleValueJSONPath(path) {
+			actual = values[0]
+		}
+
+		expectedJSON, err := json.Marshal(expected)
+		if err != nil {
+			return err
+		}
+
+		var normalized any
+		if err := json.Unmarshal(expectedJSON, &normalized); err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(normalized, actual) {
+			actualJSON, _ := json.Marshal(actual)
+			return fmt.Errorf("expected JSONPath %s to be %s but got %s", path, expectedJSON, actualJSON)
+		}
+
+		return nil
+	}
+}
+
+// ExpectJSONPathExists returns a validator that checks the given JSONPath, such as {.metadata.name}, matches at least
+// one value in the JSON body.
+func ExpectJSONPathExists(path string) ResponseValidator {
+	return func(response *Response) error {
+		values, err := jsonPathValues(response.Body, path)
+		if err != nil {
+			return err
+		}
+
+		if len(values) == 0 {
+			return fmt.Errorf("expected JSONPath %s to match a value", path)
+		}
+
+		return nil
+	}
+}
+
+// ExpectJSONSchema returns a validator that checks the JSON body is valid according to the given JSON schema, in the
+// OpenAPI v3 dialect that Kubernetes uses.
+func ExpectJSONSchema(schema string) ResponseValidator {
+	return func(response *Response) error {
+		var parsed spec.Schema
+		if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+			return fmt.Errorf("invalid JSON schema: %w", err)
+		}
+
+		var body any
+		if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+			return fmt.Errorf("body is not valid JSON: %w", err)
+		}
+
+		result := validate.NewSchemaValidator(&parsed, nil, "", strfmt.Default).Validate(body)
+		if !result.IsValid() {
+			return fmt.Errorf("body does not match the JSON schema: %w", errors.Join(result.Errors...))
+		}
+
+		return nil
+	}
+}
+
+// ExpectHeader returns a validator that checks the response has the given header with the given value.
+func ExpectHeader(name string, expected string) ResponseValidator {
+	return func(response *Response) error {
+		values := response.Header.Values(name)
+		if !slices.Contains(values, expected) {
+			return fmt.Errorf("expected header %s to be %q but got %q", name, expected, values)
+		}
+
+		return nil
+	}
+}
+
+// ExpectHeaderMatches returns a validator that checks the response has the given header with a value that matches the
+// given regular expression.
+func ExpectHeaderMatches(name string, regex *regexp.Regexp) ResponseValidator {
+	return func(response *Response) error {
+		values := response.Header.Values(name)
+		if !slices.ContainsFunc(values, regex.MatchString) {
+			return fmt.Errorf("expected header %s to match %s but got %q", name, regex, values)
+		}
+
+		return nil
+	}
+}
+
+// ExpectHeaderAbsent returns a validator that checks the response does not have the given header.
+func ExpectHeaderAbsent(name string) ResponseValidator {
+	return func(response *Response) error {
+		if values := response.Header.Values(name); len(values) > 0 {
+			return fmt.Errorf("expected no header %s but got %q", name, values)
+		}
+
+		return nil
+	}
+}
+
+// ExpectCookie returns a validator that checks the response sets the given cookie. If value is not empty, the cookie
+// must also have that value.
+func ExpectCookie(name string, value string) ResponseValidator {
+	return func(response *Response) error {
+		cookie := findCookie(response.Cookies, name)
+		if cookie == nil {
+			return fmt.Errorf("expected cookie %s to be set", name)
+		}
+
+		if value != "" && cookie.Value != value {
+			return fmt.Errorf("expected cookie %s to be %q but got %q", name, value, cookie.Value)
+		}
+
+		return nil
+	}
+}
+
+// ExpectCookieFlags returns a validator that checks the response sets the given cookie with the given Secure and
+// HttpOnly attributes.
+func ExpectCookieFlags(name string, secure bool, httpOnly bool) ResponseValidator {
+	return func(response *Response) error {
+		cookie := findCookie(response.Cookies, name)
+		if cookie == nil {
+			return fmt.Errorf("expected cookie %s to be set", name)
+		}
+
+		if cookie.Secure != secure || cookie.HttpOnly != httpOnly {
+			return fmt.Errorf("expected cookie %s to have Secure=%t and HttpOnly=%t but got Secure=%t and HttpOnly=%t", name, secure, httpOnly, cookie.Secure, cookie.HttpOnly)
+		}
+
+		return nil
+	}
+}
+
+// ExpectRedirectChain returns a validator that checks the request visited exactly the given URLs, starting with the
+// requested URL and ending with the URL that returned the final response. Pass only the requested URL to check no
+// redirect was followed.
+func ExpectRedirectChain(urls ...string) ResponseValidator {
+	return func(response *Response) error {
+		visited := make([]string, 0, len(response.Redirects)+1)
+		for _, redirect := range response.Redirects {
+			visited = append(visited, redirect.URL)
+		}
+
+		visited = append(visited, response.URL.String())
+
+		if !slices.Equal(urls, visited) {
+			return fmt.Errorf("expected redirect chain %q but got %q", urls, visited)
+		}
+
+		return nil
+	}
+}
+
+// ExpectHTTP2 returns a validator that checks the response was received over HTTP/2.
+func ExpectHTTP2() ResponseValidator {
+	return func(response *Response) error {
+		if response.Proto != "HTTP/2.0" {
+			return fmt.Errorf("expected HTTP/2.0 but got %s", response.Proto)
+		}
+
+		return nil
+	}
+}
+
+// ExpectMinTLSVersion returns a validator that checks the connection used at least the given TLS version, such as
+// tls.VersionTLS12.
+func ExpectMinTLSVersion(version uint16) ResponseValidator {
+	return func(response *Response) error {
+		if response.TLS == nil {
+			return errors.New("expected a TLS connection but got plain HTTP")
+		}
+
+		if response.TLS.Version < version {
+			return fmt.Errorf("expected at least %s but got %s", tls.VersionName(version), tls.VersionName(response.TLS.Version))
+		}
+
+		return nil
+	}
+}
+
+// ExpectCertificateIssuer returns a validator that checks the server certificate was issued by a CA with the given
+// common name.
+func ExpectCertificateIssuer(commonName string) ResponseValidator {
+	return certificateValidator(func(certificate *x509.Certificate, _ *Response) error {
+		if certificate.Issuer.CommonName != commonName {
+			return fmt.Errorf("expected certificate issuer %q but got %q", commonName, certificate.Issuer.CommonName)
+		}
+
+		return nil
+	})
+}
+
+// ExpectCertificateSAN returns a validator that checks the server certificate is valid for all the given host names
+// and IP addresses, taking wildcards into account.
+func ExpectCertificateSAN(names ...string) ResponseValidator {
+	return certificateValidator(func(certificate *x509.Certificate, _ *Response) error {
+		var errs []error
+
+		for _, name := range names {
+			if err := certificate.VerifyHostname(name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+// ExpectCertificateValidFor returns a validator that checks the server certificate does not expire within the given
+// duration.
+func ExpectCertificateValidFor(duration time.Duration) ResponseValidator {
+	return certificateValidator(func(certificate *x509.Certificate, _ *Response) error {
+		if time.Now().Add(duration).After(certificate.NotAfter) {
+			return fmt.Errorf("expected certificate to be valid for %s but it expires at %s", duration, certificate.NotAfter)
+		}
+
+		return nil
+	})
+}
+
+// ExpectCertificateChainValid returns a validator that checks the certificate chain the server sent is trusted by the
+// given roots, or the system roots if nil, and is valid for the host name of the URL. This is useful along with a TLS
+// configuration that skips verification, to report why a certificate is not trusted.
+func ExpectCertificateChainValid(roots *x509.CertPool) ResponseValidator {
+	return certificateValidator(func(certificate *x509.Certificate, response *Response) error {
+		intermediates := x509.NewCertPool()
+		for _, intermediate := range response.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(intermediate)
+		}
+
+		_, err := certificate.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			DNSName:       response.URL.Hostname(),
+		})
+
+		return err
+	})
+}
+
+// certificateValidator returns a validator that checks the server certificate with the given function.
+func certificateValidator(check func(certificate *x509.Certificate, response *Response) error) ResponseValidator {
+	return func(response *Response) error {
+		if response.TLS == nil || len(response.TLS.PeerCertificates) == 0 {
+			return errors.New("expected a server certificate but the response was not received over TLS")
+		}
+
+		return check(response.TLS.PeerCertificates[0], response)
+	}
+}
+
+// jsonPathValues returns all the values at the given JSONPath in the given JSON document.
+func jsonPathValues(body string, path string) ([]any, error) {
+	var document any
+	if err := json.Unmarshal([]byte(body), &document); err != nil {
+		return nil, fmt.Errorf("body is not valid JSON: %w", err)
+	}
+
+	parser := jsonpath.New("response").AllowMissingKeys(true)
+	if err := parser.Parse(path); err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %s: %w", path, err)
+	}
+
+	results, err := parser.FindResults(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []any
+
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, value.Interface())
+		}
+	}
+
+	return values, nil
+}
+
+// isSingleValueJSONPath returns true if the given JSONPath selects at most one value: a single expression made only of
+// fields and array indexes, such as {.items[0].name}.
+func isSingleValueJSONPath(path string) bool {
+	parser, err := jsonpath.Parse("response", path)
+	if err != nil || len(parser.Root.Nodes) != 1 {
+		return false
+	}
+
+	list, ok := parser.Root.Nodes[0].(*jsonpath.ListNode)
+	if !ok {
+		return false
+	}
+
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *jsonpath.FieldNode:
+		case *jsonpath.ArrayNode:
+			// The end of a single index, such as [0], is derived from its start, unlike the end of a slice
+			if !node.Params[1].Derived {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// findCookie returns the cookie with the given name, or nil if there is none.
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
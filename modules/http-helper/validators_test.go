@@ -0,0 +1,110 @@
+package http_helper_test //nolint:staticcheck // package name determined by directory
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	httphelper "github.com/gruntwork-io/terratest/modules/http-helper"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+func TestResponseValidators(t *testing.T) {
+	t.Parallel()
+
+	server := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		TLS:    true,
+		Routes: []httphelper.MockRoute{
+			{Path: "/old", Handler: func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/new", http.StatusMovedPermanently) }},
+			{Path: "/new", Handler: func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/api", http.StatusFound) }},
+			{Path: "/api", Handler: func(w http.ResponseWriter, _ *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Secure: true, HttpOnly: true})
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"name": "terratest", "version": 3, "tags": ["a", "b"], "items": [{"name": "only"}]}`))
+			}},
+		},
+	})
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(server.CACertPEM))
+
+	options := httphelper.HttpDoOptions{Method: "GET", Url: server.URL + "/old", TlsConfig: server.TLSConfig(), Timeout: 10}
+
+	response := httphelper.HTTPDoWithValidators(t, options,
+		httphelper.ExpectStatus(http.StatusOK),
+		httphelper.ExpectBodyContains("terratest"),
+		httphelper.ExpectJSONPath("{.name}", "terratest"),
+		httphelper.ExpectJSONPath("{.version}", 3),
+		httphelper.ExpectJSONPath("{.tags[*]}", []string{"a", "b"}),
+		httphelper.ExpectJSONPath("{.tags[0:1]}", []string{"a"}),
+		httphelper.ExpectJSONPath("{.items[*].name}", []string{"only"}),
+		httphelper.ExpectJSONPath("{.items[0].name}", "only"),
+		httphelper.ExpectJSONPathExists("{.tags[0]}"),
+		httphelper.ExpectJSONSchema(`{"type": "object", "required": ["name", "version"], "properties": {"version": {"type": "integer", "minimum": 1}}}`),
+		httphelper.ExpectHeader("Content-Type", "application/json"),
+		httphelper.ExpectHeaderMatches("Content-Type", regexp.MustCompile(`^application/`)),
+		httphelper.ExpectHeaderAbsent("X-Powered-By"),
+		httphelper.ExpectCookie("session", "abc"),
+		httphelper.ExpectCookieFlags("session", true, true),
+		httphelper.ExpectRedirectChain(server.URL+"/old", server.URL+"/new", server.URL+"/api"),
+		httphelper.ExpectHTTP2(),
+		httphelper.ExpectMinTLSVersion(tls.VersionTLS12),
+		httphelper.ExpectCertificateIssuer("Terratest CA"),
+		httphelper.ExpectCertificateSAN("localhost", "127.0.0.1"),
+		httphelper.ExpectCertificateValidFor(time.Hour),
+		httphelper.ExpectCertificateChainValid(roots),
+	)
+	assert.Equal(t, []httphelper.Redirect{{URL: server.URL + "/old", StatusCode: 301}, {URL: server.URL + "/new", StatusCode: 302}}, response.Redirects)
+
+	_, err := httphelper.HTTPDoWithValidatorsE(t, options,
+		httphelper.ExpectJSONPath("{.version}", 4),
+		httphelper.ExpectJSONPath("{.items[*].name}", "only"),
+		httphelper.ExpectJSONSchema(`{"type": "object", "required": ["missing"]}`),
+		httphelper.ExpectCertificateSAN("example.com"),
+		httphelper.ExpectCertificateValidFor(48*time.Hour),
+		httphelper.ExpectCertificateChainValid(x509.NewCertPool()),
+		httphelper.ExpectMinTLSVersion(tls.VersionTLS13+1),
+	)
+
+	var validationErr httphelper.ResponseValidationFailed
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, http.StatusOK, validationErr.Status)
+	assert.Contains(t, err.Error(), "expected JSONPath {.version} to be 4 but got 3")
+	assert.Contains(t, err.Error(), `expected JSONPath {.items[*].name} to be "only" but got ["only"]`)
+	assert.Contains(t, err.Error(), "missing")
+	assert.Contains(t, err.Error(), "example.com")
+	assert.Contains(t, err.Error(), "expires at")
+	assert.Contains(t, err.Error(), "unknown authority")
+	assert.Contains(t, err.Error(), "expected at least")
+}
+
+func TestHTTPDoWithRetryWithValidators(t *testing.T) {
+	t.Parallel()
+
+	server := httphelper.StartMockServerContext(t, t.Context(), &httphelper.MockServerOptions{
+		Logger: logger.Discard,
+		Routes: []httphelper.MockRoute{
+			{Times: 2, Response: httphelper.MockResponse{Body: `{"ready": false}`}},
+			{Response: httphelper.MockResponse{Body: `{"ready": true}`}},
+		},
+	})
+
+	options := httphelper.HttpDoOptions{Method: "GET", Url: server.URL, Timeout: 10}
+
+	response := httphelper.HTTPDoWithRetryWithValidators(t, options, 5, time.Millisecond, httphelper.ExpectJSONPath("{.ready}", true))
+	assert.JSONEq(t, `{"ready": true}`, response.Body)
+	server.RequireReceived(t, "GET", "/", 3)
+
+	options.RetryPolicy = retry.ConstantPolicy(1, time.Millisecond)
+	_, err := httphelper.HTTPDoWithRetryWithValidatorsE(t, options, 0, 0, httphelper.ExpectStatus(http.StatusNoContent))
+	require.ErrorAs(t, err, &httphelper.ResponseValidationFailed{})
+	require.ErrorIs(t, err, retry.MaxRetriesExceeded{Description: "HTTP GET to URL " + server.URL, MaxRetries: 1})
+}
@@ -85,7 +85,11 @@ func GetKubernetesClientFromOptionsContextE(t testing.TestingT, ctx context.Cont
 		}
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	if options.ImpersonateUser != "" {
+		options.Logger.Logf(t, "Impersonating user %s with groups %v", options.ImpersonateUser, options.ImpersonateGroups)
+	}
+
+	clientset, err := kubernetes.NewForConfig(options.applyImpersonation(config))
 	if err != nil {
 		return nil, err
 	}
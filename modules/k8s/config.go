@@ -80,7 +80,7 @@ func DeleteConfigContextWithPathE(t testing.TestingT, kubeConfigPath string, con
 	// Check if the context we want to delete actually exists, and if so, delete it.
 	_, ok := rawConfig.Contexts[contextName]
 	if !ok {
-		logger.Default.Logf(t, "WARNING: Could not find context %s from config at path %s", contextName, kubeConfigPath)
+		logger.Default.Warnf(t, "Could not find context %s from config at path %s", contextName, kubeConfigPath)
 		return nil
 	}
 
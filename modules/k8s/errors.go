@@ -3,6 +3,7 @@ package k8s
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -25,6 +26,12 @@ var (
 
 	// ErrNilPod is returned when a nil pod is passed to a function that requires a non-nil pod.
 	ErrNilPod = errors.New("cannot get port for pod which is nil")
+
+	// ErrNoTunnelPorts is returned when a tunnel is opened without any port pairs to forward.
+	ErrNoTunnelPorts = errors.New("tunnel has no ports to forward")
+
+	// ErrTunnelClosed is returned when a tunnel is closed while it is trying to reconnect.
+	ErrTunnelClosed = errors.New("tunnel closed")
 )
 
 // IngressNotAvailable is returned when a Kubernetes service is not yet available to accept traffic.
@@ -361,3 +368,90 @@ func (err CronJobNotSucceeded) Error() string {
 func NewCronJobNotSucceeded(cronJob *batchv1.CronJob) CronJobNotSucceeded {
 	return CronJobNotSucceeded{cronJob}
 }
+
+// LogLineNotFound is returned when no log line matching a regular expression was captured within the timeout.
+type LogLineNotFound struct {
+	Regex   string
+	Timeout time.Duration
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err LogLineNotFound) Error() string {
+	return fmt.Sprintf("No log line matching '%s' found within %s", err.Regex, err.Timeout)
+}
+
+// UnknownLocalClusterProvider is returned if the given local cluster provider does not match the list of known
+// providers.
+type UnknownLocalClusterProvider struct {
+	Provider LocalClusterProvider
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err UnknownLocalClusterProvider) Error() string {
+	return fmt.Sprintf("LocalClusterProvider ID %d is unknown", err.Provider)
+}
+
+// RBACMatrixMismatch is returned when the permissions granted by the cluster do not match an RBAC permission matrix.
+type RBACMatrixMismatch struct {
+	Mismatches RBACMismatches
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err RBACMatrixMismatch) Error() string {
+	return fmt.Sprintf("%d RBAC expectations did not match:\n%s", len(err.Mismatches), err.Mismatches)
+}
+
+// UnknownConnectivityProtocol is returned if the given connectivity protocol does not match the list of known protocols.
+type UnknownConnectivityProtocol struct {
+	Protocol ConnectivityProtocol
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err UnknownConnectivityProtocol) Error() string {
+	return fmt.Sprintf("ConnectivityProtocol ID %d is unknown", err.Protocol)
+}
+
+// ConnectivityTargetNotFound is returned when no available pod matches the labels of a connectivity target.
+type ConnectivityTargetNotFound struct {
+	Target ConnectivityTarget
+}
+
+// Error is a simple function to return a formatted error message as a string
+//
+//nolint:gocritic // hugeParam: error types are passed by value
+func (err ConnectivityTargetNotFound) Error() string {
+	return fmt.Sprintf("No available pod found for connectivity target %s", err.Target)
+}
+
+// ConnectivityProbeFailed is returned when a connectivity check could not be run at all, for example because the source
+// pod does not exist, exec into it is forbidden, or the probe command is missing from its image. The outcome of the
+// check is unknown, so it is neither counted as reachable nor as blocked.
+type ConnectivityProbeFailed struct {
+	Expectation ConnectivityExpectation
+	Err         error
+}
+
+// Error is a simple function to return a formatted error message as a string
+//
+//nolint:gocritic // hugeParam: error types are passed by value
+func (err ConnectivityProbeFailed) Error() string {
+	return fmt.Sprintf("Could not check connectivity from %s to %s: %s", err.Expectation.From, err.Expectation.To, err.Err)
+}
+
+// Unwrap returns the error of the probe command.
+//
+//nolint:gocritic // hugeParam: error types are passed by value
+func (err ConnectivityProbeFailed) Unwrap() error {
+	return err.Err
+}
+
+// ConnectivityMatrixMismatch is returned when the observed connectivity between pods does not match a connectivity
+// matrix.
+type ConnectivityMatrixMismatch struct {
+	Mismatches ConnectivityMismatches
+}
+
+// Error is a simple function to return a formatted error message as a string
+func (err ConnectivityMatrixMismatch) Error() string {
+	return fmt.Sprintf("%d connectivity expectations did not match:\n%s", len(err.Mismatches), err.Mismatches)
+}
@@ -52,6 +52,14 @@ func RunKubectlAndGetOutputContextE(t testing.TestingT, ctx context.Context, opt
 		cmdArgs = append(cmdArgs, "--namespace", options.Namespace)
 	}
 
+	if options.ImpersonateUser != "" {
+		cmdArgs = append(cmdArgs, "--as", options.ImpersonateUser)
+	}
+
+	for _, group := range options.ImpersonateGroups {
+		cmdArgs = append(cmdArgs, "--as-group", group)
+	}
+
 	if options.RequestTimeout > 0 {
 		cmdArgs = append(cmdArgs, "--request-timeout", options.RequestTimeout.String())
 	}
@@ -1,6 +1,8 @@
 package k8s
 
 import (
+	"fmt"
+	"maps"
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
@@ -10,14 +12,18 @@ import (
 
 // KubectlOptions represents common options necessary to specify for all Kubectl calls
 type KubectlOptions struct {
-	Env            map[string]string
-	RestConfig     *rest.Config
-	Logger         *logger.Logger
-	ContextName    string
-	ConfigPath     string
-	Namespace      string
-	RequestTimeout time.Duration
-	InClusterAuth  bool
+	Env        map[string]string
+	RestConfig *rest.Config
+	Logger     *logger.Logger
+	// ImpersonateGroups are the groups to impersonate, in addition to ImpersonateUser.
+	ImpersonateGroups []string
+	ContextName       string
+	ConfigPath        string
+	Namespace         string
+	// ImpersonateUser is the user to impersonate for all API calls, like kubectl --as.
+	ImpersonateUser string
+	RequestTimeout  time.Duration
+	InClusterAuth   bool
 }
 
 // NewKubectlOptions will return a pointer to new instance of KubectlOptions with the configured options
@@ -45,6 +51,44 @@ func NewKubectlOptionsWithRestConfig(config *rest.Config, namespace string) *Kub
 	}
 }
 
+// Impersonate returns a copy of the options that makes all API calls as the given user and groups, like kubectl --as and
+// --as-group. The identity used by the options must be allowed to impersonate.
+func (kubectlOptions *KubectlOptions) Impersonate(user string, groups ...string) *KubectlOptions {
+	impersonated := *kubectlOptions
+	impersonated.Env = maps.Clone(kubectlOptions.Env)
+	impersonated.ImpersonateUser = user
+	impersonated.ImpersonateGroups = groups
+
+	return &impersonated
+}
+
+// ImpersonateServiceAccount returns a copy of the options that makes all API calls as the given service account,
+// including the groups Kubernetes assigns to service accounts.
+func (kubectlOptions *KubectlOptions) ImpersonateServiceAccount(namespace string, name string) *KubectlOptions {
+	return kubectlOptions.Impersonate(
+		fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+		"system:serviceaccounts",
+		"system:serviceaccounts:"+namespace,
+		"system:authenticated",
+	)
+}
+
+// applyImpersonation returns a copy of the given rest config that impersonates the user and groups configured on the
+// options, or the config itself if no impersonation is configured.
+func (kubectlOptions *KubectlOptions) applyImpersonation(config *rest.Config) *rest.Config {
+	if kubectlOptions.ImpersonateUser == "" && len(kubectlOptions.ImpersonateGroups) == 0 {
+		return config
+	}
+
+	config = rest.CopyConfig(config)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: kubectlOptions.ImpersonateUser,
+		Groups:   kubectlOptions.ImpersonateGroups,
+	}
+
+	return config
+}
+
 // GetConfigPath will return a sensible default if the config path is not set on the options.
 func (kubectlOptions *KubectlOptions) GetConfigPath(t testing.TestingT) (string, error) {
 	// We predeclare `err` here so that we can update `kubeConfigPath` in the if block below. Otherwise, go complains
@@ -0,0 +1,484 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// LocalClusterProvider is an enum representing the tools that can be used to run an ephemeral local cluster.
+type LocalClusterProvider int
+
+const (
+	// LocalClusterProviderKind runs the cluster with kind (https://kind.sigs.k8s.io).
+	LocalClusterProviderKind LocalClusterProvider = iota
+	// LocalClusterProviderK3d runs the cluster with k3d (https://k3d.io).
+	LocalClusterProviderK3d
+)
+
+func (provider LocalClusterProvider) String() string {
+	switch provider {
+	case LocalClusterProviderKind:
+		return "kind"
+	case LocalClusterProviderK3d:
+		return "k3d"
+	default:
+		// This should not happen
+		return "UNKNOWN_LOCAL_CLUSTER_PROVIDER"
+	}
+}
+
+const (
+	// localRegistryImage is the image used to run the local registry for kind clusters.
+	localRegistryImage = "registry:2"
+
+	// kindNetwork is the docker network kind attaches its nodes to.
+	kindNetwork = "kind"
+
+	// localClusterNodeReadyRetries and localClusterNodeReadySleep bound how long to wait for the nodes of a new cluster
+	// to become ready.
+	localClusterNodeReadyRetries = 60
+	localClusterNodeReadySleep   = 5 * time.Second
+)
+
+// LocalClusterPortMapping maps a port on the host to a port on the cluster. For kind, the container port is a port on
+// the control plane node; for k3d, it is a port on the cluster load balancer.
+type LocalClusterPortMapping struct {
+	// Protocol is TCP or UDP. Defaults to TCP.
+	Protocol      string
+	HostPort      int
+	ContainerPort int
+}
+
+// LocalClusterOptions configures an ephemeral local Kubernetes cluster.
+type LocalClusterOptions struct {
+	// Set a logger that should be used. See the logger package for more info.
+	Logger *logger.Logger
+
+	// Additional environment variables to set when running kind, k3d and docker.
+	Env map[string]string
+
+	// Name of the cluster. Defaults to a unique name prefixed with "terratest-".
+	Name string
+
+	// KubernetesVersion to run, such as "v1.31.0". Ignored if NodeImage is set. Defaults to the provider's default.
+	KubernetesVersion string
+
+	// NodeImage overrides the image used for the cluster nodes (kindest/node for kind, rancher/k3s for k3d).
+	NodeImage string
+
+	// KubeconfigPath is the kubeconfig the cluster is added to. Only the entries of the cluster are removed from it when
+	// the cluster is deleted. Defaults to a file in a new temp directory, which is removed when the cluster is deleted.
+	KubeconfigPath string
+
+	// PortMappings to expose from the cluster on the host.
+	PortMappings []LocalClusterPortMapping
+
+	// Provider selects the tool used to run the cluster.
+	Provider LocalClusterProvider
+
+	// Workers is the number of worker (agent) nodes in addition to the single control plane node.
+	Workers int
+
+	// RegistryPort is the host port of the local registry when EnableRegistry is set. If 0, a free port is selected.
+	RegistryPort int
+
+	// EnableRegistry starts a local image registry reachable from the host at localhost:RegistryPort and from the
+	// cluster under the same address, so that images pushed from the host can be pulled by pods.
+	EnableRegistry bool
+}
+
+// LocalCluster is an ephemeral local Kubernetes cluster created by CreateLocalClusterContextE.
+type LocalCluster struct {
+	options *LocalClusterOptions
+
+	// kubeconfigDir is the temp directory created for the kubeconfig when no KubeconfigPath was given, which is removed
+	// along with the cluster.
+	kubeconfigDir string
+
+	// Name of the cluster.
+	Name string
+
+	// ContextName is the name of the kubeconfig context for the cluster.
+	ContextName string
+
+	// KubeconfigPath is the path to the kubeconfig file that holds the context of the cluster.
+	KubeconfigPath string
+
+	// RegistryAddress is the host:port of the local registry, or empty if no registry was requested.
+	RegistryAddress string
+}
+
+// CreateLocalClusterContextE creates a new local cluster with kind or k3d, writes a dedicated kubeconfig for it and
+// waits until all of its nodes are ready. If t supports registering cleanup functions (like testing.T does), the
+// cluster is deleted automatically when the test finishes. The ctx parameter supports cancellation and timeouts.
+func CreateLocalClusterContextE(t testing.TestingT, ctx context.Context, options *LocalClusterOptions) (*LocalCluster, error) {
+	cluster, err := newLocalClusterE(t, ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	options.Logger.Logf(t, "Creating %s cluster %s", options.Provider, cluster.Name)
+
+	switch options.Provider {
+	case LocalClusterProviderKind:
+		err = cluster.createKindClusterE(t, ctx)
+	case LocalClusterProviderK3d:
+		err = cluster.createK3dClusterE(t, ctx)
+	default:
+		_ = cluster.removeKubeconfig()
+		return nil, UnknownLocalClusterProvider{Provider: options.Provider}
+	}
+
+	// Register the cleanup even when creation failed, since a partially created cluster may still be left behind.
+	if tt, ok := t.(testing.Cleaner); ok {
+		tt.Cleanup(func() {
+			if err := cluster.DeleteContextE(t, context.Background()); err != nil {
+				options.Logger.Logf(t, "Error deleting %s cluster %s: %s", options.Provider, cluster.Name, err)
+			}
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = WaitUntilAllNodesReadyContextE(t, ctx, cluster.KubectlOptions(""), localClusterNodeReadyRetries, localClusterNodeReadySleep)
+	if err != nil {
+		return nil, err
+	}
+
+	return cluster, nil
+}
+
+// CreateLocalClusterContext creates a new local cluster with kind or k3d, writes a dedicated kubeconfig for it and
+// waits until all of its nodes are ready. If t supports registering cleanup functions (like testing.T does), the
+// cluster is deleted automatically when the test finishes. The ctx parameter supports cancellation and timeouts.
+// This will fail the test if there is an error.
+func CreateLocalClusterContext(t testing.TestingT, ctx context.Context, options *LocalClusterOptions) *LocalCluster {
+	t.Helper()
+	cluster, err := CreateLocalClusterContextE(t, ctx, options)
+	require.NoError(t, err)
+
+	return cluster
+}
+
+// newLocalClusterE fills in the defaults of the given options and returns the LocalCluster that will be created.
+func newLocalClusterE(t testing.TestingT, ctx context.Context, options *LocalClusterOptions) (*LocalCluster, error) {
+	name := options.Name
+	if name == "" {
+		name = "terratest-" + strings.ToLower(random.UniqueID())
+	}
+
+	cluster := &LocalCluster{
+		options:        options,
+		Name:           name,
+		ContextName:    fmt.Sprintf("%s-%s", options.Provider, name),
+		KubeconfigPath: options.KubeconfigPath,
+	}
+
+	if options.EnableRegistry {
+		port := options.RegistryPort
+		if port == 0 {
+			var err error
+
+			port, err = GetAvailablePortContextE(t, ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		cluster.RegistryAddress = fmt.Sprintf("localhost:%d", port)
+	}
+
+	if cluster.KubeconfigPath == "" {
+		dir, err := os.MkdirTemp("", name)
+		if err != nil {
+			return nil, err
+		}
+
+		cluster.kubeconfigDir = dir
+		cluster.KubeconfigPath = filepath.Join(dir, "kubeconfig")
+	}
+
+	return cluster, nil
+}
+
+// KubectlOptions returns KubectlOptions configured to use the dedicated kubeconfig of the cluster and the given
+// namespace.
+func (cluster *LocalCluster) KubectlOptions(namespace string) *KubectlOptions {
+	options := NewKubectlOptions(cluster.ContextName, cluster.KubeconfigPath, namespace)
+	options.Logger = cluster.options.Logger
+
+	return options
+}
+
+// LoadImagesContextE loads locally built Docker images into the nodes of the cluster, so that pods can use them without
+// pushing them to a registry. The ctx parameter supports cancellation and timeouts.
+func (cluster *LocalCluster) LoadImagesContextE(t testing.TestingT, ctx context.Context, images ...string) error {
+	cluster.options.Logger.Logf(t, "Loading images %v into %s cluster %s", images, cluster.options.Provider, cluster.Name)
+
+	switch cluster.options.Provider {
+	case LocalClusterProviderKind:
+		return cluster.run(t, ctx, "kind", append(append([]string{"load", "docker-image"}, images...), "--name", cluster.Name)...)
+	case LocalClusterProviderK3d:
+		return cluster.run(t, ctx, "k3d", append(append([]string{"image", "import"}, images...), "--cluster", cluster.Name)...)
+	default:
+		return UnknownLocalClusterProvider{Provider: cluster.options.Provider}
+	}
+}
+
+// LoadImagesContext loads locally built Docker images into the nodes of the cluster, so that pods can use them without
+// pushing them to a registry. The ctx parameter supports cancellation and timeouts. This will fail the test if there is
+// an error.
+func (cluster *LocalCluster) LoadImagesContext(t testing.TestingT, ctx context.Context, images ...string) {
+	t.Helper()
+	require.NoError(t, cluster.LoadImagesContextE(t, ctx, images...))
+}
+
+// DeleteContextE deletes the cluster, its local registry if any, and its credentials from its kubeconfig. The kubeconfig
+// itself is only deleted, along with its temp directory, if no KubeconfigPath was given. The ctx parameter supports
+// cancellation and timeouts.
+func (cluster *LocalCluster) DeleteContextE(t testing.TestingT, ctx context.Context) error {
+	cluster.options.Logger.Logf(t, "Deleting %s cluster %s", cluster.options.Provider, cluster.Name)
+
+	var err error
+
+	switch cluster.options.Provider {
+	case LocalClusterProviderKind:
+		err = cluster.run(t, ctx, "kind", "delete", "cluster", "--name", cluster.Name, "--kubeconfig", cluster.KubeconfigPath)
+		if cluster.RegistryAddress != "" {
+			if registryErr := cluster.run(t, ctx, "docker", "rm", "--force", cluster.kindRegistryName()); err == nil {
+				err = registryErr
+			}
+		}
+	case LocalClusterProviderK3d:
+		err = cluster.run(t, ctx, "k3d", "cluster", "delete", cluster.Name)
+	default:
+		return UnknownLocalClusterProvider{Provider: cluster.options.Provider}
+	}
+
+	if removeErr := cluster.removeKubeconfig(); removeErr != nil && err == nil {
+		err = removeErr
+	}
+
+	return err
+}
+
+// removeKubeconfig removes the credentials of the cluster from its kubeconfig. If the kubeconfig was written to a temp
+// directory created for it, the whole directory is removed. A KubeconfigPath given in the options may be shared with
+// other clusters, such as ~/.kube/config, so only the context of this cluster, and the cluster and user entries that
+// no other context uses, are removed from it.
+func (cluster *LocalCluster) removeKubeconfig() error {
+	if cluster.kubeconfigDir != "" {
+		return os.RemoveAll(cluster.kubeconfigDir)
+	}
+
+	config, err := clientcmd.LoadFromFile(cluster.KubeconfigPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := config.Contexts[cluster.ContextName]; !ok {
+		return nil
+	}
+
+	delete(config.Contexts, cluster.ContextName)
+
+	if config.CurrentContext == cluster.ContextName {
+		config.CurrentContext = ""
+	}
+
+	RemoveOrphanedClusterAndAuthInfoConfig(config)
+
+	return clientcmd.WriteToFile(*config, cluster.KubeconfigPath)
+}
+
+// DeleteContext deletes the cluster, its local registry if any, and its credentials from its kubeconfig, like
+// DeleteContextE. The ctx parameter supports cancellation and timeouts. This will fail the test if there is an error.
+func (cluster *LocalCluster) DeleteContext(t testing.TestingT, ctx context.Context) {
+	t.Helper()
+	require.NoError(t, cluster.DeleteContextE(t, ctx))
+}
+
+// createKindClusterE creates the cluster with kind, including the local registry if requested.
+func (cluster *LocalCluster) createKindClusterE(t testing.TestingT, ctx context.Context) error {
+	if cluster.RegistryAddress != "" {
+		_, port, _ := strings.Cut(cluster.RegistryAddress, ":")
+
+		err := cluster.run(
+			t, ctx, "docker", "run", "--detach", "--restart=always",
+			"--publish", fmt.Sprintf("127.0.0.1:%s:5000", port),
+			"--name", cluster.kindRegistryName(),
+			localRegistryImage,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	configPath, err := StoreConfigToTempFileE(t, cluster.kindConfig())
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(configPath) }()
+
+	args := []string{
+		"create", "cluster",
+		"--name", cluster.Name,
+		"--config", configPath,
+		"--kubeconfig", cluster.KubeconfigPath,
+		"--wait", "5m",
+	}
+
+	if image := cluster.nodeImage(); image != "" {
+		args = append(args, "--image", image)
+	}
+
+	if err := cluster.run(t, ctx, "kind", args...); err != nil {
+		return err
+	}
+
+	if cluster.RegistryAddress != "" {
+		// Make the registry reachable from the nodes under its container name, which the containerd mirror points to.
+		return cluster.run(t, ctx, "docker", "network", "connect", kindNetwork, cluster.kindRegistryName())
+	}
+
+	return nil
+}
+
+// kindConfig renders the kind cluster configuration file for the options.
+func (cluster *LocalCluster) kindConfig() string {
+	var config strings.Builder
+
+	config.WriteString("kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n- role: control-plane\n")
+
+	if len(cluster.options.PortMappings) > 0 {
+		config.WriteString("  extraPortMappings:\n")
+
+		for _, mapping := range cluster.options.PortMappings {
+			fmt.Fprintf(&config, "  - containerPort: %d\n    hostPort: %d\n    protocol: %s\n", mapping.ContainerPort, mapping.HostPort, mapping.protocol())
+		}
+	}
+
+	for range cluster.options.Workers {
+		config.WriteString("- role: worker\n")
+	}
+
+	if cluster.RegistryAddress != "" {
+		config.WriteString("containerdConfigPatches:\n- |-\n")
+		fmt.Fprintf(&config, "  [plugins.\"io.containerd.grpc.v1.cri\".registry.mirrors.%q]\n", cluster.RegistryAddress)
+		fmt.Fprintf(&config, "    endpoint = [\"http://%s:5000\"]\n", cluster.kindRegistryName())
+	}
+
+	return config.String()
+}
+
+// kindRegistryName returns the name of the registry container started for a kind cluster.
+func (cluster *LocalCluster) kindRegistryName() string {
+	return cluster.Name + "-registry"
+}
+
+// createK3dClusterE creates the cluster with k3d, including the local registry if requested, and merges its
+// kubeconfig into KubeconfigPath.
+func (cluster *LocalCluster) createK3dClusterE(t testing.TestingT, ctx context.Context) error {
+	args := []string{
+		"cluster", "create", cluster.Name,
+		"--agents", strconv.Itoa(cluster.options.Workers),
+		"--kubeconfig-update-default=false",
+		"--kubeconfig-switch-context=false",
+		"--wait",
+	}
+
+	if image := cluster.nodeImage(); image != "" {
+		args = append(args, "--image", image)
+	}
+
+	for _, mapping := range cluster.options.PortMappings {
+		args = append(args, "--port", fmt.Sprintf("%d:%d/%s@loadbalancer", mapping.HostPort, mapping.ContainerPort, strings.ToLower(mapping.protocol())))
+	}
+
+	if cluster.RegistryAddress != "" {
+		_, port, _ := strings.Cut(cluster.RegistryAddress, ":")
+		args = append(args, "--registry-create", fmt.Sprintf("%s-registry:127.0.0.1:%s", cluster.Name, port))
+
+		// k3d prefixes the registry container name with "k3d-". Mirror the host address of the registry to that
+		// container so that images can be referenced by the same name from the host and from the cluster.
+		registryConfig := fmt.Sprintf(
+			"mirrors:\n  %q:\n    endpoint:\n      - http://k3d-%s-registry:5000\n",
+			cluster.RegistryAddress,
+			cluster.Name,
+		)
+
+		registryConfigPath, err := StoreConfigToTempFileE(t, registryConfig)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = os.Remove(registryConfigPath) }()
+
+		args = append(args, "--registry-config", registryConfigPath)
+	}
+
+	if err := cluster.run(t, ctx, "k3d", args...); err != nil {
+		return err
+	}
+
+	// Merge into the kubeconfig rather than overwriting it, as a KubeconfigPath given in the options may hold other
+	// clusters
+	return cluster.run(t, ctx, "k3d", "kubeconfig", "merge", cluster.Name, "--output", cluster.KubeconfigPath, "--kubeconfig-switch-context=false")
+}
+
+// nodeImage returns the node image to use for the cluster, or an empty string to use the provider's default.
+func (cluster *LocalCluster) nodeImage() string {
+	if cluster.options.NodeImage != "" || cluster.options.KubernetesVersion == "" {
+		return cluster.options.NodeImage
+	}
+
+	version := cluster.options.KubernetesVersion
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+
+	if cluster.options.Provider == LocalClusterProviderK3d {
+		return fmt.Sprintf("rancher/k3s:%s-k3s1", version)
+	}
+
+	return "kindest/node:" + version
+}
+
+// run runs the given command with the environment and logger of the cluster options.
+func (cluster *LocalCluster) run(t testing.TestingT, ctx context.Context, command string, args ...string) error {
+	return shell.RunCommandContextE(t, ctx, &shell.Command{
+		Command: command,
+		Args:    args,
+		Env:     cluster.options.Env,
+		Logger:  cluster.options.Logger,
+	})
+}
+
+// protocol returns the protocol of the port mapping, defaulting to TCP.
+func (mapping LocalClusterPortMapping) protocol() string {
+	if mapping.Protocol == "" {
+		return "TCP"
+	}
+
+	return strings.ToUpper(mapping.Protocol)
+}
@@ -0,0 +1,83 @@
+//go:build kubeall || kubernetes
+// +build kubeall kubernetes
+
+// NOTE: we have build tags to differentiate kubernetes tests from non-kubernetes tests. This is done because minikube
+// is heavy and can interfere with docker related tests in terratest. Specifically, many of the tests start to fail with
+// `connection refused` errors from `minikube`. To avoid overloading the system, we run the kubernetes tests and helm
+// tests separately from the others. This may not be necessary if you have a sufficiently powerful machine.  We
+// recommend at least 4 cores and 16GB of RAM if you want to run all the tests together.
+
+package k8s_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+)
+
+func TestCreateLocalClusterWithKind(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("kind"); err != nil {
+		t.Skip("kind is not installed")
+	}
+
+	cluster := k8s.CreateLocalClusterContext(t, t.Context(), &k8s.LocalClusterOptions{
+		Provider: k8s.LocalClusterProviderKind,
+		Workers:  1,
+	})
+
+	options := cluster.KubectlOptions("default")
+	nodes := k8s.GetNodesContext(t, t.Context(), options)
+	require.Len(t, nodes, 2)
+	require.FileExists(t, cluster.KubeconfigPath)
+}
+
+func TestDeleteLocalClusterKeepsGivenKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	name := "terratest-" + strings.ToLower(random.UniqueID())
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+
+	// A kubeconfig shared with another cluster, which the local cluster was added to
+	config := api.NewConfig()
+	k8s.UpsertConfigContext(config, "existing", "existing-cluster", "existing-user")
+	k8s.UpsertConfigContext(config, "kind-"+name, "kind-"+name, "kind-"+name)
+	config.Clusters["existing-cluster"] = &api.Cluster{Server: "https://existing.invalid"}
+	config.Clusters["kind-"+name] = &api.Cluster{Server: "https://kind.invalid"}
+	config.AuthInfos["existing-user"] = &api.AuthInfo{Token: "existing-token"}
+	config.AuthInfos["kind-"+name] = &api.AuthInfo{Token: "kind-token"}
+	config.CurrentContext = "existing"
+	require.NoError(t, clientcmd.WriteToFile(*config, kubeconfigPath))
+
+	t.Run("create", func(t *testing.T) {
+		// Creating the cluster fails, either because kind is not installed or because the node image does not exist,
+		// but the cluster is still deleted when the subtest finishes
+		_, err := k8s.CreateLocalClusterContextE(t, t.Context(), &k8s.LocalClusterOptions{
+			Logger:         logger.Discard,
+			Name:           name,
+			NodeImage:      "terratest.invalid/node:missing",
+			KubeconfigPath: kubeconfigPath,
+			Provider:       k8s.LocalClusterProviderKind,
+		})
+		require.Error(t, err)
+	})
+
+	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	require.NoError(t, err)
+	require.Contains(t, config.Contexts, "existing")
+	require.Contains(t, config.Clusters, "existing-cluster")
+	require.NotContains(t, config.Contexts, "kind-"+name)
+	require.NotContains(t, config.Clusters, "kind-"+name)
+	require.NotContains(t, config.AuthInfos, "kind-"+name)
+	require.Equal(t, "existing", config.CurrentContext)
+}
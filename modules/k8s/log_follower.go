@@ -0,0 +1,291 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// maxLogLineSize is the longest single log line a LogFollower will read before giving up on the stream.
+const maxLogLineSize = 1024 * 1024
+
+// LogLine is a single line of container output captured by a LogFollower.
+type LogLine struct {
+	Time      time.Time
+	PodName   string
+	Container string
+	Text      string
+}
+
+// String formats the log line with the pod and container prefix used when multiplexing into the logger.
+func (line LogLine) String() string {
+	return fmt.Sprintf("[%s/%s] %s", line.PodName, line.Container, line.Text)
+}
+
+// LogFollower streams the logs of every container in every pod matching a label selector, including pods that are
+// created after the follower was started. Each line is written to the logger configured on the KubectlOptions,
+// prefixed with the pod and container name, and retained so that tests can wait for a particular line to appear.
+type LogFollower struct {
+	t              testing.TestingT
+	kubectlOptions *KubectlOptions
+	clientset      *kubernetes.Clientset
+	cancel         context.CancelFunc
+	notify         chan struct{}
+	followed       map[string]bool
+	labelSelector  string
+	lines          []LogLine
+	wg             sync.WaitGroup
+	mutex          sync.Mutex
+}
+
+// NewLogFollowerContextE starts streaming the logs of all pods in the namespace of the provided KubectlOptions that
+// match the given label selector. The follower keeps running until Close is called or ctx is cancelled.
+func NewLogFollowerContextE(t testing.TestingT, ctx context.Context, options *KubectlOptions, labelSelector string) (*LogFollower, error) {
+	clientset, err := GetKubernetesClientFromOptionsContextE(t, ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	follower := &LogFollower{
+		t:              t,
+		kubectlOptions: options,
+		clientset:      clientset,
+		labelSelector:  labelSelector,
+		cancel:         cancel,
+		notify:         make(chan struct{}),
+		followed:       map[string]bool{},
+	}
+
+	// Do an initial listing so that pods which already exist are followed before we return, and so that the watch can
+	// pick up from a known resource version.
+	pods, err := clientset.CoreV1().Pods(options.Namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	for i := range pods.Items {
+		follower.followPod(ctx, &pods.Items[i])
+	}
+
+	options.Logger.Logf(t, "Following logs of pods matching selector '%s' in namespace %s", labelSelector, options.Namespace)
+
+	follower.wg.Add(1)
+
+	go follower.watchPods(ctx, pods.ResourceVersion)
+
+	return follower, nil
+}
+
+// NewLogFollowerContext starts streaming the logs of all pods in the namespace of the provided KubectlOptions that
+// match the given label selector. The follower keeps running until Close is called or ctx is cancelled.
+// This will fail the test if there is an error.
+func NewLogFollowerContext(t testing.TestingT, ctx context.Context, options *KubectlOptions, labelSelector string) *LogFollower {
+	t.Helper()
+	follower, err := NewLogFollowerContextE(t, ctx, options, labelSelector)
+	require.NoError(t, err)
+
+	return follower
+}
+
+// Close stops following logs and waits for all the background streams to finish.
+func (follower *LogFollower) Close() {
+	follower.cancel()
+	follower.wg.Wait()
+}
+
+// Lines returns a copy of all the log lines captured so far.
+func (follower *LogFollower) Lines() []LogLine {
+	follower.mutex.Lock()
+	defer follower.mutex.Unlock()
+
+	lines := make([]LogLine, len(follower.lines))
+	copy(lines, follower.lines)
+
+	return lines
+}
+
+// WaitForLogLineE waits until a captured log line matches the given regular expression, returning the first matching
+// line. Lines captured before this call are also considered. Returns a LogLineNotFound error if no line matches within
+// the given timeout.
+func (follower *LogFollower) WaitForLogLineE(regex string, timeout time.Duration) (LogLine, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return LogLine{}, err
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	checked := 0
+
+	for {
+		follower.mutex.Lock()
+		for ; checked < len(follower.lines); checked++ {
+			if re.MatchString(follower.lines[checked].Text) {
+				line := follower.lines[checked]
+				follower.mutex.Unlock()
+
+				return line, nil
+			}
+		}
+
+		notify := follower.notify
+		follower.mutex.Unlock()
+
+		select {
+		case <-notify:
+			// A new line arrived, loop around and check it
+		case <-deadline.C:
+			return LogLine{}, LogLineNotFound{Regex: regex, Timeout: timeout}
+		}
+	}
+}
+
+// WaitForLogLine waits until a captured log line matches the given regular expression, returning the first matching
+// line. Lines captured before this call are also considered. This will fail the test if no line matches within the
+// given timeout.
+func (follower *LogFollower) WaitForLogLine(t testing.TestingT, regex string, timeout time.Duration) LogLine {
+	t.Helper()
+	line, err := follower.WaitForLogLineE(regex, timeout)
+	require.NoError(t, err)
+
+	return line
+}
+
+// watchPods watches for pods matching the label selector and starts following the containers of any new or updated
+// pod. The watch is re-established whenever the API server closes it, until the context is cancelled.
+func (follower *LogFollower) watchPods(ctx context.Context, resourceVersion string) {
+	defer follower.wg.Done()
+
+	for ctx.Err() == nil {
+		watcher, err := follower.clientset.CoreV1().Pods(follower.kubectlOptions.Namespace).Watch(ctx, metav1.ListOptions{
+			LabelSelector:   follower.labelSelector,
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			follower.kubectlOptions.Logger.Logf(follower.t, "Error watching pods matching selector '%s': %s", follower.labelSelector, err)
+			// The resource version may have expired, so restart the watch from the current state.
+			resourceVersion = ""
+
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+
+			continue
+		}
+
+		for event := range watcher.ResultChan() {
+			if event.Type == watch.Error {
+				// Most likely the resource version is too old, so restart the watch from the current state.
+				resourceVersion = ""
+				break
+			}
+
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+
+			resourceVersion = pod.ResourceVersion
+
+			if event.Type == watch.Added || event.Type == watch.Modified {
+				follower.followPod(ctx, pod)
+			}
+		}
+
+		watcher.Stop()
+	}
+}
+
+// followPod starts a log stream for each container of the pod that has started and is not yet being followed. A
+// container that restarts is followed again, since it is a new instance with new output.
+func (follower *LogFollower) followPod(ctx context.Context, pod *corev1.Pod) {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+
+	for i := range statuses {
+		status := &statuses[i]
+		if status.State.Running == nil && status.State.Terminated == nil {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s/%d", pod.Name, status.Name, status.RestartCount)
+
+		follower.mutex.Lock()
+		alreadyFollowed := follower.followed[key]
+		follower.followed[key] = true
+		follower.mutex.Unlock()
+
+		if alreadyFollowed {
+			continue
+		}
+
+		follower.wg.Add(1)
+
+		go follower.streamContainer(ctx, pod.Name, status.Name)
+	}
+}
+
+// streamContainer follows the logs of a single container until the stream ends or the context is cancelled.
+func (follower *LogFollower) streamContainer(ctx context.Context, podName string, containerName string) {
+	defer follower.wg.Done()
+
+	request := follower.clientset.CoreV1().Pods(follower.kubectlOptions.Namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: containerName,
+		Follow:    true,
+	})
+
+	stream, err := request.Stream(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			follower.kubectlOptions.Logger.Logf(follower.t, "Error streaming logs of container %s in pod %s: %s", containerName, podName, err)
+		}
+
+		return
+	}
+
+	defer func() { _ = stream.Close() }()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLogLineSize)
+
+	for scanner.Scan() {
+		follower.addLine(LogLine{
+			Time:      time.Now(),
+			PodName:   podName,
+			Container: containerName,
+			Text:      scanner.Text(),
+		})
+	}
+}
+
+// addLine records a new log line, writes it to the logger and wakes up anyone waiting for new lines.
+func (follower *LogFollower) addLine(line LogLine) {
+	follower.kubectlOptions.Logger.Logf(follower.t, "%s", line.String())
+
+	follower.mutex.Lock()
+	defer follower.mutex.Unlock()
+
+	follower.lines = append(follower.lines, line)
+	close(follower.notify)
+	follower.notify = make(chan struct{})
+}
@@ -0,0 +1,84 @@
+//go:build kubeall || kubernetes
+// +build kubeall kubernetes
+
+// NOTE: we have build tags to differentiate kubernetes tests from non-kubernetes tests. This is done because minikube
+// is heavy and can interfere with docker related tests in terratest. Specifically, many of the tests start to fail with
+// `connection refused` errors from `minikube`. To avoid overloading the system, we run the kubernetes tests and helm
+// tests separately from the others. This may not be necessary if you have a sufficiently powerful machine.  We
+// recommend at least 4 cores and 16GB of RAM if you want to run all the tests together.
+
+package k8s_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+)
+
+func TestLogFollowerWaitsForLogLineFromPodCreatedLater(t *testing.T) {
+	t.Parallel()
+
+	uniqueID := strings.ToLower(random.UniqueID())
+	options := k8s.NewKubectlOptions("", "", uniqueID)
+
+	namespaceData := fmt.Sprintf(exampleLogFollowerNamespaceYAMLTemplate, uniqueID)
+	defer k8s.KubectlDeleteFromString(t, options, namespaceData)
+
+	k8s.KubectlApplyFromString(t, options, namespaceData)
+
+	follower := k8s.NewLogFollowerContext(t, t.Context(), options, "app=log-follower")
+	defer follower.Close()
+
+	// Create the pod after the follower has started to make sure new pods are picked up.
+	k8s.KubectlApplyFromString(t, options, fmt.Sprintf(exampleLogFollowerPodYAMLTemplate, uniqueID))
+
+	line := follower.WaitForLogLine(t, "^ready$", 2*time.Minute)
+	require.Equal(t, "log-follower-pod", line.PodName)
+	require.Equal(t, "printer", line.Container)
+}
+
+func TestLogFollowerWaitForLogLineETimesOut(t *testing.T) {
+	t.Parallel()
+
+	uniqueID := strings.ToLower(random.UniqueID())
+	options := k8s.NewKubectlOptions("", "", uniqueID)
+
+	namespaceData := fmt.Sprintf(exampleLogFollowerNamespaceYAMLTemplate, uniqueID)
+	defer k8s.KubectlDeleteFromString(t, options, namespaceData)
+
+	k8s.KubectlApplyFromString(t, options, namespaceData)
+
+	follower := k8s.NewLogFollowerContext(t, t.Context(), options, "app=log-follower")
+	defer follower.Close()
+
+	_, err := follower.WaitForLogLineE("ready", 5*time.Second)
+	require.ErrorAs(t, err, &k8s.LogLineNotFound{})
+}
+
+const exampleLogFollowerNamespaceYAMLTemplate = `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+`
+
+const exampleLogFollowerPodYAMLTemplate = `---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: log-follower-pod
+  namespace: %s
+  labels:
+    app: log-follower
+spec:
+  containers:
+  - name: printer
+    image: busybox:1.36
+    command: ["sh", "-c", "echo starting; sleep 5; echo ready; sleep 3600"]
+`
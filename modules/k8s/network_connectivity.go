@@ -0,0 +1,484 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ConnectivityProtocol is an enum representing the kinds of reachability checks that can be made between pods.
+type ConnectivityProtocol int
+
+const (
+	// ConnectivityTCP checks that a TCP connection can be opened to the target port.
+	ConnectivityTCP ConnectivityProtocol = iota
+	// ConnectivityHTTP checks that an HTTP response, of any status, can be received from the target port and path.
+	ConnectivityHTTP
+	// ConnectivityDNS checks that the target host name can be resolved.
+	ConnectivityDNS
+)
+
+func (protocol ConnectivityProtocol) String() string {
+	switch protocol {
+	case ConnectivityTCP:
+		return "tcp"
+	case ConnectivityHTTP:
+		return "http"
+	case ConnectivityDNS:
+		return "dns"
+	default:
+		// This should not happen
+		return "UNKNOWN_CONNECTIVITY_PROTOCOL"
+	}
+}
+
+const (
+	// defaultProbeImage is the image used for probe pods. It must provide nc, wget and nslookup.
+	defaultProbeImage = "busybox:1.36"
+
+	// defaultProbeTimeout is how long a single reachability check may take before the path is considered blocked.
+	defaultProbeTimeout = 3 * time.Second
+
+	// probePodReadyRetries and probePodReadySleep bound how long to wait for a probe pod to start.
+	probePodReadyRetries = 60
+	probePodReadySleep   = 2 * time.Second
+
+	// probeCommandNotExecutable and probeCommandNotFound are the exit codes of a probe command that could not be run,
+	// as reported by the container runtime or by timeout.
+	probeCommandNotExecutable = 126
+	probeCommandNotFound      = 127
+)
+
+// ConnectivitySource is where a reachability check is made from. Either set PodName to exec into an existing pod, or
+// set Labels to launch a short-lived probe pod with those labels, so that it is selected by the policies under test.
+type ConnectivitySource struct {
+	// Labels to put on the probe pod. Ignored if PodName is set.
+	Labels map[string]string
+
+	// Namespace of the existing pod or of the probe pod.
+	Namespace string
+
+	// PodName is the name of an existing pod to run the check from.
+	PodName string
+
+	// Container in the existing pod to run the check from. Leave empty if the pod has a single container.
+	Container string
+}
+
+// String formats the source as a human readable description.
+func (source ConnectivitySource) String() string {
+	if source.PodName != "" {
+		return fmt.Sprintf("%s/pod/%s", source.Namespace, source.PodName)
+	}
+
+	return fmt.Sprintf("%s/{%s}", source.Namespace, makeSortedLabels(source.Labels))
+}
+
+// ConnectivityTarget is what a reachability check tries to reach. Set exactly one of Host, PodName, ServiceName or
+// Labels to select the target.
+type ConnectivityTarget struct {
+	// Labels selects the first available pod in Namespace with these labels.
+	Labels map[string]string
+
+	// Host is a host name or IP address to reach directly.
+	Host string
+
+	// Namespace of the target pod or service.
+	Namespace string
+
+	// PodName is the name of the target pod, which is reached by its IP address.
+	PodName string
+
+	// ServiceName is the name of the target service, which is reached by its cluster DNS name.
+	ServiceName string
+
+	// Path is the HTTP path to request. Defaults to "/".
+	Path string
+
+	// Port to reach. Ignored for DNS checks.
+	Port int
+
+	// Protocol of the check.
+	Protocol ConnectivityProtocol
+}
+
+// String formats the target as a human readable description.
+func (target ConnectivityTarget) String() string {
+	var host string
+
+	switch {
+	case target.Host != "":
+		host = target.Host
+	case target.PodName != "":
+		host = fmt.Sprintf("%s/pod/%s", target.Namespace, target.PodName)
+	case target.ServiceName != "":
+		host = fmt.Sprintf("%s/svc/%s", target.Namespace, target.ServiceName)
+	default:
+		host = fmt.Sprintf("%s/{%s}", target.Namespace, makeSortedLabels(target.Labels))
+	}
+
+	if target.Protocol == ConnectivityDNS {
+		return fmt.Sprintf("%s://%s", target.Protocol, host)
+	}
+
+	return fmt.Sprintf("%s://%s:%d%s", target.Protocol, host, target.Port, target.Path)
+}
+
+// ConnectivityExpectation is a single row of a connectivity matrix: whether From is expected to be able to reach To.
+type ConnectivityExpectation struct {
+	From    ConnectivitySource
+	To      ConnectivityTarget
+	Allowed bool
+}
+
+// ConnectivityOptions configures how connectivity checks are run.
+type ConnectivityOptions struct {
+	// ProbeImage is the image used for probe pods. It must provide nc, wget and nslookup. Defaults to busybox.
+	ProbeImage string
+
+	// Timeout for each individual check. Defaults to 3 seconds.
+	Timeout time.Duration
+}
+
+// ConnectivityMismatch is a ConnectivityExpectation that did not match the observed behavior.
+type ConnectivityMismatch struct {
+	// Output of the probe command, useful to tell a blocked path from a misconfigured check.
+	Output      string
+	Expectation ConnectivityExpectation
+}
+
+// ConnectivityMismatches is the list of mismatches found when verifying a connectivity matrix.
+type ConnectivityMismatches []ConnectivityMismatch
+
+// String renders the mismatches as a table.
+func (mismatches ConnectivityMismatches) String() string {
+	var out strings.Builder
+
+	writer := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+
+	//nolint:errcheck // writing to a strings.Builder cannot fail
+	fmt.Fprintln(writer, "FROM\tTO\tEXPECTED\tACTUAL")
+
+	for _, mismatch := range mismatches {
+		expectation := mismatch.Expectation
+
+		//nolint:errcheck // writing to a strings.Builder cannot fail
+		fmt.Fprintf(
+			writer,
+			"%s\t%s\t%s\t%s\n",
+			expectation.From,
+			expectation.To,
+			allowedString(expectation.Allowed),
+			allowedString(!expectation.Allowed),
+		)
+	}
+
+	_ = writer.Flush()
+
+	return out.String()
+}
+
+// VerifyConnectivityContextE runs every check in the connectivity matrix and returns the checks whose outcome did not
+// match the expectation. Probe pods are launched as needed, shared between checks from the same namespace and labels,
+// and deleted before returning. This will return an error if a probe pod or target cannot be set up, or a
+// ConnectivityProbeFailed error if a check cannot be run, but not if a check does not match. The ctx parameter
+// supports cancellation and timeouts.
+//
+//nolint:gocritic // hugeParam: options struct is passed by value to match the other connectivity functions
+func VerifyConnectivityContextE(
+	t testing.TestingT,
+	ctx context.Context,
+	options *KubectlOptions,
+	connectivityOptions ConnectivityOptions,
+	matrix []ConnectivityExpectation,
+) (ConnectivityMismatches, error) {
+	if connectivityOptions.ProbeImage == "" {
+		connectivityOptions.ProbeImage = defaultProbeImage
+	}
+
+	if connectivityOptions.Timeout == 0 {
+		connectivityOptions.Timeout = defaultProbeTimeout
+	}
+
+	probes := map[string]string{}
+
+	defer func() {
+		for key, podName := range probes {
+			namespace, _, _ := strings.Cut(key, "/")
+			if err := RunKubectlContextE(t, context.Background(), namespaceOptions(options, namespace), "delete", "pod", podName, "--wait=false"); err != nil { //nolint:contextcheck // cleanup must run even if ctx is cancelled
+				options.Logger.Logf(t, "Error deleting probe pod %s: %s", podName, err)
+			}
+		}
+	}()
+
+	var mismatches ConnectivityMismatches
+
+	for _, expectation := range matrix {
+		source := expectation.From
+
+		if source.PodName == "" {
+			key := source.String()
+
+			podName, ok := probes[key]
+			if !ok {
+				var err error
+
+				podName, err = launchProbePodE(t, ctx, namespaceOptions(options, source.Namespace), connectivityOptions.ProbeImage, source.Labels)
+
+				// Record the pod before checking the error, so that a pod that never became available is deleted too
+				if podName != "" {
+					probes[key] = podName
+				}
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			source.PodName = podName
+		}
+
+		command, err := connectivityCommandE(t, ctx, options, &connectivityOptions, &expectation.To)
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := ExecPodContextE(t, ctx, namespaceOptions(options, source.Namespace), source.PodName, source.Container, command...)
+
+		reachable, err := probeReachable(ctx, expectation.To.Protocol, output, err)
+		if err != nil {
+			return nil, ConnectivityProbeFailed{Expectation: expectation, Err: err}
+		}
+
+		options.Logger.Logf(t, "Connectivity from %s to %s: %s (expected %s)", expectation.From, expectation.To, allowedString(reachable), allowedString(expectation.Allowed))
+
+		if reachable != expectation.Allowed {
+			mismatches = append(mismatches, ConnectivityMismatch{Expectation: expectation, Output: output})
+		}
+	}
+
+	return mismatches, nil
+}
+
+// VerifyConnectivityContext runs every check in the connectivity matrix and returns the checks whose outcome did not
+// match the expectation. The ctx parameter supports cancellation and timeouts. This will fail the test if a probe pod
+// or target cannot be set up.
+//
+//nolint:gocritic // hugeParam: options struct is passed by value to match the other connectivity functions
+func VerifyConnectivityContext(
+	t testing.TestingT,
+	ctx context.Context,
+	options *KubectlOptions,
+	connectivityOptions ConnectivityOptions,
+	matrix []ConnectivityExpectation,
+) ConnectivityMismatches {
+	t.Helper()
+	mismatches, err := VerifyConnectivityContextE(t, ctx, options, connectivityOptions, matrix)
+	require.NoError(t, err)
+
+	return mismatches
+}
+
+// RequireConnectivityContextE runs every check in the connectivity matrix and returns a ConnectivityMatrixMismatch
+// error listing every path that behaved unexpectedly. The ctx parameter supports cancellation and timeouts.
+//
+//nolint:gocritic // hugeParam: options struct is passed by value to match the other connectivity functions
+func RequireConnectivityContextE(
+	t testing.TestingT,
+	ctx context.Context,
+	options *KubectlOptions,
+	connectivityOptions ConnectivityOptions,
+	matrix []ConnectivityExpectation,
+) error {
+	mismatches, err := VerifyConnectivityContextE(t, ctx, options, connectivityOptions, matrix)
+	if err != nil {
+		return err
+	}
+
+	if len(mismatches) > 0 {
+		return ConnectivityMatrixMismatch{Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+// RequireConnectivityContext runs every check in the connectivity matrix. The ctx parameter supports cancellation and
+// timeouts. This will fail the test with a table of all the paths that behaved unexpectedly.
+//
+//nolint:gocritic // hugeParam: options struct is passed by value to match the other connectivity functions
+func RequireConnectivityContext(
+	t testing.TestingT,
+	ctx context.Context,
+	options *KubectlOptions,
+	connectivityOptions ConnectivityOptions,
+	matrix []ConnectivityExpectation,
+) {
+	t.Helper()
+	require.NoError(t, RequireConnectivityContextE(t, ctx, options, connectivityOptions, matrix))
+}
+
+// launchProbePodE starts a pod that sleeps so that checks can be exec'd in it, and waits until it is available. The name
+// of the pod is returned whenever it was created, even if it did not become available, so that the caller can delete it.
+func launchProbePodE(t testing.TestingT, ctx context.Context, options *KubectlOptions, image string, labels map[string]string) (string, error) {
+	clientset, err := GetKubernetesClientFromOptionsContextE(t, ctx, options)
+	if err != nil {
+		return "", err
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "terratest-probe-" + strings.ToLower(random.UniqueID()),
+			Labels: maps.Clone(labels),
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:    "probe",
+				Image:   image,
+				Command: []string{"sleep", "3600"},
+			}},
+			RestartPolicy: corev1.RestartPolicyNever,
+		},
+	}
+
+	options.Logger.Logf(t, "Launching probe pod %s in namespace %s with labels {%s}", pod.Name, options.Namespace, makeSortedLabels(labels))
+
+	if _, err := clientset.CoreV1().Pods(options.Namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+
+	if err := WaitUntilPodAvailableContextE(t, ctx, options, pod.Name, probePodReadyRetries, probePodReadySleep); err != nil {
+		return pod.Name, err
+	}
+
+	return pod.Name, nil
+}
+
+// probeReachable interprets the result of exec'ing a probe command. kubectl exits with the exit code of the command it
+// ran and reports it as "command terminated with exit code N", so only such a failure of the probe command itself means
+// that the target is not reachable. Any other error, such as a missing pod, a forbidden exec or a cancelled context,
+// and the exit codes of a command that could not be found or run, are returned as errors, since they say nothing about
+// the network path.
+func probeReachable(ctx context.Context, protocol ConnectivityProtocol, output string, execErr error) (bool, error) {
+	if execErr == nil {
+		return true, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	exitCode, err := shell.GetExitCodeForRunCommandError(execErr)
+	if err != nil || exitCode == 0 || !strings.Contains(output, fmt.Sprintf("command terminated with exit code %d", exitCode)) {
+		return false, execErr
+	}
+
+	if exitCode == probeCommandNotExecutable || exitCode == probeCommandNotFound {
+		return false, execErr
+	}
+
+	// wget fails on non-2xx responses, but a response means the target was reached
+	return protocol == ConnectivityHTTP && strings.Contains(output, "HTTP/"), nil
+}
+
+// connectivityCommandE builds the command to exec in the source pod to check whether the target is reachable.
+func connectivityCommandE(
+	t testing.TestingT,
+	ctx context.Context,
+	options *KubectlOptions,
+	connectivityOptions *ConnectivityOptions,
+	target *ConnectivityTarget,
+) ([]string, error) {
+	host, err := resolveConnectivityTargetE(t, ctx, options, target)
+	if err != nil {
+		return nil, err
+	}
+
+	timeoutSeconds := strconv.Itoa(max(1, int(connectivityOptions.Timeout.Seconds())))
+	port := strconv.Itoa(target.Port)
+
+	switch target.Protocol {
+	case ConnectivityTCP:
+		return []string{"nc", "-z", "-w", timeoutSeconds, host, port}, nil
+	case ConnectivityHTTP:
+		path := target.Path
+		if path == "" {
+			path = "/"
+		}
+
+		url := fmt.Sprintf("http://%s:%s%s", host, port, path)
+
+		// Print the response headers, on stderr, so that non-2xx responses, which make wget fail, are still counted as
+		// reachable. The URL is passed as an argument rather than through a shell, so that it is never interpreted.
+		return []string{"wget", "-S", "-q", "-T", timeoutSeconds, "-O", "/dev/null", url}, nil
+	case ConnectivityDNS:
+		return []string{"timeout", timeoutSeconds, "nslookup", host}, nil
+	default:
+		return nil, UnknownConnectivityProtocol{Protocol: target.Protocol}
+	}
+}
+
+// resolveConnectivityTargetE returns the host name or IP address to use for the target.
+func resolveConnectivityTargetE(t testing.TestingT, ctx context.Context, options *KubectlOptions, target *ConnectivityTarget) (string, error) {
+	targetOptions := namespaceOptions(options, target.Namespace)
+
+	switch {
+	case target.Host != "":
+		return target.Host, nil
+	case target.ServiceName != "":
+		return fmt.Sprintf("%s.%s.svc.cluster.local", target.ServiceName, targetOptions.Namespace), nil
+	case target.PodName != "":
+		pod, err := GetPodContextE(t, ctx, targetOptions, target.PodName)
+		if err != nil {
+			return "", err
+		}
+
+		return pod.Status.PodIP, nil
+	default:
+		pods, err := ListPodsContextE(t, ctx, targetOptions, metav1.ListOptions{LabelSelector: makeSortedLabels(target.Labels)})
+		if err != nil {
+			return "", err
+		}
+
+		for i := range pods {
+			if IsPodAvailable(&pods[i]) {
+				return pods[i].Status.PodIP, nil
+			}
+		}
+
+		return "", ConnectivityTargetNotFound{Target: *target}
+	}
+}
+
+// namespaceOptions returns a copy of the options that targets the given namespace, or the options themselves if the
+// namespace is empty.
+func namespaceOptions(options *KubectlOptions, namespace string) *KubectlOptions {
+	if namespace == "" || namespace == options.Namespace {
+		return options
+	}
+
+	copied := *options
+	copied.Namespace = namespace
+
+	return &copied
+}
+
+// makeSortedLabels is like makeLabels, but sorts the labels so the result is stable.
+func makeSortedLabels(labels map[string]string) string {
+	out := strings.Split(makeLabels(labels), ",")
+	sort.Strings(out)
+
+	return strings.Join(out, ",")
+}
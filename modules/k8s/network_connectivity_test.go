@@ -0,0 +1,103 @@
+//go:build kubeall || kubernetes
+// +build kubeall kubernetes
+
+// NOTE: we have build tags to differentiate kubernetes tests from non-kubernetes tests. This is done because minikube
+// is heavy and can interfere with docker related tests in terratest. Specifically, many of the tests start to fail with
+// `connection refused` errors from `minikube`. To avoid overloading the system, we run the kubernetes tests and helm
+// tests separately from the others. This may not be necessary if you have a sufficiently powerful machine.  We
+// recommend at least 4 cores and 16GB of RAM if you want to run all the tests together.
+
+package k8s_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+)
+
+// NOTE: minikube does not enforce NetworkPolicies without a CNI plugin that supports them, so this test only relies on
+// paths that are open or closed regardless of policy enforcement.
+func TestVerifyConnectivityBetweenProbeAndPod(t *testing.T) {
+	t.Parallel()
+
+	uniqueID := strings.ToLower(random.UniqueID())
+	options := k8s.NewKubectlOptions("", "", uniqueID)
+
+	configData := fmt.Sprintf(ExamplePodWithServiceYAMLTemplate, uniqueID, uniqueID, uniqueID, uniqueID)
+	defer k8s.KubectlDeleteFromString(t, options, configData)
+
+	k8s.KubectlApplyFromString(t, options, configData)
+	k8s.WaitUntilPodAvailable(t, options, "nginx-pod", 60, 1*time.Second)
+
+	client := k8s.ConnectivitySource{Namespace: uniqueID, Labels: map[string]string{"app": "client"}}
+
+	k8s.RequireConnectivityContext(t, t.Context(), options, k8s.ConnectivityOptions{}, []k8s.ConnectivityExpectation{
+		{
+			From:    client,
+			To:      k8s.ConnectivityTarget{Namespace: uniqueID, ServiceName: "nginx-service-number", Port: 8080, Protocol: k8s.ConnectivityHTTP},
+			Allowed: true,
+		},
+		{
+			From:    client,
+			To:      k8s.ConnectivityTarget{Namespace: uniqueID, PodName: "nginx-pod", Port: 80, Protocol: k8s.ConnectivityTCP},
+			Allowed: true,
+		},
+		{
+			From:    client,
+			To:      k8s.ConnectivityTarget{Namespace: uniqueID, ServiceName: "nginx-service-name", Protocol: k8s.ConnectivityDNS},
+			Allowed: true,
+		},
+		{
+			From:    client,
+			To:      k8s.ConnectivityTarget{Namespace: uniqueID, Labels: map[string]string{"app": "nginx"}, Port: 81, Protocol: k8s.ConnectivityTCP},
+			Allowed: false,
+		},
+		{
+			// The path is passed to wget as is, so the quote does not break out of the command, and the 404 response
+			// still counts as reachable
+			From:    client,
+			To:      k8s.ConnectivityTarget{Namespace: uniqueID, PodName: "nginx-pod", Port: 80, Path: "/it's", Protocol: k8s.ConnectivityHTTP},
+			Allowed: true,
+		},
+	})
+
+	mismatches := k8s.VerifyConnectivityContext(t, t.Context(), options, k8s.ConnectivityOptions{}, []k8s.ConnectivityExpectation{
+		{
+			From:    client,
+			To:      k8s.ConnectivityTarget{Namespace: uniqueID, PodName: "nginx-pod", Port: 81, Protocol: k8s.ConnectivityHTTP},
+			Allowed: true,
+		},
+	})
+	require.Len(t, mismatches, 1)
+}
+
+func TestVerifyConnectivityFailsWhenProbeCannotRun(t *testing.T) {
+	t.Parallel()
+
+	uniqueID := strings.ToLower(random.UniqueID())
+	options := k8s.NewKubectlOptions("", "", uniqueID)
+
+	configData := fmt.Sprintf(ExamplePodWithServiceYAMLTemplate, uniqueID, uniqueID, uniqueID, uniqueID)
+	defer k8s.KubectlDeleteFromString(t, options, configData)
+
+	k8s.KubectlApplyFromString(t, options, configData)
+	k8s.WaitUntilPodAvailable(t, options, "nginx-pod", 60, 1*time.Second)
+
+	// Exec into a pod that does not exist fails, which must not be mistaken for a blocked path
+	_, err := k8s.VerifyConnectivityContextE(t, t.Context(), options, k8s.ConnectivityOptions{}, []k8s.ConnectivityExpectation{
+		{
+			From:    k8s.ConnectivitySource{Namespace: uniqueID, PodName: "missing-pod"},
+			To:      k8s.ConnectivityTarget{Namespace: uniqueID, PodName: "nginx-pod", Port: 81, Protocol: k8s.ConnectivityTCP},
+			Allowed: false,
+		},
+	})
+
+	var probeErr k8s.ConnectivityProbeFailed
+	require.ErrorAs(t, err, &probeErr)
+}
@@ -0,0 +1,186 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/stretchr/testify/require"
+	authv1 "k8s.io/api/authorization/v1"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// RBACExpectation is a single row of an RBAC permission matrix: whether the identity configured on the KubectlOptions
+// is expected to be allowed to perform Verb on Resource.
+type RBACExpectation struct {
+	// Verb is the API verb, such as get, list, create or delete.
+	Verb string
+
+	// Group is the API group of the resource, such as "apps". Leave empty for the core group.
+	Group string
+
+	// Resource is the plural resource name, such as pods or deployments.
+	Resource string
+
+	// Subresource is an optional subresource, such as log or exec.
+	Subresource string
+
+	// Name is an optional name of a specific resource instance.
+	Name string
+
+	// Namespace to check the permission in. Leave empty for cluster scoped resources or to check all namespaces.
+	Namespace string
+
+	// Allowed is whether the action is expected to be allowed.
+	Allowed bool
+}
+
+// String formats the expectation as a human readable action.
+func (expectation RBACExpectation) String() string {
+	resource := expectation.Resource
+	if expectation.Group != "" {
+		resource = resource + "." + expectation.Group
+	}
+
+	if expectation.Subresource != "" {
+		resource = resource + "/" + expectation.Subresource
+	}
+
+	if expectation.Name != "" {
+		resource = resource + "/" + expectation.Name
+	}
+
+	namespace := expectation.Namespace
+	if namespace == "" {
+		namespace = "*"
+	}
+
+	return fmt.Sprintf("%s %s in namespace %s", expectation.Verb, resource, namespace)
+}
+
+// RBACMismatch is an RBACExpectation that did not match the permissions granted by the cluster.
+type RBACMismatch struct {
+	// Reason is the explanation given by the authorizer, if any.
+	Reason      string
+	Expectation RBACExpectation
+}
+
+// RBACMismatches is the list of mismatches found when evaluating an RBAC permission matrix.
+type RBACMismatches []RBACMismatch
+
+// String renders the mismatches as a table.
+func (mismatches RBACMismatches) String() string {
+	var out strings.Builder
+
+	writer := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+
+	//nolint:errcheck // writing to a strings.Builder cannot fail
+	fmt.Fprintln(writer, "VERB\tGROUP\tRESOURCE\tNAME\tNAMESPACE\tEXPECTED\tACTUAL\tREASON")
+
+	for _, mismatch := range mismatches {
+		expectation := mismatch.Expectation
+
+		resource := expectation.Resource
+		if expectation.Subresource != "" {
+			resource = resource + "/" + expectation.Subresource
+		}
+
+		//nolint:errcheck // writing to a strings.Builder cannot fail
+		fmt.Fprintf(
+			writer,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			expectation.Verb,
+			expectation.Group,
+			resource,
+			expectation.Name,
+			expectation.Namespace,
+			allowedString(expectation.Allowed),
+			allowedString(!expectation.Allowed),
+			mismatch.Reason,
+		)
+	}
+
+	_ = writer.Flush()
+
+	return out.String()
+}
+
+// allowedString formats an allowed flag for the mismatch table.
+func allowedString(allowed bool) string {
+	if allowed {
+		return "allow"
+	}
+
+	return "deny"
+}
+
+// EvaluateRBACMatrixContextE checks every expectation in the matrix against the cluster, as the identity configured on
+// the provided KubectlOptions (see KubectlOptions.Impersonate and KubectlOptions.ImpersonateServiceAccount), and returns
+// the expectations that did not match. This will return an error if there are problems accessing the kubernetes API,
+// but not if an expectation does not match. The ctx parameter supports cancellation and timeouts.
+func EvaluateRBACMatrixContextE(t testing.TestingT, ctx context.Context, options *KubectlOptions, matrix []RBACExpectation) (RBACMismatches, error) {
+	clientset, err := GetKubernetesClientFromOptionsContextE(t, ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches RBACMismatches
+
+	for _, expectation := range matrix {
+		status, err := selfSubjectAccessReviewE(ctx, clientset, &authv1.ResourceAttributes{
+			Verb:        expectation.Verb,
+			Group:       expectation.Group,
+			Resource:    expectation.Resource,
+			Subresource: expectation.Subresource,
+			Name:        expectation.Name,
+			Namespace:   expectation.Namespace,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if status.Allowed != expectation.Allowed {
+			options.Logger.Logf(t, "RBAC mismatch for %s: expected %s, got %s", expectation, allowedString(expectation.Allowed), allowedString(status.Allowed))
+			mismatches = append(mismatches, RBACMismatch{Expectation: expectation, Reason: status.Reason})
+		}
+	}
+
+	return mismatches, nil
+}
+
+// EvaluateRBACMatrixContext checks every expectation in the matrix against the cluster, as the identity configured on
+// the provided KubectlOptions, and returns the expectations that did not match. The ctx parameter supports cancellation
+// and timeouts. This will fail the test if there are problems accessing the kubernetes API.
+func EvaluateRBACMatrixContext(t testing.TestingT, ctx context.Context, options *KubectlOptions, matrix []RBACExpectation) RBACMismatches {
+	t.Helper()
+	mismatches, err := EvaluateRBACMatrixContextE(t, ctx, options, matrix)
+	require.NoError(t, err)
+
+	return mismatches
+}
+
+// RequireRBACMatrixContextE checks every expectation in the matrix against the cluster, as the identity configured on
+// the provided KubectlOptions, and returns an RBACMatrixMismatch error listing every expectation that did not match.
+// The ctx parameter supports cancellation and timeouts.
+func RequireRBACMatrixContextE(t testing.TestingT, ctx context.Context, options *KubectlOptions, matrix []RBACExpectation) error {
+	mismatches, err := EvaluateRBACMatrixContextE(t, ctx, options, matrix)
+	if err != nil {
+		return err
+	}
+
+	if len(mismatches) > 0 {
+		return RBACMatrixMismatch{Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+// RequireRBACMatrixContext checks every expectation in the matrix against the cluster, as the identity configured on
+// the provided KubectlOptions. The ctx parameter supports cancellation and timeouts. This will fail the test with a
+// table of all the mismatches if any expectation does not match.
+func RequireRBACMatrixContext(t testing.TestingT, ctx context.Context, options *KubectlOptions, matrix []RBACExpectation) {
+	t.Helper()
+	require.NoError(t, RequireRBACMatrixContextE(t, ctx, options, matrix))
+}
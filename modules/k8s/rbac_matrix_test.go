@@ -0,0 +1,87 @@
+//go:build kubeall || kubernetes
+// +build kubeall kubernetes
+
+// NOTE: we have build tags to differentiate kubernetes tests from non-kubernetes tests. This is done because minikube
+// is heavy and can interfere with docker related tests in terratest. Specifically, many of the tests start to fail with
+// `connection refused` errors from `minikube`. To avoid overloading the system, we run the kubernetes tests and helm
+// tests separately from the others. This may not be necessary if you have a sufficiently powerful machine.  We
+// recommend at least 4 cores and 16GB of RAM if you want to run all the tests together.
+
+package k8s_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+)
+
+func TestRBACMatrixForImpersonatedServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	uniqueID := strings.ToLower(random.UniqueID())
+	options := k8s.NewKubectlOptions("", "", uniqueID)
+
+	configData := fmt.Sprintf(exampleRBACMatrixYAMLTemplate, uniqueID, uniqueID, uniqueID, uniqueID, uniqueID)
+	defer k8s.KubectlDeleteFromString(t, options, configData)
+
+	k8s.KubectlApplyFromString(t, options, configData)
+
+	saOptions := options.ImpersonateServiceAccount(uniqueID, "terratest")
+
+	k8s.RequireRBACMatrixContext(t, t.Context(), saOptions, []k8s.RBACExpectation{
+		{Verb: "get", Resource: "pods", Namespace: uniqueID, Allowed: true},
+		{Verb: "list", Resource: "pods", Namespace: uniqueID, Allowed: true},
+		{Verb: "delete", Resource: "pods", Namespace: uniqueID, Allowed: false},
+		{Verb: "get", Resource: "pods", Namespace: "kube-system", Allowed: false},
+		{Verb: "get", Group: "apps", Resource: "deployments", Namespace: uniqueID, Allowed: false},
+	})
+
+	mismatches := k8s.EvaluateRBACMatrixContext(t, t.Context(), saOptions, []k8s.RBACExpectation{
+		{Verb: "get", Resource: "pods", Namespace: uniqueID, Allowed: false},
+		{Verb: "create", Resource: "secrets", Namespace: uniqueID, Allowed: true},
+	})
+	require.Len(t, mismatches, 2)
+	require.Contains(t, mismatches.String(), "secrets")
+}
+
+const exampleRBACMatrixYAMLTemplate = `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: terratest
+  namespace: %s
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: terratest-pod-reader
+  namespace: %s
+rules:
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["get", "list"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: terratest-pod-reader
+  namespace: %s
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: terratest-pod-reader
+subjects:
+- kind: ServiceAccount
+  name: terratest
+  namespace: %s
+`
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 	authv1 "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
@@ -22,20 +23,30 @@ func CanIDoContextE(t testing.TestingT, ctx context.Context, options *KubectlOpt
 		return false, err
 	}
 
+	status, err := selfSubjectAccessReviewE(ctx, clientset, &action)
+	if err != nil {
+		return false, err
+	}
+
+	if !status.Allowed {
+		options.Logger.Logf(t, "Denied action %s on resource %s with name '%s' for reason %s", action.Verb, action.Resource, action.Name, status.Reason)
+	}
+
+	return status.Allowed, nil
+}
+
+// selfSubjectAccessReviewE submits a SelfSubjectAccessReview for the given action and returns the resulting status.
+func selfSubjectAccessReviewE(ctx context.Context, clientset kubernetes.Interface, action *authv1.ResourceAttributes) (*authv1.SubjectAccessReviewStatus, error) {
 	check := authv1.SelfSubjectAccessReview{
-		Spec: authv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &action},
+		Spec: authv1.SelfSubjectAccessReviewSpec{ResourceAttributes: action},
 	}
 
 	resp, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &check, metav1.CreateOptions{})
 	if err != nil {
-		return false, errors.WithStackTrace(err)
-	}
-
-	if !resp.Status.Allowed {
-		options.Logger.Logf(t, "Denied action %s on resource %s with name '%s' for reason %s", action.Verb, action.Resource, action.Name, resp.Status.Reason)
+		return nil, errors.WithStackTrace(err)
 	}
 
-	return resp.Status.Allowed, nil
+	return &resp.Status, nil
 }
 
 // CanIDoContext returns whether or not the provided action is allowed by the client configured by the provided kubectl option.
@@ -11,18 +11,23 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
 
@@ -65,17 +70,67 @@ func makeLabels(labels map[string]string) string {
 	return strings.Join(out, ",")
 }
 
+// PortPair is a single local to remote port mapping forwarded by a Tunnel. Use 0 for the local port to have an open
+// port on the host system selected automatically.
+type PortPair struct {
+	Local  int
+	Remote int
+}
+
+// TunnelOptions configures the optional behavior of a Tunnel.
+type TunnelOptions struct {
+	// HealthCheck, if set, is called every HealthCheckInterval while the tunnel is open. An error is logged and, when
+	// AutoReconnect is enabled, causes the tunnel to be re-established. It must not call Close on the tunnel.
+	HealthCheck func(tunnel *Tunnel) error
+
+	// Logger is used to log tunnel activity. Defaults to logger.Terratest.
+	Logger logger.TestLogger
+
+	// Ports is the list of local to remote port pairs to forward.
+	Ports []PortPair
+
+	// HealthCheckInterval is how often HealthCheck is called. Defaults to 10 seconds.
+	HealthCheckInterval time.Duration
+
+	// SleepBetweenReconnects is how long to wait between attempts to reconnect. Defaults to 2 seconds.
+	SleepBetweenReconnects time.Duration
+
+	// MaxReconnectRetries is the number of times to retry finding a new pod to reconnect to. Defaults to 30.
+	MaxReconnectRetries int
+
+	// AutoReconnect re-establishes the tunnel to a new backing pod when the connection to the current pod is lost,
+	// for example because the pod was replaced during a rollout. Not supported for ResourceTypePod.
+	AutoReconnect bool
+}
+
+const (
+	defaultHealthCheckInterval    = 10 * time.Second
+	defaultSleepBetweenReconnects = 2 * time.Second
+	defaultMaxReconnectRetries    = 30
+)
+
 // Tunnel is the main struct that configures and manages port forwading tunnels to Kubernetes resources.
 type Tunnel struct {
 	out            io.Writer
 	logger         logger.TestLogger
 	kubectlOptions *KubectlOptions
 	stopChan       chan struct{}
-	readyChan      chan struct{}
+	supervisorDone chan struct{}
+	tunnelOptions  TunnelOptions
 	resourceName   string
-	localPort      int
-	remotePort     int
+	podName        string
+	ports          []PortPair
 	resourceType   KubeResourceType
+	closeOnce      sync.Once
+	mutex          sync.Mutex
+}
+
+// tunnelConnection is a single port forwarding session to a specific pod. A Tunnel that reconnects goes through
+// several connections over its lifetime.
+type tunnelConnection struct {
+	stopChan chan struct{}
+	errChan  chan error
+	podName  string
 }
 
 // NewTunnel creates a new tunnel with NewTunnelWithLogger, setting logger.Terratest as the logger.
@@ -94,27 +149,106 @@ func NewTunnelWithLogger(
 	remote int,
 	logger logger.TestLogger,
 ) *Tunnel {
+	return NewTunnelWithOptions(kubectlOptions, resourceType, resourceName, TunnelOptions{
+		Ports:  []PortPair{{Local: local, Remote: remote}},
+		Logger: logger,
+	})
+}
+
+// NewTunnelWithOptions will create a new Tunnel struct that forwards all the port pairs in the provided TunnelOptions,
+// optionally reconnecting and health checking the tunnel as configured. Any local port set to 0 is replaced with an
+// open port on the host system when the tunnel is opened.
+//
+//nolint:gocritic // hugeParam: options struct is passed by value to match the other constructors
+func NewTunnelWithOptions(kubectlOptions *KubectlOptions, resourceType KubeResourceType, resourceName string, options TunnelOptions) *Tunnel {
+	if options.Logger == nil {
+		options.Logger = logger.Terratest
+	}
+
+	if options.HealthCheckInterval == 0 {
+		options.HealthCheckInterval = defaultHealthCheckInterval
+	}
+
+	if options.SleepBetweenReconnects == 0 {
+		options.SleepBetweenReconnects = defaultSleepBetweenReconnects
+	}
+
+	if options.MaxReconnectRetries == 0 {
+		options.MaxReconnectRetries = defaultMaxReconnectRetries
+	}
+
 	return &Tunnel{
 		out:            io.Discard,
-		localPort:      local,
-		remotePort:     remote,
+		ports:          append([]PortPair{}, options.Ports...),
 		kubectlOptions: kubectlOptions,
 		resourceType:   resourceType,
 		resourceName:   resourceName,
-		logger:         logger,
+		logger:         options.Logger,
+		tunnelOptions:  options,
 		stopChan:       make(chan struct{}, 1),
-		readyChan:      make(chan struct{}, 1),
 	}
 }
 
-// Endpoint returns the tunnel endpoint
+// Endpoint returns the tunnel endpoint of the first port pair, or an empty string if the tunnel does not forward any
+// ports.
 func (tunnel *Tunnel) Endpoint() string {
-	return fmt.Sprintf("localhost:%d", tunnel.localPort)
+	if len(tunnel.ports) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("localhost:%d", tunnel.ports[0].Local)
+}
+
+// EndpointForRemotePort returns the tunnel endpoint that forwards to the given remote port, or an empty string if the
+// tunnel does not forward that port.
+func (tunnel *Tunnel) EndpointForRemotePort(remote int) string {
+	for _, pair := range tunnel.ports {
+		if pair.Remote == remote {
+			return fmt.Sprintf("localhost:%d", pair.Local)
+		}
+	}
+
+	return ""
+}
+
+// Ports returns the port pairs forwarded by the tunnel. Once the tunnel is open, local ports requested as 0 are
+// replaced with the selected open port.
+func (tunnel *Tunnel) Ports() []PortPair {
+	return append([]PortPair{}, tunnel.ports...)
+}
+
+// PodName returns the name of the pod the tunnel is currently connected to. This changes when the tunnel reconnects.
+func (tunnel *Tunnel) PodName() string {
+	tunnel.mutex.Lock()
+	defer tunnel.mutex.Unlock()
+
+	return tunnel.podName
 }
 
-// Close disconnects a tunnel connection by closing the StopChan, thereby stopping the goroutine.
+// Close disconnects a tunnel connection by closing the StopChan, and waits for the goroutine supervising the tunnel to
+// stop, so that it does not log anything after Close returns. It is safe to call Close more than once.
 func (tunnel *Tunnel) Close() {
-	close(tunnel.stopChan)
+	tunnel.closeOnce.Do(func() {
+		close(tunnel.stopChan)
+	})
+
+	tunnel.mutex.Lock()
+	supervisorDone := tunnel.supervisorDone
+	tunnel.mutex.Unlock()
+
+	if supervisorDone != nil {
+		<-supervisorDone
+	}
+}
+
+// isClosed returns true if Close has been called on the tunnel.
+func (tunnel *Tunnel) isClosed() bool {
+	select {
+	case <-tunnel.stopChan:
+		return true
+	default:
+		return false
+	}
 }
 
 // getAttachablePodForResource will find a pod that can be port forwarded to given the provided resource type and return
@@ -147,7 +281,7 @@ func (tunnel *Tunnel) getAttachablePodForDeploymentE(t testing.TestingT) (string
 	}
 
 	for i := range deploymentPods {
-		if IsPodAvailable(&deploymentPods[i]) {
+		if IsPodAvailable(&deploymentPods[i]) && deploymentPods[i].DeletionTimestamp == nil {
 			return deploymentPods[i].Name, nil
 		}
 	}
@@ -170,7 +304,7 @@ func (tunnel *Tunnel) getAttachablePodForServiceE(t testing.TestingT) (string, e
 	}
 
 	for i := range servicePods {
-		if IsPodAvailable(&servicePods[i]) {
+		if IsPodAvailable(&servicePods[i]) && servicePods[i].DeletionTimestamp == nil {
 			return servicePods[i].Name, nil
 		}
 	}
@@ -178,22 +312,67 @@ func (tunnel *Tunnel) getAttachablePodForServiceE(t testing.TestingT) (string, e
 	return "", ServiceNotAvailable{service}
 }
 
+// getTargetPortE returns the port on the pod to forward to for the given remote port. For services, the remote port is
+// the service port, which is translated to the target port on the pod based on the service definition.
+func (tunnel *Tunnel) getTargetPortE(t testing.TestingT, podName string, remotePort int) (int, error) {
+	if tunnel.resourceType != ResourceTypeService {
+		return remotePort, nil
+	}
+
+	service, err := GetServiceE(t, tunnel.kubectlOptions, tunnel.resourceName)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, portSpec := range service.Spec.Ports {
+		if portSpec.Port != int32(remotePort) {
+			continue
+		}
+
+		if portSpec.TargetPort.Type == intstr.String {
+			pod, err := GetPodE(t, tunnel.kubectlOptions, podName)
+			if err != nil {
+				return 0, err
+			}
+
+			targetPort, err := getPodPortByName(pod, portSpec.TargetPort.String())
+			if err != nil {
+				tunnel.logger.Logf(t, "Error selecting port by name: %s", err)
+				return 0, err
+			}
+
+			return targetPort, nil
+		}
+
+		return portSpec.TargetPort.IntValue(), nil
+	}
+
+	return 0, TargetPortNotFoundError{TargetPort: remotePort, ServiceName: tunnel.resourceName}
+}
+
 // ForwardPort opens a tunnel to a kubernetes resource, as specified by the provided tunnel struct. This will fail the
 // test if there is an error attempting to open the port.
 func (tunnel *Tunnel) ForwardPort(t testing.TestingT) {
 	require.NoError(t, tunnel.ForwardPortE(t))
 }
 
-// ForwardPortE opens a tunnel to a kubernetes resource, as specified by the provided tunnel struct.
+// ForwardPortE opens a tunnel to a kubernetes resource, as specified by the provided tunnel struct. The tunnel keeps
+// logging to t until it is closed, so call Close before the test finishes, or use a TunnelManager.
 func (tunnel *Tunnel) ForwardPortE(t testing.TestingT) error {
-	tunnel.logger.Logf(
-		t,
-		"Creating a port forwarding tunnel for resource %s/%s routing local port %d to remote port %d",
-		tunnel.resourceType.String(),
-		tunnel.resourceName,
-		tunnel.localPort,
-		tunnel.remotePort,
-	)
+	if len(tunnel.ports) == 0 {
+		return ErrNoTunnelPorts
+	}
+
+	for _, pair := range tunnel.ports {
+		tunnel.logger.Logf(
+			t,
+			"Creating a port forwarding tunnel for resource %s/%s routing local port %d to remote port %d",
+			tunnel.resourceType.String(),
+			tunnel.resourceName,
+			pair.Local,
+			pair.Remote,
+		)
+	}
 
 	// Prepare a kubernetes client for the client-go library
 	clientset, err := GetKubernetesClientFromOptionsE(t, tunnel.kubectlOptions)
@@ -217,52 +396,75 @@ func (tunnel *Tunnel) ForwardPortE(t testing.TestingT) error {
 		}
 	}
 
-	// Find the pod to port forward to
-	podName, err := tunnel.getAttachablePodForResourceE(t)
+	config = tunnel.kubectlOptions.applyImpersonation(config)
+
+	// If any local port is 0, get an available port before continuing. We do this here instead of relying on the
+	// underlying portforwarder library, because the portforwarder library does not expose the selected local port in a
+	// machine readable manner. Selecting the ports up front also means reconnections reuse the same local ports.
+	// Synchronize on the global lock to avoid race conditions with concurrently selecting the same available port,
+	// since there is a brief moment between `GetAvailablePort` and `portforwader.ForwardPorts` where the selected port
+	// is available for selection again.
+	if slices.ContainsFunc(tunnel.ports, func(pair PortPair) bool { return pair.Local == 0 }) {
+		globalMutex.Lock()
+		defer globalMutex.Unlock()
+	}
+
+	for i := range tunnel.ports {
+		if tunnel.ports[i].Local != 0 {
+			continue
+		}
+
+		tunnel.logger.Logf(t, "Requested local port is 0. Selecting an open port on host system")
+
+		tunnel.ports[i].Local, err = GetAvailablePortE(t)
+		if err != nil {
+			tunnel.logger.Logf(t, "Error getting available port: %s", err)
+			return err
+		}
+
+		tunnel.logger.Logf(t, "Selected port %d", tunnel.ports[i].Local)
+	}
+
+	connection, err := tunnel.connectE(t, clientset, config)
 	if err != nil {
-		tunnel.logger.Logf(t, "Error finding available pod: %s", err)
 		return err
 	}
 
-	tunnel.logger.Logf(t, "Selected pod %s to open port forward to", podName)
-
-	var targetPort = tunnel.remotePort
+	supervisorDone := make(chan struct{})
 
-	// in case of services, find target port on pod based on service definition
-	if tunnel.resourceType == ResourceTypeService {
-		service := GetService(t, tunnel.kubectlOptions, tunnel.resourceName)
+	tunnel.mutex.Lock()
+	tunnel.supervisorDone = supervisorDone
+	tunnel.mutex.Unlock()
 
-		var portFound = false
+	go func() {
+		defer close(supervisorDone)
 
-		for _, portSpec := range service.Spec.Ports {
-			if portSpec.Port == int32(targetPort) {
-				if portSpec.TargetPort.Type == intstr.String {
-					pod, err := GetPodE(t, tunnel.kubectlOptions, podName)
-					if err != nil {
-						return err
-					}
+		tunnel.supervise(t, clientset, config, connection)
+	}()
 
-					targetPort, err = getPodPortByName(pod, portSpec.TargetPort.String())
-					if err != nil {
-						tunnel.logger.Logf(t, "Error selecting port by name: %s", err)
-						return err
-					}
+	return nil
+}
 
-					portFound = true
+// connectE finds a pod for the tunnel resource and opens a port forwarding session to it, waiting until it is ready.
+func (tunnel *Tunnel) connectE(t testing.TestingT, clientset *kubernetes.Clientset, config *rest.Config) (*tunnelConnection, error) {
+	// Find the pod to port forward to
+	podName, err := tunnel.getAttachablePodForResourceE(t)
+	if err != nil {
+		tunnel.logger.Logf(t, "Error finding available pod: %s", err)
+		return nil, err
+	}
 
-					break
-				}
+	tunnel.logger.Logf(t, "Selected pod %s to open port forward to", podName)
 
-				targetPort = portSpec.TargetPort.IntValue()
-				portFound = true
+	ports := make([]string, 0, len(tunnel.ports))
 
-				break
-			}
+	for _, pair := range tunnel.ports {
+		targetPort, err := tunnel.getTargetPortE(t, podName, pair.Remote)
+		if err != nil {
+			return nil, err
 		}
 
-		if !portFound {
-			return TargetPortNotFoundError{TargetPort: targetPort, ServiceName: tunnel.resourceName}
-		}
+		ports = append(ports, fmt.Sprintf("%d:%d", pair.Local, targetPort))
 	}
 
 	// Build a url to the portforward endpoint
@@ -282,57 +484,208 @@ func (tunnel *Tunnel) ForwardPortE(t testing.TestingT) error {
 	transport, upgrader, err := spdy.RoundTripperFor(config)
 	if err != nil {
 		tunnel.logger.Logf(t, "Error creating http client: %s", err)
-		return err
+		return nil, err
 	}
 
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", portForwardCreateURL)
 
-	// If the localport is 0, get an available port before continuing. We do this here instead of relying on the
-	// underlying portforwarder library, because the portforwarder library does not expose the selected local port in a
-	// machine readable manner.
-	// Synchronize on the global lock to avoid race conditions with concurrently selecting the same available port,
-	// since there is a brief moment between `GetAvailablePort` and `portforwader.ForwardPorts` where the selected port
-	// is available for selection again.
-	if tunnel.localPort == 0 {
-		tunnel.logger.Logf(t, "Requested local port is 0. Selecting an open port on host system")
-
-		tunnel.localPort, err = GetAvailablePortE(t)
-		if err != nil {
-			tunnel.logger.Logf(t, "Error getting available port: %s", err)
-			return err
-		}
-
-		tunnel.logger.Logf(t, "Selected port %d", tunnel.localPort)
-		globalMutex.Lock()
-		defer globalMutex.Unlock()
+	connection := &tunnelConnection{
+		podName:  podName,
+		stopChan: make(chan struct{}),
+		errChan:  make(chan error, 1),
 	}
 
 	// Construct a new PortForwarder struct that manages the instructed port forward tunnel
-	ports := []string{fmt.Sprintf("%d:%d", tunnel.localPort, targetPort)}
-
-	portforwarder, err := portforward.New(dialer, ports, tunnel.stopChan, tunnel.readyChan, tunnel.out, tunnel.out)
+	portforwarder, err := portforward.New(dialer, ports, connection.stopChan, make(chan struct{}, 1), tunnel.out, tunnel.out)
 	if err != nil {
 		tunnel.logger.Logf(t, "Error creating port forwarding tunnel: %s", err)
-		return err
+		return nil, err
 	}
 
-	// Open the tunnel in a goroutine so that it is available in the background. Report errors to the main goroutine via
-	// a new channel.
-	errChan := make(chan error)
-
+	// Open the tunnel in a goroutine so that it is available in the background. Report errors to the supervising
+	// goroutine via the connection's error channel.
 	go func() {
-		errChan <- portforwarder.ForwardPorts()
+		connection.errChan <- portforwarder.ForwardPorts()
 	}()
 
 	// Wait for an error or the tunnel to be ready
 	select {
-	case err = <-errChan:
+	case err = <-connection.errChan:
 		tunnel.logger.Logf(t, "Error starting port forwarding tunnel: %s", err)
-		return err
+		return nil, err
 	case <-portforwarder.Ready:
 		tunnel.logger.Logf(t, "Successfully created port forwarding tunnel")
-		return nil
 	}
+
+	tunnel.mutex.Lock()
+	tunnel.podName = podName
+	tunnel.mutex.Unlock()
+
+	return connection, nil
+}
+
+// supervise watches an open connection until the tunnel is closed. If the connection drops or the health check fails,
+// it is logged and, when AutoReconnect is enabled, the tunnel is re-established to a new backing pod.
+func (tunnel *Tunnel) supervise(t testing.TestingT, clientset *kubernetes.Clientset, config *rest.Config, connection *tunnelConnection) {
+	var healthCheckTicks <-chan time.Time
+
+	if tunnel.tunnelOptions.HealthCheck != nil {
+		ticker := time.NewTicker(tunnel.tunnelOptions.HealthCheckInterval)
+		defer ticker.Stop()
+
+		healthCheckTicks = ticker.C
+	}
+
+	for {
+		select {
+		case <-tunnel.stopChan:
+			close(connection.stopChan)
+			return
+		case err := <-connection.errChan:
+			if tunnel.isClosed() {
+				return
+			}
+
+			tunnel.logger.Logf(t, "Port forwarding tunnel to pod %s was lost: %v", connection.podName, err)
+		case <-healthCheckTicks:
+			err := tunnel.tunnelOptions.HealthCheck(tunnel)
+			if err == nil {
+				continue
+			}
+
+			tunnel.logger.Logf(t, "Health check of port forwarding tunnel to pod %s failed: %s", connection.podName, err)
+
+			if !tunnel.canReconnect() {
+				continue
+			}
+
+			close(connection.stopChan)
+			<-connection.errChan
+		}
+
+		if !tunnel.canReconnect() {
+			tunnel.logger.Logf(t, "Port forwarding tunnel for resource %s/%s is closed", tunnel.resourceType.String(), tunnel.resourceName)
+			return
+		}
+
+		var err error
+
+		connection, err = tunnel.reconnectE(t, clientset, config)
+		if err != nil {
+			tunnel.logger.Logf(t, "Giving up reconnecting port forwarding tunnel for resource %s/%s: %s", tunnel.resourceType.String(), tunnel.resourceName, err)
+			return
+		}
+	}
+}
+
+// canReconnect returns true if the tunnel is configured to reconnect and is able to do so. Tunnels directly to a pod
+// cannot reconnect to a different pod.
+func (tunnel *Tunnel) canReconnect() bool {
+	return tunnel.tunnelOptions.AutoReconnect && tunnel.resourceType != ResourceTypePod
+}
+
+// reconnectE repeatedly tries to open a new connection for the tunnel until it succeeds, the tunnel is closed, or the
+// maximum number of retries is exceeded, in which case it returns a retry.RetryError like the retry functions do.
+func (tunnel *Tunnel) reconnectE(t testing.TestingT, clientset *kubernetes.Clientset, config *rest.Config) (*tunnelConnection, error) {
+	description := fmt.Sprintf("Reconnect port forwarding tunnel for resource %s/%s", tunnel.resourceType.String(), tunnel.resourceName)
+
+	var attempts []retry.Attempt
+
+	for i := 0; i <= tunnel.tunnelOptions.MaxReconnectRetries; i++ {
+		tunnel.logger.Logf(t, "%s", description)
+
+		start := time.Now()
+
+		connection, err := tunnel.connectE(t, clientset, config)
+		if err == nil {
+			return connection, nil
+		}
+
+		attempts = append(attempts, retry.Attempt{Start: start, Err: err, Number: i + 1, Duration: time.Since(start)})
+
+		select {
+		case <-tunnel.stopChan:
+			return nil, ErrTunnelClosed
+		case <-time.After(tunnel.tunnelOptions.SleepBetweenReconnects):
+		}
+	}
+
+	return nil, retry.RetryError{
+		Err:      retry.MaxRetriesExceeded{Description: description, MaxRetries: tunnel.tunnelOptions.MaxReconnectRetries},
+		Attempts: attempts,
+	}
+}
+
+// TunnelManager keeps track of a set of tunnels and closes all of them at once, by default when the test finishes.
+type TunnelManager struct {
+	tunnels []*Tunnel
+	mutex   sync.Mutex
+}
+
+// NewTunnelManager creates a new TunnelManager. If t supports registering cleanup functions (like testing.T does), all
+// the managed tunnels are closed automatically when the test finishes.
+func NewTunnelManager(t testing.TestingT) *TunnelManager {
+	manager := &TunnelManager{}
+
+	if tt, ok := t.(testing.Cleaner); ok {
+		tt.Cleanup(manager.CloseAll)
+	}
+
+	return manager
+}
+
+// Add registers an existing tunnel with the manager so that it is closed by CloseAll.
+func (manager *TunnelManager) Add(tunnel *Tunnel) *Tunnel {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.tunnels = append(manager.tunnels, tunnel)
+
+	return tunnel
+}
+
+// ForwardPortE creates a tunnel with the provided options, registers it with the manager and opens it.
+//
+//nolint:gocritic // hugeParam: options struct is passed by value to match NewTunnelWithOptions
+func (manager *TunnelManager) ForwardPortE(
+	t testing.TestingT,
+	kubectlOptions *KubectlOptions,
+	resourceType KubeResourceType,
+	resourceName string,
+	options TunnelOptions,
+) (*Tunnel, error) {
+	tunnel := manager.Add(NewTunnelWithOptions(kubectlOptions, resourceType, resourceName, options))
+
+	return tunnel, tunnel.ForwardPortE(t)
+}
+
+// ForwardPort creates a tunnel with the provided options, registers it with the manager and opens it. This will fail
+// the test if there is an error attempting to open the tunnel.
+//
+//nolint:gocritic // hugeParam: options struct is passed by value to match NewTunnelWithOptions
+func (manager *TunnelManager) ForwardPort(
+	t testing.TestingT,
+	kubectlOptions *KubectlOptions,
+	resourceType KubeResourceType,
+	resourceName string,
+	options TunnelOptions,
+) *Tunnel {
+	tunnel, err := manager.ForwardPortE(t, kubectlOptions, resourceType, resourceName, options)
+	require.NoError(t, err)
+
+	return tunnel
+}
+
+// CloseAll closes every tunnel registered with the manager.
+func (manager *TunnelManager) CloseAll() {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	for _, tunnel := range manager.tunnels {
+		tunnel.Close()
+	}
+
+	manager.tunnels = nil
 }
 
 // GetAvailablePortContext retrieves an available port on the host machine using the provided context. This delegates the
@@ -16,6 +16,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/gruntwork-io/terratest/modules/k8s"
 
 	http_helper "github.com/gruntwork-io/terratest/modules/http-helper"
@@ -147,6 +149,89 @@ func TestTunnelOpensAPortForwardTunnelToService(t *testing.T) {
 	}
 }
 
+func TestTunnelWithoutPorts(t *testing.T) {
+	t.Parallel()
+
+	tunnel := k8s.NewTunnelWithOptions(k8s.NewKubectlOptions("", "", "default"), k8s.ResourceTypePod, "nginx-pod", k8s.TunnelOptions{})
+	defer tunnel.Close()
+
+	require.Empty(t, tunnel.Endpoint())
+	require.ErrorIs(t, tunnel.ForwardPortE(t), k8s.ErrNoTunnelPorts)
+}
+
+func TestTunnelForwardsMultiplePorts(t *testing.T) {
+	t.Parallel()
+
+	uniqueID := strings.ToLower(random.UniqueID())
+	options := k8s.NewKubectlOptions("", "", uniqueID)
+
+	configData := fmt.Sprintf(examplePodWithMultipleContainersYAMLTemplate, uniqueID, uniqueID)
+	defer k8s.KubectlDeleteFromString(t, options, configData)
+
+	k8s.KubectlApplyFromString(t, options, configData)
+	k8s.WaitUntilPodAvailable(t, options, "nginx-pod", 60, 1*time.Second)
+
+	manager := k8s.NewTunnelManager(t)
+	tunnel := manager.ForwardPort(t, options, k8s.ResourceTypePod, "nginx-pod", k8s.TunnelOptions{
+		Ports: []k8s.PortPair{{Local: 0, Remote: 80}, {Local: 0, Remote: 8080}},
+	})
+
+	tlsConfig := tls.Config{}
+
+	for _, remotePort := range []int{80, 8080} {
+		http_helper.HTTPGetWithRetryWithCustomValidationContext(
+			t,
+			t.Context(),
+			"http://"+tunnel.EndpointForRemotePort(remotePort),
+			&tlsConfig,
+			60,
+			5*time.Second,
+			verifyNginxWelcomePage,
+		)
+	}
+}
+
+func TestTunnelReconnectsWhenPodIsReplaced(t *testing.T) {
+	t.Parallel()
+
+	uniqueID := strings.ToLower(random.UniqueID())
+	options := k8s.NewKubectlOptions("", "", uniqueID)
+	configData := fmt.Sprintf(ExampleDeploymentYAMLTemplate, uniqueID)
+
+	k8s.KubectlApplyFromString(t, options, configData)
+	defer k8s.KubectlDeleteFromString(t, options, configData)
+
+	k8s.WaitUntilDeploymentAvailable(t, options, "nginx-deployment", 60, 1*time.Second)
+
+	tlsConfig := tls.Config{}
+
+	manager := k8s.NewTunnelManager(t)
+	tunnel := manager.ForwardPort(t, options, k8s.ResourceTypeDeployment, "nginx-deployment", k8s.TunnelOptions{
+		Ports:                  []k8s.PortPair{{Local: 0, Remote: 80}},
+		AutoReconnect:          true,
+		SleepBetweenReconnects: 1 * time.Second,
+		HealthCheckInterval:    2 * time.Second,
+		HealthCheck: func(tunnel *k8s.Tunnel) error {
+			_, _, err := http_helper.HTTPGetContextE(t, t.Context(), "http://"+tunnel.Endpoint(), &tlsConfig)
+			return err
+		},
+	})
+
+	originalPod := tunnel.PodName()
+	k8s.RunKubectl(t, options, "delete", "pod", originalPod)
+
+	http_helper.HTTPGetWithRetryWithCustomValidationContext(
+		t,
+		t.Context(),
+		"http://"+tunnel.Endpoint(),
+		&tlsConfig,
+		60,
+		5*time.Second,
+		verifyNginxWelcomePage,
+	)
+	require.NotEqual(t, originalPod, tunnel.PodName())
+}
+
 func verifyNginxWelcomePage(statusCode int, body string) bool {
 	if statusCode != 200 {
 		return false
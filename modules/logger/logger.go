@@ -57,7 +57,8 @@ type TestLogger interface {
 
 // Logger wraps a TestLogger implementation and provides nil-safe logging.
 type Logger struct {
-	l TestLogger
+	l      TestLogger
+	fields Fields
 }
 
 // New creates a new Logger instance wrapping the given TestLogger implementation.
@@ -75,12 +76,15 @@ func (l *Logger) Logf(t testing.TestingT, format string, args ...any) {
 
 	// methods can be called on (typed) nil pointers. In this case, use the Default function to log. This enables the
 	// caller to do `var l *Logger` and then use the logger already.
-	if l == nil || l.l == nil {
-		Default.Logf(t, format, args...)
+	inner, fields := l.resolve()
+	format, args = redactArgs(format, args)
+
+	if structured, ok := inner.(StructuredTestLogger); ok {
+		structured.Log(t, newEntry(t, LevelInfo, fields, format, args))
 		return
 	}
 
-	l.l.Logf(t, format, args...)
+	inner.Logf(t, format, args...)
 }
 
 // helper is used to mark this library as a "helper", and thus not appearing in the line numbers. testing.T implements
@@ -167,7 +171,7 @@ func DoLog(t testing.TestingT, callDepth int, writer io.Writer, args ...any) {
 	prefix := fmt.Sprintf("%s %s %s:", t.Name(), date.Format(time.RFC3339), CallerPrefix(callDepth+1))
 	allArgs := append([]any{prefix}, args...)
 	//nolint:errcheck // DoLog is a void logging function; swallowing write errors is intentional
-	fmt.Fprintln(writer, allArgs...)
+	io.WriteString(writer, Redact(fmt.Sprintln(allArgs...)))
 }
 
 // CallerPrefix returns the file and line number information about the methods that called this method, based on the current
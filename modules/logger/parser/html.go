@@ -0,0 +1,356 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	junitparser "github.com/jstemmer/go-junit-report/parser"
+	"github.com/sirupsen/logrus"
+)
+
+// RegEx for classifying log lines in the HTML report. The stage regex matches the log lines of
+// test_structure.RunTestStage.
+var (
+	regexStage = regexp.MustCompile(`(executing|skipping) stage '([^']+)'`)
+	regexError = regexp.MustCompile(`--- FAIL:|\bError( Trace)?:|\berror:|^\s*(FAIL|fatal error:)`)
+)
+
+// htmlReport is the data of the HTML report template.
+type htmlReport struct {
+	Duration string
+	Output   []htmlLine
+	Tests    []*htmlTest
+	Total    int
+	Passed   int
+	Failed   int
+	Skipped  int
+}
+
+// htmlTest is a test or subtest in the HTML report.
+type htmlTest struct {
+	Parent   *htmlTest
+	ID       string
+	Name     string
+	Package  string
+	Result   string
+	Duration string
+	Subtests []*htmlTest
+	Stages   []htmlLine
+	Lines    []htmlLine
+	Depth    int
+}
+
+// htmlLine is a line of test output in the HTML report. Class is used to highlight errors, panics and stages.
+type htmlLine struct {
+	ID    string
+	Text  string
+	Class string
+}
+
+// StoreHTMLReport renders the results of a test run into a single self-contained HTML file, report.html, in the output
+// directory. The report has a summary table with the result and duration of every test, and the output of every test
+// in a collapsible section, with the errors, panics and stages of test_structure.RunTestStage highlighted.
+func StoreHTMLReport(logger *logrus.Logger, outputDir string, report *TestReport) {
+	if err := EnsureDirectoryExists(logger, outputDir); err != nil {
+		logger.Errorf("Error ensuring output directory exists: %s", err)
+		return
+	}
+
+	filename := filepath.Join(outputDir, "report.html")
+
+	f, err := os.Create(filename)
+	if err != nil {
+		logger.Errorf("Error making file %s for html report", filename)
+		return
+	}
+
+	defer f.Close()
+
+	if err := htmlReportTemplate.Execute(f, newHTMLReport(report)); err != nil {
+		logger.Errorf("Error rendering html report: %s", err)
+	}
+}
+
+func newHTMLReport(report *TestReport) *htmlReport {
+	result := &htmlReport{}
+
+	var duration time.Duration
+
+	for _, line := range report.Output {
+		result.Output = append(result.Output, newHTMLLine(line))
+	}
+
+	for _, pkg := range report.Packages {
+		duration += pkg.Elapsed
+
+		for _, line := range pkg.Output {
+			result.Output = append(result.Output, newHTMLLine(line))
+		}
+
+		for _, test := range pkg.Tests {
+			result.addTest(pkg.Name, test, nil)
+		}
+	}
+
+	result.Duration = formatHTMLDuration(duration)
+
+	return result
+}
+
+// addTest adds the test and its subtests to the report, right after each other so that subtests are listed under their
+// parent in the summary table.
+func (report *htmlReport) addTest(pkg string, test *TestResult, parent *htmlTest) {
+	result := test.Result
+	if result == "" {
+		result = "unfinished"
+	}
+
+	item := &htmlTest{
+		ID:       fmt.Sprintf("test-%d", len(report.Tests)+1),
+		Name:     test.Name,
+		Package:  pkg,
+		Result:   result,
+		Duration: formatHTMLDuration(test.Elapsed),
+		Parent:   parent,
+	}
+
+	if parent != nil {
+		item.Depth = parent.Depth + 1
+		parent.Subtests = append(parent.Subtests, item)
+	}
+
+	for index, line := range test.Output {
+		htmlLine := newHTMLLine(line)
+
+		if htmlLine.Class == "stage" {
+			htmlLine.ID = fmt.Sprintf("%s-line-%d", item.ID, index+1)
+			item.Stages = append(item.Stages, htmlLine)
+		}
+
+		item.Lines = append(item.Lines, htmlLine)
+	}
+
+	report.Tests = append(report.Tests, item)
+	report.Total++
+
+	switch test.Result {
+	case ResultPass:
+		report.Passed++
+	case ResultSkip:
+		report.Skipped++
+	default:
+		report.Failed++
+	}
+
+	for _, subtest := range test.Subtests {
+		report.addTest(pkg, subtest, item)
+	}
+}
+
+// newHTMLLine returns the line with the class that highlights it.
+func newHTMLLine(line string) htmlLine {
+	result := htmlLine{Text: line}
+
+	switch {
+	case regexStage.MatchString(line):
+		result.Class = "stage"
+	case IsPanicLine(strings.TrimSpace(line)):
+		result.Class = "panic"
+	case regexError.MatchString(line):
+		result.Class = "error"
+	case IsStatusLine(line) || IsResultLine(line):
+		result.Class = "status"
+	}
+
+	return result
+}
+
+// OutputOpen returns whether the output that does not belong to a test is expanded when the report is opened, which is
+// the case when it contains errors or panics.
+func (report *htmlReport) OutputOpen() bool {
+	for _, line := range report.Output {
+		if line.Class == "panic" || line.Class == "error" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StageName returns the name of the stage that a stage line runs or skips.
+func (line htmlLine) StageName() string {
+	match := regexStage.FindStringSubmatch(line.Text)
+	if match == nil {
+		return ""
+	}
+
+	if match[1] == "skipping" {
+		return match[2] + " (skipped)"
+	}
+
+	return match[2]
+}
+
+// ShortName returns the name of a subtest without the name of its parent test.
+func (test *htmlTest) ShortName() string {
+	if test.Parent == nil {
+		return test.Name
+	}
+
+	return strings.TrimPrefix(test.Name, test.Parent.Name+"/")
+}
+
+// Open returns whether the output of the test is expanded when the report is opened, which is the case for failures.
+func (test *htmlTest) Open() bool {
+	return test.Result != ResultPass && test.Result != ResultSkip
+}
+
+func formatHTMLDuration(duration time.Duration) string {
+	return fmt.Sprintf("%.2fs", duration.Seconds())
+}
+
+// newTestReportFromJunit converts the report parsed from plain `go test -v` output into the results of a test run,
+// with the output of each test read from the log files stored in the output directory, and the other output, such as
+// panics, from the summary.
+func newTestReportFromJunit(logger *logrus.Logger, report *junitparser.Report, outputDir string) *TestReport {
+	result := &TestReport{}
+
+	for _, line := range readLogLines(logger, filepath.Join(outputDir, "summary.log")) {
+		if !IsResultLine(line) {
+			result.Output = append(result.Output, line)
+		}
+	}
+
+	for _, junitPkg := range report.Packages {
+		pkg := &PackageResult{Name: junitPkg.Name, Elapsed: junitPkg.Duration}
+		tests := make(map[string]*TestResult)
+
+		for _, junitTest := range junitPkg.Tests {
+			test := &TestResult{
+				Name:    junitTest.Name,
+				Elapsed: junitTest.Duration,
+				Result:  map[junitparser.Result]string{junitparser.PASS: ResultPass, junitparser.FAIL: ResultFail, junitparser.SKIP: ResultSkip}[junitTest.Result],
+				Output:  readLogLines(logger, filepath.Join(outputDir, junitTest.Name+".log")),
+			}
+
+			if test.Output == nil {
+				test.Output = junitTest.Output
+			}
+
+			addTest(pkg, tests, test)
+		}
+
+		result.Packages = append(result.Packages, pkg)
+	}
+
+	return result
+}
+
+// readLogLines returns the lines of the given log file, or nil if it can't be read.
+func readLogLines(logger *logrus.Logger, filename string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		logger.Debugf("Could not read log file %s: %s", filename, err)
+		return nil
+	}
+
+	defer file.Close()
+
+	var lines []string
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, bufio.MaxScanTokenSize*1024) //nolint:mnd // allow long log lines
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Test report</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+h1 { font-size: 1.6em; }
+.totals span { margin-right: 1.5em; }
+table { border-collapse: collapse; margin: 1em 0 2em; }
+th, td { padding: 0.3em 0.8em; border-bottom: 1px solid #d0d7de; text-align: left; }
+td.duration { text-align: right; font-variant-numeric: tabular-nums; }
+.result { display: inline-block; min-width: 5em; padding: 0.1em 0.5em; border-radius: 1em; color: #fff; text-align: center; font-size: 0.85em; }
+.result-pass { background: #1a7f37; }
+.result-fail, .result-unfinished { background: #cf222e; }
+.result-skip { background: #9a6700; }
+details { margin: 0.5em 0; border: 1px solid #d0d7de; border-radius: 6px; }
+details > summary { padding: 0.5em 0.8em; cursor: pointer; background: #f6f8fa; }
+details:target > summary { background: #ddf4ff; }
+.links { padding: 0.5em 0.8em; font-size: 0.9em; }
+.links a { margin-right: 1em; }
+pre { margin: 0; padding: 0.5em 0.8em; overflow-x: auto; font-size: 0.85em; line-height: 1.4; }
+pre span { display: block; min-height: 1.4em; }
+.status { color: #57606a; }
+.error { background: #ffebe9; color: #82071e; }
+.panic { background: #cf222e; color: #fff; font-weight: bold; }
+.stage { background: #ddf4ff; font-weight: bold; border-top: 1px solid #54aeff; }
+</style>
+</head>
+<body>
+<h1>Test report</h1>
+<p class="totals">
+<span>{{.Total}} tests</span>
+<span>{{.Passed}} passed</span>
+<span>{{.Failed}} failed</span>
+<span>{{.Skipped}} skipped</span>
+<span>Duration {{.Duration}}</span>
+</p>
+<table>
+<thead><tr><th>Test</th><th>Package</th><th>Result</th><th>Duration</th></tr></thead>
+<tbody>
+{{- range .Tests}}
+<tr><td style="padding-left: {{.Depth}}.5em"><a href="#{{.ID}}">{{if .Parent}}&#8627; {{.ShortName}}{{else}}{{.Name}}{{end}}</a></td><td>{{.Package}}</td><td><span class="result result-{{.Result}}">{{.Result}}</span></td><td class="duration">{{.Duration}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- if .Output}}
+<details id="output"{{if .OutputOpen}} open{{end}}>
+<summary>Output outside of tests</summary>
+<pre>{{range .Output}}<span{{if .Class}} class="{{.Class}}"{{end}}>{{.Text}}</span>{{end}}</pre>
+</details>
+{{- end}}
+{{- range .Tests}}
+<details id="{{.ID}}"{{if .Open}} open{{end}}>
+<summary><span class="result result-{{.Result}}">{{.Result}}</span> {{.Name}} ({{.Duration}})</summary>
+{{- if or .Parent .Subtests .Stages}}
+<div class="links">
+{{- with .Parent}}Parent: <a href="#{{.ID}}">{{.Name}}</a> {{end}}
+{{- if .Subtests}}Subtests:{{range .Subtests}} <a href="#{{.ID}}">{{.ShortName}}</a>{{end}} {{end}}
+{{- if .Stages}}Stages:{{range .Stages}} <a href="#{{.ID}}">{{.StageName}}</a>{{end}}{{end}}
+</div>
+{{- end}}
+<pre>{{range .Lines}}<span{{if .ID}} id="{{.ID}}"{{end}}{{if .Class}} class="{{.Class}}"{{end}}>{{.Text}}</span>{{end}}</pre>
+</details>
+{{- end}}
+<script>
+// Expand the output of a test when following a link to it, or to one of its lines.
+function openTarget() {
+  var target = location.hash && document.getElementById(location.hash.slice(1));
+  for (var element = target; element; element = element.parentElement) {
+    if (element.tagName === "DETAILS") { element.open = true; }
+  }
+  if (target) { target.scrollIntoView(); }
+}
+window.addEventListener("hashchange", openTarget);
+openTarget();
+</script>
+</body>
+</html>
+`))
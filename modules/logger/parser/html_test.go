@@ -0,0 +1,47 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLReport(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/pkg","Test":"TestStages"}`,
+		`{"Action":"output","Package":"example.com/pkg","Test":"TestStages","Output":"TestStages 2024-01-02T15:04:05Z test_structure.go:45: The 'SKIP_deploy' environment variable is not set, so executing stage 'deploy'.\n"}`,
+		`{"Action":"output","Package":"example.com/pkg","Test":"TestStages","Output":"TestStages 2024-01-02T15:04:05Z test_structure.go:48: The 'SKIP_teardown' environment variable is set, so skipping stage 'teardown'.\n"}`,
+		`{"Action":"run","Package":"example.com/pkg","Test":"TestStages/<script>"}`,
+		`{"Action":"output","Package":"example.com/pkg","Test":"TestStages/<script>","Output":"        \tError:      \tShould be true\n"}`,
+		`{"Action":"fail","Package":"example.com/pkg","Test":"TestStages/<script>","Elapsed":1.5}`,
+		`{"Action":"fail","Package":"example.com/pkg","Test":"TestStages","Elapsed":2}`,
+		`{"Action":"output","Package":"example.com/pkg","Output":"panic: test timed out after 30m0s\n"}`,
+		`{"Action":"fail","Package":"example.com/pkg","Elapsed":2.1}`,
+	}, "\n")
+
+	output := t.TempDir()
+	parser.SpawnJSONParsers(NewTestLogger(t), strings.NewReader(input), output)
+
+	data, err := os.ReadFile(filepath.Join(output, "report.html"))
+	require.NoError(t, err)
+
+	report := string(data)
+	assert.Contains(t, report, `<span>2 tests</span>`)
+	assert.Contains(t, report, `<span>2 failed</span>`)
+	assert.Contains(t, report, `<a href="#test-2">&#8627; &lt;script&gt;</a>`)
+	assert.Contains(t, report, `Subtests: <a href="#test-2">&lt;script&gt;</a>`)
+	assert.Contains(t, report, `Parent: <a href="#test-1">TestStages</a>`)
+	assert.Contains(t, report, `Stages: <a href="#test-1-line-1">deploy</a> <a href="#test-1-line-2">teardown (skipped)</a>`)
+	assert.Contains(t, report, `<span id="test-1-line-1" class="stage">`)
+	assert.Contains(t, report, "<span class=\"error\">        \tError:      \tShould be true</span>")
+	assert.Contains(t, report, `<span class="panic">panic: test timed out after 30m0s</span>`)
+	assert.Contains(t, report, `<td class="duration">1.50s</td>`)
+	assert.NotContains(t, report, `<script>"`)
+}
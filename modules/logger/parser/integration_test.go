@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/gruntwork-io/terratest/modules/logger/parser"
 	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -70,6 +72,12 @@ func openFile(t *testing.T, filename string) *os.File {
 func testExample(t *testing.T, example string) {
 	t.Helper()
 
+	testExampleWithParser(t, example, parser.SpawnParsers)
+}
+
+func testExampleWithParser(t *testing.T, example string, spawnParsers func(*logrus.Logger, io.Reader, string)) {
+	t.Helper()
+
 	expected, output := path.Join(t.TempDir(), "expected"), path.Join(t.TempDir(), "output")
 	require.NoError(t, os.Mkdir(expected, 0755))
 	require.NoError(t, os.Mkdir(output, 0755))
@@ -89,7 +97,7 @@ func testExample(t *testing.T, example string) {
 	logFileName := fmt.Sprintf("./fixtures/%s_example.log", example)
 	file := openFile(t, logFileName)
 
-	parser.SpawnParsers(logger, file, output)
+	spawnParsers(logger, file, output)
 
 	// assert
 	assert.True(t, DirectoryEqualContext(t, context.Background(), expected, output))
@@ -114,3 +122,8 @@ func TestIntegrationNewGoExample(t *testing.T) {
 	t.Parallel()
 	testExample(t, "new_go_failing")
 }
+
+func TestIntegrationJSONExample(t *testing.T) {
+	t.Parallel()
+	testExampleWithParser(t, "json", parser.SpawnJSONParsers)
+}
@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The results of tests and packages, which are the test2json actions that end them.
+const (
+	ResultPass = "pass"
+	ResultFail = "fail"
+	ResultSkip = "skip"
+)
+
+// TestEvent is a single event of `go test -json` output, as emitted by test2json. See `go doc test2json`.
+type TestEvent struct {
+	Time    time.Time `json:",omitzero"`
+	Action  string
+	Package string  `json:",omitempty"`
+	Test    string  `json:",omitempty"`
+	Output  string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"`
+}
+
+// TestReport contains the results of all the packages of a test run.
+type TestReport struct {
+	// Output contains the lines of output that do not belong to a package, such as build errors.
+	Output   []string
+	Packages []*PackageResult
+}
+
+// PackageResult contains the results of the tests of a single package.
+type PackageResult struct {
+	// Name is the import path of the package.
+	Name string
+	// Result is one of ResultPass, ResultFail or ResultSkip, or empty if the package did not finish.
+	Result string
+	// Output contains the lines of output that do not belong to a test, such as the final ok or FAIL line.
+	Output []string
+	// Tests are the top level tests of the package, in the order they started.
+	Tests   []*TestResult
+	Elapsed time.Duration
+}
+
+// TestResult contains the result and the output of a single test or subtest.
+type TestResult struct {
+	Start time.Time
+	// Name is the full name of the test, such as TestSnafu/Situation for subtests.
+	Name string
+	// Result is one of ResultPass, ResultFail or ResultSkip, or empty if the test did not finish, for example because
+	// the test binary panicked or timed out.
+	Result string
+	// Output contains the lines of output of the test, including the go test status lines like === RUN.
+	Output []string
+	// Subtests are the subtests of this test, in the order they started.
+	Subtests []*TestResult
+	Elapsed  time.Duration
+}
+
+// SpawnJSONParsers parses `go test -json` output from the reader, and stores the logs of each test, a summary, a junit
+// report and an html report in the output directory, like SpawnParsers does for plain `go test -v` output. Because every event
+// names the test it belongs to, the output of parallel tests is attributed exactly, including subtests. Lines that are
+// not JSON, such as build errors, are added to the summary.
+func SpawnJSONParsers(logger *logrus.Logger, reader io.Reader, outputDir string) {
+	report := parseAndStoreJSONTestOutput(logger, reader, outputDir)
+	storeJSONJunitReport(logger, outputDir, report)
+	StoreHTMLReport(logger, outputDir, report)
+}
+
+// jsonParser tracks the state of the test results while parsing the events of `go test -json` output.
+type jsonParser struct {
+	logger   *logrus.Logger
+	report   *TestReport
+	packages map[string]*PackageResult
+	tests    map[string]map[string]*TestResult
+	// logOwners maps the name of each top level test to the package whose logs are written to the log file named after
+	// the test.
+	logOwners map[string]string
+	logWriter LogWriter
+}
+
+// parseAndStoreJSONTestOutput reads test2json events and stores the output of each test in a log file named after the
+// test under outputDir, and the test results and package output in `summary.log`. When packages have tests with the same
+// name, the logs of the packages other than the first one are stored under a directory named after the package. Returns
+// the results of all the tests.
+func parseAndStoreJSONTestOutput(logger *logrus.Logger, read io.Reader, outputDir string) *TestReport {
+	parser := &jsonParser{
+		logger:    logger,
+		report:    &TestReport{},
+		packages:  make(map[string]*PackageResult),
+		tests:     make(map[string]map[string]*TestResult),
+		logOwners: make(map[string]string),
+		logWriter: LogWriter{
+			Lookup:    make(map[string]*os.File),
+			OutputDir: outputDir,
+		},
+	}
+	defer parser.logWriter.CloseFiles(logger)
+
+	var err error
+
+	reader := bufio.NewReader(read)
+
+	for {
+		var data string
+
+		data, err = reader.ReadString('\n')
+		if len(data) == 0 && err == io.EOF {
+			break
+		}
+
+		data = strings.TrimSuffix(data, "\n")
+
+		var event TestEvent
+		if jsonErr := json.Unmarshal([]byte(data), &event); jsonErr != nil || event.Action == "" {
+			parser.report.Output = append(parser.report.Output, data)
+			parser.writeLog("summary", data)
+		} else {
+			parser.handleEvent(&event)
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	if err != io.EOF {
+		logger.Fatalf("Error reading from Reader: %s", err)
+	}
+
+	return parser.report
+}
+
+func (parser *jsonParser) handleEvent(event *TestEvent) {
+	if event.Package == "" {
+		if event.Output != "" {
+			line := strings.TrimSuffix(event.Output, "\n")
+			parser.report.Output = append(parser.report.Output, line)
+			parser.writeLog("summary", line)
+		}
+
+		return
+	}
+
+	pkg := parser.getOrCreatePackage(event.Package)
+
+	switch event.Action {
+	case "run":
+		parser.getOrCreateTest(pkg, event.Test).Start = event.Time
+
+	case "output", "build-output":
+		line := strings.TrimSuffix(event.Output, "\n")
+
+		if event.Test == "" {
+			pkg.Output = append(pkg.Output, line)
+			parser.writeLog("summary", line)
+
+			return
+		}
+
+		test := parser.getOrCreateTest(pkg, parser.testForOutput(event))
+		test.Output = append(test.Output, line)
+		parser.writeLog(parser.logName(pkg, test), line)
+
+		if IsPanicLine(line) {
+			parser.writeLog("summary", line)
+		}
+
+	case ResultPass, ResultFail, ResultSkip:
+		elapsed := time.Duration(event.Elapsed * float64(time.Second))
+
+		if event.Test == "" {
+			pkg.Result, pkg.Elapsed = event.Action, elapsed
+			return
+		}
+
+		test := parser.getOrCreateTest(pkg, event.Test)
+		test.Result, test.Elapsed = event.Action, elapsed
+
+		indent := strings.Repeat("    ", strings.Count(test.Name, "/"))
+		parser.writeLog("summary", fmt.Sprintf("%s--- %s: %s (%.2fs)", indent, strings.ToUpper(event.Action), test.Name, elapsed.Seconds()))
+
+	case "build-fail":
+		pkg.Result = ResultFail
+	}
+}
+
+// testForOutput returns the name of the test that the output event belongs to. go test attributes output written
+// directly to stdout, such as the output of the terratest logger, to the test that most recently started or continued,
+// which is wrong when parallel tests log at the same time. Terratest log lines start with the name of the test that
+// logged them, so those are attributed to that test instead.
+func (parser *jsonParser) testForOutput(event *TestEvent) string {
+	name, rest, found := strings.Cut(event.Output, " ")
+	if !found || name == event.Test {
+		return event.Test
+	}
+
+	if _, ok := parser.tests[event.Package][name]; !ok {
+		return event.Test
+	}
+
+	timestamp, _, _ := strings.Cut(rest, " ")
+	if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+		return event.Test
+	}
+
+	return name
+}
+
+// logName returns the name of the log file for the test. Tests are logged to a file named after them, unless a test with
+// the same top level name was already seen in another package, in which case the file is put in a directory named after
+// the package so that the logs of both tests are not interleaved in the same file.
+func (parser *jsonParser) logName(pkg *PackageResult, test *TestResult) string {
+	topLevel, _, _ := strings.Cut(test.Name, "/")
+
+	owner, ok := parser.logOwners[topLevel]
+	if !ok {
+		parser.logOwners[topLevel] = pkg.Name
+		return test.Name
+	}
+
+	if owner == pkg.Name {
+		return test.Name
+	}
+
+	return pkg.Name + "/" + test.Name
+}
+
+func (parser *jsonParser) getOrCreatePackage(name string) *PackageResult {
+	pkg, ok := parser.packages[name]
+	if !ok {
+		pkg = &PackageResult{Name: name}
+		parser.packages[name] = pkg
+		parser.tests[name] = make(map[string]*TestResult)
+		parser.report.Packages = append(parser.report.Packages, pkg)
+	}
+
+	return pkg
+}
+
+// getOrCreateTest returns the result of the test with the given name, adding it to its parent test, or to the package
+// for top level tests, when it is first seen.
+func (parser *jsonParser) getOrCreateTest(pkg *PackageResult, name string) *TestResult {
+	tests := parser.tests[pkg.Name]
+
+	test, ok := tests[name]
+	if ok {
+		return test
+	}
+
+	test = &TestResult{Name: name}
+	addTest(pkg, tests, test)
+
+	return test
+}
+
+// addTest adds the test to its parent test in tests, or to the package for top level tests, and to tests.
+func addTest(pkg *PackageResult, tests map[string]*TestResult, test *TestResult) {
+	tests[test.Name] = test
+
+	// Subtest names can contain slashes themselves, so look for the longest prefix that is a known test
+	for index := strings.LastIndex(test.Name, "/"); index > 0; index = strings.LastIndex(test.Name[:index], "/") {
+		if parent, ok := tests[test.Name[:index]]; ok {
+			parent.Subtests = append(parent.Subtests, test)
+			return
+		}
+	}
+
+	pkg.Tests = append(pkg.Tests, test)
+}
+
+func (parser *jsonParser) writeLog(testName string, text string) {
+	if err := parser.logWriter.WriteLog(parser.logger, testName, text); err != nil {
+		parser.logger.Errorf("Error writing log for test %s: %s", testName, err)
+	}
+}
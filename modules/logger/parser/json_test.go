@@ -0,0 +1,69 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpawnJSONParsersWithPanic(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/pkg","Test":"TestPanics"}`,
+		`{"Action":"output","Package":"example.com/pkg","Test":"TestPanics","Output":"=== RUN   TestPanics\n"}`,
+		`{"Action":"output","Package":"example.com/pkg","Test":"TestPanics","Output":"panic: runtime error: index out of range\n"}`,
+		`# example.com/other`,
+		`{"Action":"output","Package":"example.com/pkg","Output":"FAIL\texample.com/pkg\t0.012s\n"}`,
+		`{"Action":"fail","Package":"example.com/pkg","Elapsed":0.012}`,
+	}, "\n")
+
+	output := t.TempDir()
+	parser.SpawnJSONParsers(NewTestLogger(t), strings.NewReader(input), output)
+
+	testLog, err := os.ReadFile(filepath.Join(output, "TestPanics.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "=== RUN   TestPanics\npanic: runtime error: index out of range\n", string(testLog))
+
+	summary, err := os.ReadFile(filepath.Join(output, "summary.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "panic: runtime error: index out of range\n# example.com/other\nFAIL\texample.com/pkg\t0.012s\n", string(summary))
+
+	report, err := os.ReadFile(filepath.Join(output, "report.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(report), `<testsuite time="0.012" name="example.com/pkg" tests="1" failures="1" skipped="0">`)
+	assert.Contains(t, string(report), `<failure message="Did not finish" type="">panic: runtime error: index out of range</failure>`)
+}
+
+func TestSpawnJSONParsersWithSameTestInSeveralPackages(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/first","Test":"TestSame"}`,
+		`{"Action":"run","Package":"example.com/second","Test":"TestSame"}`,
+		`{"Action":"output","Package":"example.com/first","Test":"TestSame","Output":"first output\n"}`,
+		`{"Action":"output","Package":"example.com/second","Test":"TestSame","Output":"second output\n"}`,
+		`{"Action":"run","Package":"example.com/second","Test":"TestSame/Sub"}`,
+		`{"Action":"output","Package":"example.com/second","Test":"TestSame/Sub","Output":"second sub output\n"}`,
+		`{"Action":"pass","Package":"example.com/first","Test":"TestSame","Elapsed":0.01}`,
+		`{"Action":"pass","Package":"example.com/second","Test":"TestSame","Elapsed":0.01}`,
+	}, "\n")
+
+	output := t.TempDir()
+	parser.SpawnJSONParsers(NewTestLogger(t), strings.NewReader(input), output)
+
+	for file, expected := range map[string]string{
+		"TestSame.log":                        "first output\n",
+		"example.com/second/TestSame.log":     "second output\n",
+		"example.com/second/TestSame/Sub.log": "second sub output\n",
+	} {
+		contents, err := os.ReadFile(filepath.Join(output, filepath.FromSlash(file)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(contents), file)
+	}
+}
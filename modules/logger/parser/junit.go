@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The junit report for `go test -json` output has the same layout as the one of go-junit-report, except that skipped
+// tests are counted and subtests are nested in the testcase of their parent test.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Time       string          `xml:"time,attr"`
+	Name       string          `xml:"name,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+}
+
+type junitTestCase struct {
+	XMLName   xml.Name        `xml:"testcase"`
+	Skipped   *junitSkipped   `xml:"skipped,omitempty"`
+	Failure   *junitFailure   `xml:"failure,omitempty"`
+	Classname string          `xml:"classname,attr"`
+	Name      string          `xml:"name,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// storeJSONJunitReport converts the results of `go test -json` output into a junit report and stores it as report.xml
+// in the output directory.
+func storeJSONJunitReport(logger *logrus.Logger, outputDir string, report *TestReport) {
+	if err := EnsureDirectoryExists(logger, outputDir); err != nil {
+		logger.Errorf("Error ensuring output directory exists: %s", err)
+		return
+	}
+
+	suites := junitTestSuites{}
+
+	for _, pkg := range report.Packages {
+		suite := junitTestSuite{
+			Name:       pkg.Name,
+			Time:       formatJunitTime(pkg.Elapsed),
+			Properties: []junitProperty{{Name: "go.version", Value: runtime.Version()}},
+		}
+
+		classname := pkg.Name[strings.LastIndex(pkg.Name, "/")+1:]
+
+		for _, test := range pkg.Tests {
+			suite.TestCases = append(suite.TestCases, newJunitTestCase(&suite, classname, test))
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "\t")
+	if err != nil {
+		logger.Errorf("Error formatting junit xml report: %s", err)
+		return
+	}
+
+	filename := filepath.Join(outputDir, "report.xml")
+
+	f, err := os.Create(filename)
+	if err != nil {
+		logger.Errorf("Error making file %s for junit report", filename)
+		return
+	}
+
+	defer f.Close()
+
+	if _, err := f.WriteString(xml.Header + string(data) + "\n"); err != nil {
+		logger.Errorf("Error writing junit report %s: %s", filename, err)
+	}
+}
+
+// newJunitTestCase converts the test and its subtests into a testcase, and adds them to the counts of the suite.
+func newJunitTestCase(suite *junitTestSuite, classname string, test *TestResult) junitTestCase {
+	testCase := junitTestCase{
+		Classname: classname,
+		Name:      test.Name,
+		Time:      formatJunitTime(test.Elapsed),
+	}
+
+	suite.Tests++
+
+	switch test.Result {
+	case ResultPass:
+		// passing tests only have their name and time
+	case ResultSkip:
+		suite.Skipped++
+		testCase.Skipped = &junitSkipped{Message: strings.Join(testLogLines(test), "\n")}
+	case ResultFail:
+		suite.Failures++
+		testCase.Failure = &junitFailure{Message: "Failed", Contents: strings.Join(testLogLines(test), "\n")}
+	default:
+		suite.Failures++
+		testCase.Failure = &junitFailure{Message: "Did not finish", Contents: strings.Join(testLogLines(test), "\n")}
+	}
+
+	for _, subtest := range test.Subtests {
+		testCase.TestCases = append(testCase.TestCases, newJunitTestCase(suite, classname, subtest))
+	}
+
+	return testCase
+}
+
+// testLogLines returns the output of the test without the go test status and result lines.
+func testLogLines(test *TestResult) []string {
+	var lines []string
+
+	for _, line := range test.Output {
+		if !IsStatusLine(line) && !IsResultLine(line) {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func formatJunitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -15,12 +15,16 @@ import (
 // parserCount is the number of concurrent parsers spawned by SpawnParsers.
 const parserCount = 2
 
-// SpawnParsers will spawn the log parser and junit report parsers off of a single reader.
+// SpawnParsers will spawn the log parser and junit report parsers off of a single reader. Once both are done, the
+// html report is rendered from the junit report and the logs of each test.
 func SpawnParsers(logger *logrus.Logger, reader io.Reader, outputDir string) {
 	forkedReader, forkedWriter := io.Pipe()
 	teedReader := io.TeeReader(reader, forkedWriter)
 
-	var waitForParsers sync.WaitGroup
+	var (
+		waitForParsers sync.WaitGroup
+		report         *junitparser.Report
+	)
 
 	waitForParsers.Add(parserCount)
 
@@ -39,7 +43,9 @@ func SpawnParsers(logger *logrus.Logger, reader io.Reader, outputDir string) {
 	go func() {
 		defer waitForParsers.Done()
 
-		report, err := junitparser.Parse(forkedReader, "")
+		var err error
+
+		report, err = junitparser.Parse(forkedReader, "")
 		if err == nil {
 			storeJunitReport(logger, outputDir, report)
 		} else {
@@ -48,6 +54,10 @@ func SpawnParsers(logger *logrus.Logger, reader io.Reader, outputDir string) {
 	}()
 
 	waitForParsers.Wait()
+
+	if report != nil {
+		StoreHTMLReport(logger, outputDir, newTestReportFromJunit(logger, report, outputDir))
+	}
 }
 
 // RegEx for parsing test status lines. Pulled from jstemmer/go-junit-report
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Redacted is what registered secrets are replaced with in log output and error messages.
+const Redacted = "[REDACTED]"
+
+// secrets is the registry of the values and patterns that are redacted by Redact.
+var secrets = &secretRegistry{values: make(map[string]struct{})}
+
+type secretRegistry struct {
+	values   map[string]struct{}
+	replacer *strings.Replacer
+	patterns []*regexp.Regexp
+	mutex    sync.RWMutex
+}
+
+// RegisterSecrets registers values, such as passwords or tokens, that are replaced with Redacted in everything that is
+// logged through this package, and in the error messages of the terratest modules that include command output. Empty
+// values are ignored. The registry is global: secrets stay registered for the rest of the test run, and are redacted
+// from the logs of every test in the process, not only the one that registered them.
+func RegisterSecrets(values ...string) {
+	secrets.mutex.Lock()
+	defer secrets.mutex.Unlock()
+
+	added := false
+
+	for _, value := range values {
+		if _, ok := secrets.values[value]; value != "" && !ok {
+			secrets.values[value] = struct{}{}
+			added = true
+		}
+	}
+
+	if !added {
+		return
+	}
+
+	// strings.Replacer replaces the first matching value it finds, so longer values go first so that secrets
+	// containing other secrets are replaced entirely
+	sorted := make([]string, 0, len(secrets.values))
+	for value := range secrets.values {
+		sorted = append(sorted, value)
+	}
+
+	slices.SortFunc(sorted, func(a, b string) int { return len(b) - len(a) })
+
+	oldnew := make([]string, 0, 2*len(sorted)) //nolint:mnd // pairs of old and new values
+	for _, value := range sorted {
+		oldnew = append(oldnew, value, Redacted)
+	}
+
+	secrets.replacer = strings.NewReplacer(oldnew...)
+}
+
+// RegisterSecretPatterns registers regular expressions for secrets that are not known in advance, such as
+// `password=(\S+)`. If a pattern has capture groups, only the text of the groups is replaced with Redacted, otherwise
+// the whole match is.
+func RegisterSecretPatterns(patterns ...*regexp.Regexp) {
+	secrets.mutex.Lock()
+	defer secrets.mutex.Unlock()
+
+	secrets.patterns = append(secrets.patterns, patterns...)
+}
+
+// Redact returns the text with all the registered secrets replaced with Redacted.
+func Redact(text string) string {
+	secrets.mutex.RLock()
+	defer secrets.mutex.RUnlock()
+
+	if secrets.replacer != nil {
+		text = secrets.replacer.Replace(text)
+	}
+
+	for _, pattern := range secrets.patterns {
+		text = redactPattern(pattern, text)
+	}
+
+	return text
+}
+
+// hasSecrets returns whether any secrets or patterns are registered, so that logging can skip redaction otherwise.
+func hasSecrets() bool {
+	secrets.mutex.RLock()
+	defer secrets.mutex.RUnlock()
+
+	return secrets.replacer != nil || len(secrets.patterns) > 0
+}
+
+// redactArgs returns the format and arguments to log instead of the given ones, with the registered secrets redacted
+// from the formatted message. If no secrets are registered, the format and arguments are returned unchanged.
+func redactArgs(format string, args []any) (string, []any) {
+	if !hasSecrets() {
+		return format, args
+	}
+
+	return "%s", []any{Redact(fmt.Sprintf(format, args...))}
+}
+
+func redactPattern(pattern *regexp.Regexp, text string) string {
+	if pattern.NumSubexp() == 0 {
+		return pattern.ReplaceAllLiteralString(text, Redacted)
+	}
+
+	var builder strings.Builder
+
+	last := 0
+
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		for group := 1; group <= pattern.NumSubexp(); group++ {
+			start, end := match[2*group], match[2*group+1]
+			if start < last || start < 0 {
+				continue
+			}
+
+			builder.WriteString(text[last:start])
+			builder.WriteString(Redacted)
+
+			last = end
+		}
+	}
+
+	builder.WriteString(text[last:])
+
+	return builder.String()
+}
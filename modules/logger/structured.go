@@ -0,0 +1,312 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// modulePackagePrefix is the package path prefix of the terratest modules, used to find the module that logged an entry.
+const modulePackagePrefix = "github.com/gruntwork-io/terratest/modules/"
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	// LevelDebug is used for detailed information that is only useful when debugging a test.
+	LevelDebug Level = iota
+	// LevelInfo is used for regular progress information. This is the level of Logf.
+	LevelInfo
+	// LevelWarn is used for problems that do not fail the test, such as failing to clean up a resource.
+	LevelWarn
+)
+
+// String returns the lower case name of the level, such as "info".
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return fmt.Sprintf("level(%d)", int(level))
+	}
+}
+
+// MarshalText encodes the level as its name, so that it shows up as a string in JSON records.
+func (level Level) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+// Fields are key-value pairs attached to log entries, such as the command being run or its working directory.
+type Fields map[string]any
+
+// Entry is a single structured log record.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Fields  Fields    `json:"fields,omitempty"`
+	Test    string    `json:"test,omitempty"`
+	Source  string    `json:"source,omitempty"`
+	Module  string    `json:"module,omitempty"`
+	Message string    `json:"msg"`
+	Level   Level     `json:"level"`
+}
+
+// StructuredTestLogger is implemented by TestLogger implementations that can log structured entries. When a Logger
+// wraps a StructuredTestLogger, all its methods, including Logf, log through Log with the level, the fields and the
+// source of the entry. Other TestLogger implementations ignore the fields, and get the level as a message prefix.
+type StructuredTestLogger interface {
+	TestLogger
+	Log(t testing.TestingT, entry Entry)
+}
+
+// WithFields returns a copy of the logger that attaches the given fields, in addition to the fields of this logger, to
+// everything it logs. Like the other methods, this can be called on a nil Logger, in which case the returned logger
+// logs to Default.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	result := &Logger{}
+
+	if l != nil {
+		result.l = l.l
+		result.fields = maps.Clone(l.fields)
+	}
+
+	if result.fields == nil {
+		result.fields = make(Fields, len(fields))
+	}
+
+	maps.Copy(result.fields, fields)
+
+	return result
+}
+
+// Debugf logs the given format and arguments at debug level. Loggers that are not structured log the message with a
+// "DEBUG: " prefix.
+func (l *Logger) Debugf(t testing.TestingT, format string, args ...any) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	inner, fields := l.resolve()
+	format, args = redactArgs(format, args)
+
+	if structured, ok := inner.(StructuredTestLogger); ok {
+		structured.Log(t, newEntry(t, LevelDebug, fields, format, args))
+		return
+	}
+
+	inner.Logf(t, "DEBUG: "+format, args...)
+}
+
+// Infof logs the given format and arguments at info level. This is the same as Logf.
+func (l *Logger) Infof(t testing.TestingT, format string, args ...any) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	inner, fields := l.resolve()
+	format, args = redactArgs(format, args)
+
+	if structured, ok := inner.(StructuredTestLogger); ok {
+		structured.Log(t, newEntry(t, LevelInfo, fields, format, args))
+		return
+	}
+
+	inner.Logf(t, format, args...)
+}
+
+// Warnf logs the given format and arguments at warning level. Loggers that are not structured log the message with a
+// "WARNING: " prefix.
+func (l *Logger) Warnf(t testing.TestingT, format string, args ...any) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	inner, fields := l.resolve()
+	format, args = redactArgs(format, args)
+
+	if structured, ok := inner.(StructuredTestLogger); ok {
+		structured.Log(t, newEntry(t, LevelWarn, fields, format, args))
+		return
+	}
+
+	inner.Logf(t, "WARNING: "+format, args...)
+}
+
+// resolve returns the TestLogger to log with and the fields to attach. Loggers without an implementation log to
+// Default, with the fields of both.
+func (l *Logger) resolve() (TestLogger, Fields) {
+	if l != nil && l.l != nil {
+		return l.l, l.fields
+	}
+
+	var fields Fields
+	if l != nil {
+		fields = l.fields
+	}
+
+	if Default == nil || Default.l == nil {
+		return terratestLogger{}, fields
+	}
+
+	if len(Default.fields) == 0 {
+		return Default.l, fields
+	}
+
+	merged := maps.Clone(Default.fields)
+	maps.Copy(merged, fields)
+
+	return Default.l, merged
+}
+
+// newEntry creates an entry for the message that was logged by the caller of the Logger method calling newEntry.
+func newEntry(t testing.TestingT, level Level, fields Fields, format string, args []any) Entry {
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+		Fields:  fields,
+	}
+
+	if t != nil {
+		entry.Test = t.Name()
+	}
+
+	entry.Source, entry.Module = caller(callDepthDirect)
+
+	return entry
+}
+
+// caller returns the file:line and the terratest module, such as "terraform", of the function callDepth stack frames up
+// from the function that called caller. The module is empty for code outside of the terratest modules.
+func caller(callDepth int) (source string, module string) {
+	pc, file, line, ok := runtime.Caller(callDepth + 1)
+	if !ok {
+		return "???:1", ""
+	}
+
+	if index := strings.LastIndexAny(file, `/\`); index >= 0 {
+		file = file[index+1:]
+	}
+
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		if name, found := strings.CutPrefix(fn.Name(), modulePackagePrefix); found {
+			module, _, _ = strings.Cut(name, ".")
+			module, _, _ = strings.Cut(module, "/")
+			module = strings.TrimSuffix(module, "_test")
+		}
+	}
+
+	return fmt.Sprintf("%s:%d", file, line), module
+}
+
+// JSON logs every entry as a JSON object on a single line to stdout. See JSONLogger for the format.
+var JSON = New(NewJSONLogger(nil))
+
+// JSONLogger is a StructuredTestLogger that writes every entry as a JSON object on a single line, for example:
+//
+//	{"time":"2024-01-02T15:04:05.123Z","fields":{"command":"terraform"},"test":"TestApply","source":"cmd.go:221","module":"terraform","msg":"Running command terraform with args [apply]","level":"info"}
+type JSONLogger struct {
+	writer io.Writer
+	mutex  sync.Mutex
+
+	// MinLevel is the lowest level that is logged. Entries with a lower level are discarded.
+	MinLevel Level
+}
+
+// NewJSONLogger creates a JSONLogger that writes to the given writer, or to stdout if the writer is nil.
+func NewJSONLogger(writer io.Writer) *JSONLogger {
+	return &JSONLogger{writer: writer, MinLevel: LevelDebug}
+}
+
+// Logf logs the given format and arguments at info level.
+func (logger *JSONLogger) Logf(t testing.TestingT, format string, args ...any) {
+	entry := Entry{Time: time.Now(), Level: LevelInfo, Message: fmt.Sprintf(format, args...)}
+	if t != nil {
+		entry.Test = t.Name()
+	}
+
+	entry.Source, entry.Module = caller(1)
+
+	logger.Log(t, entry)
+}
+
+// Log writes the entry as a JSON object on a single line.
+func (logger *JSONLogger) Log(_ testing.TestingT, entry Entry) {
+	if entry.Level < logger.MinLevel {
+		return
+	}
+
+	if hasSecrets() {
+		entry.Message = Redact(entry.Message)
+		entry.Fields = redactFields(entry.Fields)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		// Fall back to the string representation of fields that can't be encoded, such as functions or channels
+		entry.Fields = stringifyFields(entry.Fields)
+
+		if data, err = json.Marshal(entry); err != nil {
+			return
+		}
+	}
+
+	data = append(data, '\n')
+
+	if logger.writer == nil {
+		MutexStdout.Lock()
+		defer MutexStdout.Unlock()
+
+		_, _ = os.Stdout.Write(data)
+
+		return
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	_, _ = logger.writer.Write(data)
+}
+
+func stringifyFields(fields Fields) Fields {
+	result := make(Fields, len(fields))
+	for key, value := range fields {
+		result[key] = fmt.Sprint(value)
+	}
+
+	return result
+}
+
+// redactFields returns a copy of the fields with the registered secrets redacted from the string values.
+func redactFields(fields Fields) Fields {
+	result := make(Fields, len(fields))
+
+	for key, value := range fields {
+		switch value := value.(type) {
+		case string:
+			result[key] = Redact(value)
+		case []string:
+			redacted := make([]string, len(value))
+			for i, s := range value {
+				redacted[i] = Redact(s)
+			}
+
+			result[key] = redacted
+		default:
+			result[key] = value
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,110 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonRecord struct {
+	Fields map[string]any `json:"fields"`
+	Time   string         `json:"time"`
+	Test   string         `json:"test"`
+	Source string         `json:"source"`
+	Module string         `json:"module"`
+	Msg    string         `json:"msg"`
+	Level  string         `json:"level"`
+}
+
+func decodeRecords(t *testing.T, output string) []jsonRecord {
+	t.Helper()
+
+	var records []jsonRecord
+
+	for line := range strings.SplitSeq(strings.TrimSpace(output), "\n") {
+		var record jsonRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestJSONLogger(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+
+	jsonLogger := logger.NewJSONLogger(&buffer)
+	l := logger.New(jsonLogger).WithFields(logger.Fields{"region": "us-east-1"})
+
+	l.Logf(t, "hello %s", "world")
+	l.Debugf(t, "details")
+	l.WithFields(logger.Fields{"attempt": 2, "callback": func() {}}).Warnf(t, "careful")
+
+	jsonLogger.MinLevel = logger.LevelInfo
+	l.Debugf(t, "not logged")
+	l.Infof(t, "logged")
+
+	records := decodeRecords(t, buffer.String())
+	require.Len(t, records, 4)
+
+	assert.Equal(t, "hello world", records[0].Msg)
+	assert.Equal(t, "info", records[0].Level)
+	assert.Equal(t, t.Name(), records[0].Test)
+	assert.Regexp(t, `^structured_test\.go:[0-9]+$`, records[0].Source)
+	assert.Equal(t, "logger", records[0].Module)
+	assert.Equal(t, map[string]any{"region": "us-east-1"}, records[0].Fields)
+	assert.NotEmpty(t, records[0].Time)
+
+	assert.Equal(t, "debug", records[1].Level)
+	assert.Equal(t, "warn", records[2].Level)
+	assert.Equal(t, "careful", records[2].Msg)
+	assert.Equal(t, "2", records[2].Fields["attempt"])
+	assert.Equal(t, "us-east-1", records[2].Fields["region"])
+	assert.Equal(t, "logged", records[3].Msg)
+}
+
+func TestJSONLoggerModuleFields(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+
+	shell.RunCommand(t, shell.Command{
+		Command:    "echo",
+		Args:       []string{"hello"},
+		WorkingDir: t.TempDir(),
+		Logger:     logger.New(logger.NewJSONLogger(&buffer)),
+	})
+
+	records := decodeRecords(t, buffer.String())
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "shell", records[0].Module)
+	assert.Regexp(t, `^command\.go:[0-9]+$`, records[0].Source)
+	assert.Equal(t, "echo", records[0].Fields["command"])
+	assert.NotEmpty(t, records[0].Fields["working_dir"])
+
+	assert.Equal(t, "hello", records[1].Msg)
+	assert.Equal(t, "stdout", records[1].Fields["stream"])
+}
+
+func TestLeveledTextLogger(t *testing.T) {
+	t.Parallel()
+
+	c := &customLogger{}
+	l := logger.New(c).WithFields(logger.Fields{"ignored": true})
+
+	l.Debugf(t, "one")
+	l.Infof(t, "two")
+	l.Warnf(t, "three")
+
+	assert.Equal(t, []string{"DEBUG: one", "two", "WARNING: three"}, c.logs)
+}
@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// globalHooks holds the hooks set with SetGlobalHooks.
+var globalHooks atomic.Pointer[Hooks]
+
+// Attempt records a single attempt of an action run by the retry functions.
+type Attempt struct {
+	// Time the attempt started
+	Start time.Time
+
+	// Error returned by the action, or nil if the attempt succeeded
+	Err error
+
+	// Output returned by the action, such as stdout/stderr of a command
+	Output any
+
+	// Number of the attempt, starting at 1
+	Number int
+
+	// How long the action ran for
+	Duration time.Duration
+}
+
+// Hooks are optional callbacks the retry functions invoke while retrying an action, for example to emit metrics about
+// flaky cloud APIs. Hooks are called from the goroutine running the action, so they should return quickly.
+type Hooks struct {
+	// OnAttempt is called after every attempt, whether it succeeded or not.
+	OnAttempt func(description string, attempt Attempt)
+
+	// OnGiveUp is called when the action is not retried anymore because it ran out of retries or its deadline passed.
+	// It is not called for errors that are fatal or not retryable.
+	OnGiveUp func(description string, err RetryError)
+}
+
+// SetGlobalHooks sets hooks that are invoked for every action run by the retry functions in this package, in addition
+// to the hooks of the policy. This is meant to be called once, such as from TestMain, to collect metrics across a whole
+// test suite. Pass nil to remove the hooks.
+func SetGlobalHooks(hooks *Hooks) {
+	globalHooks.Store(hooks)
+}
+
+// RetryError is returned when the retry functions give up on an action. It wraps the MaxRetriesExceeded or
+// TimeoutExceeded error that explains why, along with the errors of all the attempts, so errors.Is and errors.As match
+// any of them.
+type RetryError struct {
+	// The MaxRetriesExceeded or TimeoutExceeded error
+	Err error
+
+	// All the attempts of the action, in order
+	Attempts []Attempt
+}
+
+func (err RetryError) Error() string {
+	lines := []string{err.Err.Error()}
+
+	for _, attempt := range err.Attempts {
+		lines = append(lines, fmt.Sprintf("attempt %d (%s): %v", attempt.Number, attempt.Duration, attempt.Err))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the reason for giving up followed by the errors of all the attempts.
+func (err RetryError) Unwrap() []error {
+	errs := []error{err.Err}
+
+	for _, attempt := range err.Attempts {
+		if attempt.Err != nil {
+			errs = append(errs, attempt.Err)
+		}
+	}
+
+	return errs
+}
+
+// onAttempt calls the OnAttempt hooks of the policy and the global hooks.
+func onAttempt(policy *Policy, description string, attempt Attempt) {
+	for _, hooks := range []*Hooks{policy.Hooks, globalHooks.Load()} {
+		if hooks != nil && hooks.OnAttempt != nil {
+			hooks.OnAttempt(description, attempt)
+		}
+	}
+}
+
+// onGiveUp calls the OnGiveUp hooks of the policy and the global hooks.
+func onGiveUp(policy *Policy, description string, err RetryError) {
+	for _, hooks := range []*Hooks{policy.Hooks, globalHooks.Load()} {
+		if hooks != nil && hooks.OnGiveUp != nil {
+			hooks.OnGiveUp(description, err)
+		}
+	}
+}
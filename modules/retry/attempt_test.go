@@ -0,0 +1,87 @@
+package retry_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+// Not parallel, as the global hooks see the retries of all the tests in this package.
+func TestRetryErrorRecordsAttempts(t *testing.T) {
+	var (
+		mutex      sync.Mutex
+		attempts   []retry.Attempt
+		globalSeen []string
+		gaveUp     []retry.RetryError
+	)
+
+	retry.SetGlobalHooks(&retry.Hooks{
+		OnAttempt: func(description string, _ retry.Attempt) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			globalSeen = append(globalSeen, description)
+		},
+	})
+	t.Cleanup(func() { retry.SetGlobalHooks(nil) })
+
+	policy := retry.ConstantPolicy(2, time.Millisecond)
+	policy.Hooks = &retry.Hooks{
+		OnAttempt: func(_ string, attempt retry.Attempt) { attempts = append(attempts, attempt) },
+		OnGiveUp:  func(_ string, err retry.RetryError) { gaveUp = append(gaveUp, err) },
+	}
+
+	before := time.Now()
+	count := 0
+	_, err := retry.DoWithPolicyContextE(t, t.Context(), "flaky", policy, func() (string, error) {
+		count++
+		return fmt.Sprintf("output %d", count), fmt.Errorf("failure %d", count)
+	})
+
+	var retryErr retry.RetryError
+	require.ErrorAs(t, err, &retryErr)
+	require.ErrorIs(t, err, retry.MaxRetriesExceeded{Description: "flaky", MaxRetries: 2})
+	assert.Equal(t, "'flaky' unsuccessful after 2 retries", retryErr.Err.Error())
+	assert.Contains(t, err.Error(), "attempt 3")
+	assert.Contains(t, err.Error(), "failure 3")
+
+	require.Len(t, retryErr.Attempts, 3)
+
+	for i, attempt := range retryErr.Attempts {
+		assert.Equal(t, i+1, attempt.Number)
+		assert.Equal(t, fmt.Sprintf("output %d", i+1), attempt.Output)
+		require.EqualError(t, attempt.Err, fmt.Sprintf("failure %d", i+1))
+		assert.False(t, attempt.Start.Before(before))
+		assert.GreaterOrEqual(t, attempt.Duration, time.Duration(0))
+		require.ErrorIs(t, err, attempt.Err)
+	}
+
+	assert.Equal(t, retryErr.Attempts, attempts)
+	assert.Equal(t, []retry.RetryError{retryErr}, gaveUp)
+
+	mutex.Lock()
+	assert.Equal(t, []string{"flaky", "flaky", "flaky"}, globalSeen)
+	mutex.Unlock()
+
+	attempts, gaveUp = nil, nil
+	out, err := retry.DoWithPolicyContextE(t, t.Context(), "succeeds", policy, func() (string, error) { return "done", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "done", out)
+	require.Len(t, attempts, 1)
+	require.NoError(t, attempts[0].Err)
+	assert.Empty(t, gaveUp)
+
+	_, err = retry.DoWithPolicyContextE(t, t.Context(), "fatal", policy, func() (string, error) {
+		return "", retry.FatalError{Underlying: errors.New("fatal")}
+	})
+	require.ErrorAs(t, err, &retry.FatalError{})
+	require.NotErrorAs(t, err, &retry.RetryError{})
+	assert.Empty(t, gaveUp)
+}
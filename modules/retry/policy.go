@@ -0,0 +1,306 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"regexp"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// defaultBackoffMultiplier is the factor ExponentialBackoff grows by if no Multiplier is set.
+const defaultBackoffMultiplier = 2
+
+// decorrelatedJitterFactor is how much DecorrelatedJitterBackoff can grow the previous delay by.
+const decorrelatedJitterFactor = 3
+
+// Policy decides whether and when a failed action is retried by the DoWithPolicy functions. The action is retried
+// until MaxRetries retries have been done or Deadline has passed, whichever comes first. If Deadline is set and
+// MaxRetries is 0, the number of retries is not limited. If neither is set, the action is attempted only once.
+type Policy struct {
+	// Backoff computes the time to sleep before each retry. Defaults to retrying immediately.
+	Backoff Backoff
+
+	// Classifier decides whether an error is retried. Defaults to retrying every error. A FatalError is never retried.
+	Classifier Classifier
+
+	// Maximum number of retries after the first attempt.
+	MaxRetries int
+
+	// Maximum time since the first attempt started after which no more attempts are started. A running attempt is not
+	// interrupted.
+	Deadline time.Duration
+
+	// Hooks called while retrying, in addition to the ones set with SetGlobalHooks
+	Hooks *Hooks
+}
+
+// Backoff computes the time to sleep before a retry.
+type Backoff interface {
+	// Delay returns the time to sleep before the given retry, starting at 1, given the time slept before the previous
+	// retry, which is 0 before the first one.
+	Delay(retry int, previous time.Duration) time.Duration
+}
+
+// Classifier returns true if an action that failed with the given error should be retried, or false if the error is
+// fatal and should be returned immediately.
+type Classifier func(err error) bool
+
+// ConstantBackoff sleeps for the same amount of time before every retry.
+type ConstantBackoff time.Duration
+
+// Delay implements Backoff.
+func (backoff ConstantBackoff) Delay(int, time.Duration) time.Duration {
+	return time.Duration(backoff)
+}
+
+// ExponentialBackoff sleeps for Initial before the first retry and Multiplier times longer before each following
+// retry, up to Max.
+type ExponentialBackoff struct {
+	// Time to sleep before the first retry
+	Initial time.Duration
+
+	// Maximum time to sleep before a retry. No maximum if 0.
+	Max time.Duration
+
+	// Factor the time to sleep grows by after each retry. Defaults to 2.
+	Multiplier float64
+}
+
+// Delay implements Backoff.
+func (backoff ExponentialBackoff) Delay(retry int, previous time.Duration) time.Duration {
+	multiplier := backoff.Multiplier
+	if multiplier == 0 {
+		multiplier = defaultBackoffMultiplier
+	}
+
+	delay := backoff.Initial
+	if retry > 1 {
+		delay = time.Duration(float64(previous) * multiplier)
+	}
+
+	return capDelay(delay, backoff.Max)
+}
+
+// DecorrelatedJitterBackoff sleeps for a random time between Base and three times the previous sleep, up to Max. This
+// spreads out the retries of many concurrent clients, such as parallel tests hitting the same rate-limited cloud API.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/.
+type DecorrelatedJitterBackoff struct {
+	// Minimum time to sleep before a retry
+	Base time.Duration
+
+	// Maximum time to sleep before a retry. No maximum if 0.
+	Max time.Duration
+}
+
+// Delay implements Backoff.
+func (backoff DecorrelatedJitterBackoff) Delay(_ int, previous time.Duration) time.Duration {
+	upper := max(previous, backoff.Base) * decorrelatedJitterFactor
+	if upper <= backoff.Base {
+		return capDelay(backoff.Base, backoff.Max)
+	}
+
+	return capDelay(backoff.Base+rand.N(upper-backoff.Base), backoff.Max) //nolint:gosec // jitter does not need a secure random number
+}
+
+// ConstantPolicy returns a policy that retries up to maxRetries times, sleeping for sleepBetweenRetries before each
+// retry. This is the policy used by the DoWithRetry functions.
+func ConstantPolicy(maxRetries int, sleepBetweenRetries time.Duration) *Policy {
+	return &Policy{MaxRetries: maxRetries, Backoff: ConstantBackoff(sleepBetweenRetries)}
+}
+
+// ExponentialPolicy returns a policy that retries up to maxRetries times, sleeping for initial before the first retry
+// and twice as long before each following retry, up to maxSleep.
+func ExponentialPolicy(maxRetries int, initial time.Duration, maxSleep time.Duration) *Policy {
+	return &Policy{MaxRetries: maxRetries, Backoff: ExponentialBackoff{Initial: initial, Max: maxSleep}}
+}
+
+// DecorrelatedJitterPolicy returns a policy that retries up to maxRetries times, sleeping for a random time between
+// base and three times the previous sleep, up to maxSleep, before each retry.
+func DecorrelatedJitterPolicy(maxRetries int, base time.Duration, maxSleep time.Duration) *Policy {
+	return &Policy{MaxRetries: maxRetries, Backoff: DecorrelatedJitterBackoff{Base: base, Max: maxSleep}}
+}
+
+// DeadlinePolicy returns a policy that retries for as long as the deadline since the first attempt has not passed,
+// sleeping for sleepBetweenRetries before each retry.
+func DeadlinePolicy(deadline time.Duration, sleepBetweenRetries time.Duration) *Policy {
+	return &Policy{Deadline: deadline, Backoff: ConstantBackoff(sleepBetweenRetries)}
+}
+
+// DoWithPolicyContext runs the specified action. If it returns a string, return that string. If it returns an error
+// that is a FatalError or that the policy's classifier does not consider retryable, fail the test immediately.
+// Otherwise, retry the action as the policy allows, and fail the test if it gives up. The ctx parameter supports
+// cancellation and timeouts.
+func DoWithPolicyContext(t testing.TestingT, ctx context.Context, actionDescription string, policy *Policy, action func() (string, error)) string {
+	out, err := DoWithPolicyContextE(t, ctx, actionDescription, policy, action)
+	require.NoError(t, err)
+
+	return out
+}
+
+// DoWithPolicyContextE runs the specified action. If it returns a string, return that string. If it returns an error
+// that is a FatalError or that the policy's classifier does not consider retryable, return that error immediately.
+// Otherwise, retry the action as the policy allows. If the policy gives up, return a RetryError that wraps a
+// MaxRetriesExceeded error, or a TimeoutExceeded error if its deadline passed. The ctx parameter supports cancellation
+// and timeouts.
+func DoWithPolicyContextE(t testing.TestingT, ctx context.Context, actionDescription string, policy *Policy, action func() (string, error)) (string, error) {
+	out, err := DoWithPolicyInterfaceContextE(t, ctx, actionDescription, policy, func() (any, error) { return action() })
+
+	return out.(string), err
+}
+
+// DoWithPolicyInterfaceContext runs the specified action. If it returns a value, return that value. If it returns an
+// error that is a FatalError or that the policy's classifier does not consider retryable, fail the test immediately.
+// Otherwise, retry the action as the policy allows, and fail the test if it gives up. The ctx parameter supports
+// cancellation and timeouts.
+func DoWithPolicyInterfaceContext(t testing.TestingT, ctx context.Context, actionDescription string, policy *Policy, action func() (any, error)) any {
+	out, err := DoWithPolicyInterfaceContextE(t, ctx, actionDescription, policy, action)
+	require.NoError(t, err)
+
+	return out
+}
+
+// DoWithPolicyInterfaceContextE runs the specified action. If it returns a value, return that value. If it returns an
+// error that is a FatalError or that the policy's classifier does not consider retryable, return that error
+// immediately. Otherwise, retry the action as the policy allows. If the policy gives up, return a RetryError with all
+// the attempts that wraps a MaxRetriesExceeded error, or a TimeoutExceeded error if its deadline passed. A nil policy
+// attempts the action once. The ctx parameter supports cancellation and timeouts.
+func DoWithPolicyInterfaceContextE(t testing.TestingT, ctx context.Context, actionDescription string, policy *Policy, action func() (any, error)) (any, error) {
+	if policy == nil {
+		policy = &Policy{}
+	}
+
+	var (
+		output   any
+		err      error
+		delay    time.Duration
+		attempts []Attempt
+	)
+
+	start := time.Now()
+
+	for retries := 0; ; retries++ {
+		if err := ctx.Err(); err != nil {
+			return output, err
+		}
+
+		logger.Default.Logf(t, "%s", actionDescription)
+
+		attempt := Attempt{Number: retries + 1, Start: time.Now()}
+		output, err = action()
+		attempt.Duration, attempt.Err, attempt.Output = time.Since(attempt.Start), err, output
+		attempts = append(attempts, attempt)
+		onAttempt(policy, actionDescription, attempt)
+
+		if err == nil {
+			return output, nil
+		}
+
+		var fatalErr FatalError
+		if errors.As(err, &fatalErr) {
+			logger.Default.Logf(t, "Returning due to fatal error: %v", err)
+
+			return output, err
+		}
+
+		if policy.Classifier != nil && !policy.Classifier(err) {
+			logger.Default.Logf(t, "Returning due to error that is not retryable: %v", err)
+
+			return output, err
+		}
+
+		if retries >= policy.MaxRetries && (policy.MaxRetries > 0 || policy.Deadline == 0) {
+			logger.Default.Logf(t, "%s returned an error: %s. Giving up after %d retries.", actionDescription, err.Error(), retries)
+
+			return output, giveUp(policy, actionDescription, MaxRetriesExceeded{Description: actionDescription, MaxRetries: policy.MaxRetries}, attempts)
+		}
+
+		if policy.Backoff != nil {
+			delay = policy.Backoff.Delay(retries+1, delay)
+		}
+
+		if policy.Deadline > 0 && time.Since(start)+delay > policy.Deadline {
+			logger.Default.Logf(t, "%s returned an error: %s. Giving up as the next attempt would start after the deadline of %s.", actionDescription, err.Error(), policy.Deadline)
+
+			return output, giveUp(policy, actionDescription, TimeoutExceeded{Description: actionDescription, Timeout: policy.Deadline}, attempts)
+		}
+
+		logger.Default.Logf(t, "%s returned an error: %s. Sleeping for %s and will try again.", actionDescription, err.Error(), delay)
+
+		select {
+		case <-time.After(delay):
+			// Continue to next retry
+		case <-ctx.Done():
+			return output, ctx.Err()
+		}
+	}
+}
+
+// DoWithRetryableErrorsPolicyContext runs the specified action. If it returns a value, return that value. If it
+// returns an error, check if error message or the string output from the action (which is often stdout/stderr from
+// running some command) matches any of the regular expressions in the specified retryableErrors map. If there is a
+// match, retry the action as the policy allows. If there is no match, fail the test immediately. The ctx parameter
+// supports cancellation and timeouts.
+func DoWithRetryableErrorsPolicyContext(t testing.TestingT, ctx context.Context, actionDescription string, retryableErrors map[string]string, policy *Policy, action func() (string, error)) string {
+	out, err := DoWithRetryableErrorsPolicyContextE(t, ctx, actionDescription, retryableErrors, policy, action)
+	require.NoError(t, err)
+
+	return out
+}
+
+// DoWithRetryableErrorsPolicyContextE runs the specified action. If it returns a value, return that value. If it
+// returns an error, check if error message or the string output from the action (which is often stdout/stderr from
+// running some command) matches any of the regular expressions in the specified retryableErrors map. If there is a
+// match, retry the action as the policy allows. If there is no match, return that error immediately, wrapped in a
+// FatalError. If the policy gives up, return a RetryError that wraps a MaxRetriesExceeded or TimeoutExceeded error.
+// The ctx parameter supports cancellation and timeouts.
+func DoWithRetryableErrorsPolicyContextE(t testing.TestingT, ctx context.Context, actionDescription string, retryableErrors map[string]string, policy *Policy, action func() (string, error)) (string, error) {
+	retryableErrorsRegexp := map[*regexp.Regexp]string{}
+
+	for errorStr, errorMessage := range retryableErrors {
+		errorRegex, err := regexp.Compile(errorStr)
+		if err != nil {
+			return "", FatalError{Underlying: err}
+		}
+
+		retryableErrorsRegexp[errorRegex] = errorMessage
+	}
+
+	return DoWithPolicyContextE(t, ctx, actionDescription, policy, func() (string, error) {
+		output, err := action()
+		if err == nil {
+			return output, nil
+		}
+
+		for errorRegexp, errorMessage := range retryableErrorsRegexp {
+			if errorRegexp.MatchString(output) || errorRegexp.MatchString(err.Error()) {
+				logger.Default.Logf(t, "'%s' failed with the error '%s' but this error was expected and warrants a retry. Further details: %s\n", actionDescription, err.Error(), errorMessage)
+				return output, err
+			}
+		}
+
+		return output, FatalError{Underlying: err}
+	})
+}
+
+// giveUp returns a RetryError with the given reason and attempts, after calling the OnGiveUp hooks.
+func giveUp(policy *Policy, description string, reason error, attempts []Attempt) RetryError {
+	err := RetryError{Err: reason, Attempts: attempts}
+	onGiveUp(policy, description, err)
+
+	return err
+}
+
+// capDelay returns delay, or maxDelay if it is set and delay is longer.
+func capDelay(delay time.Duration, maxDelay time.Duration) time.Duration {
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,157 @@
+package retry_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+func TestBackoffDelays(t *testing.T) {
+	t.Parallel()
+
+	delays := func(backoff retry.Backoff, retries int) []time.Duration {
+		var (
+			result   []time.Duration
+			previous time.Duration
+		)
+
+		for retry := 1; retry <= retries; retry++ {
+			previous = backoff.Delay(retry, previous)
+			result = append(result, previous)
+		}
+
+		return result
+	}
+
+	assert.Equal(t, []time.Duration{time.Second, time.Second, time.Second}, delays(retry.ConstantBackoff(time.Second), 3))
+
+	exponential := retry.ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays(exponential, 5))
+
+	exponential = retry.ExponentialBackoff{Initial: time.Second, Multiplier: 3}
+	assert.Equal(t, []time.Duration{time.Second, 3 * time.Second, 9 * time.Second}, delays(exponential, 3))
+
+	jitter := retry.DecorrelatedJitterBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+
+	previous := time.Duration(0)
+	for retry := 1; retry <= 100; retry++ {
+		delay := jitter.Delay(retry, previous)
+		assert.GreaterOrEqual(t, delay, jitter.Base)
+		assert.LessOrEqual(t, delay, jitter.Max)
+		assert.LessOrEqual(t, delay, max(previous, jitter.Base)*3)
+
+		previous = delay
+	}
+}
+
+func TestDoWithPolicy(t *testing.T) {
+	t.Parallel()
+
+	errRetryable := errors.New("retryable")
+	errPermanent := errors.New("permanent")
+
+	failingAction := func(errs ...error) (func() (string, error), *int) {
+		attempts := 0
+
+		return func() (string, error) {
+			attempts++
+			if attempts <= len(errs) {
+				return "", errs[attempts-1]
+			}
+
+			return "done", nil
+		}, &attempts
+	}
+
+	t.Run("SucceedsAfterRetries", func(t *testing.T) {
+		t.Parallel()
+
+		action, attempts := failingAction(errRetryable, errRetryable)
+		out := retry.DoWithPolicyContext(t, t.Context(), "succeeds", retry.ExponentialPolicy(5, time.Millisecond, 10*time.Millisecond), action)
+		assert.Equal(t, "done", out)
+		assert.Equal(t, 3, *attempts)
+	})
+
+	t.Run("MaxRetriesExceeded", func(t *testing.T) {
+		t.Parallel()
+
+		action, attempts := failingAction(errRetryable, errRetryable, errRetryable)
+		_, err := retry.DoWithPolicyContextE(t, t.Context(), "fails", retry.ConstantPolicy(1, time.Millisecond), action)
+		require.ErrorIs(t, err, retry.MaxRetriesExceeded{Description: "fails", MaxRetries: 1})
+		assert.Equal(t, 2, *attempts)
+	})
+
+	t.Run("ClassifierStopsOnPermanentError", func(t *testing.T) {
+		t.Parallel()
+
+		policy := retry.ConstantPolicy(5, time.Millisecond)
+		policy.Classifier = func(err error) bool { return !errors.Is(err, errPermanent) }
+
+		action, attempts := failingAction(errRetryable, errPermanent, errRetryable)
+		_, err := retry.DoWithPolicyContextE(t, t.Context(), "permanent", policy, action)
+		require.ErrorIs(t, err, errPermanent)
+		assert.Equal(t, 2, *attempts)
+	})
+
+	t.Run("FatalErrorIsNeverRetried", func(t *testing.T) {
+		t.Parallel()
+
+		policy := retry.ConstantPolicy(5, time.Millisecond)
+		policy.Classifier = func(error) bool { return true }
+
+		action, attempts := failingAction(retry.FatalError{Underlying: errRetryable})
+		_, err := retry.DoWithPolicyContextE(t, t.Context(), "fatal", policy, action)
+		require.ErrorAs(t, err, &retry.FatalError{})
+		assert.Equal(t, 1, *attempts)
+	})
+
+	t.Run("DeadlineExceeded", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		_, err := retry.DoWithPolicyContextE(t, t.Context(), "deadline", retry.DeadlinePolicy(100*time.Millisecond, 10*time.Millisecond), func() (string, error) {
+			attempts++
+			return "", errRetryable
+		})
+		require.ErrorIs(t, err, retry.TimeoutExceeded{Description: "deadline", Timeout: 100 * time.Millisecond})
+		assert.Greater(t, attempts, 2)
+		assert.LessOrEqual(t, attempts, 11)
+	})
+
+	t.Run("NilPolicyAttemptsOnce", func(t *testing.T) {
+		t.Parallel()
+
+		action, attempts := failingAction(errRetryable)
+		_, err := retry.DoWithPolicyContextE(t, t.Context(), "once", nil, action)
+		require.Error(t, err)
+		assert.Equal(t, 1, *attempts)
+	})
+}
+
+func TestDoWithRetryableErrorsPolicy(t *testing.T) {
+	t.Parallel()
+
+	retryableErrors := map[string]string{".*timeout.*": "transient"}
+
+	attempts := 0
+	out, err := retry.DoWithRetryableErrorsPolicyContextE(t, t.Context(), "retryable errors", retryableErrors, retry.DecorrelatedJitterPolicy(5, time.Millisecond, 5*time.Millisecond), func() (string, error) {
+		attempts++
+		if attempts == 1 {
+			return "request timeout", errors.New("failed")
+		}
+
+		if attempts == 2 {
+			return "access denied", errors.New("failed")
+		}
+
+		return "done", nil
+	})
+	require.ErrorAs(t, err, &retry.FatalError{})
+	assert.Equal(t, "access denied", out)
+	assert.Equal(t, 2, attempts)
+}
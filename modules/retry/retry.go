@@ -3,9 +3,7 @@ package retry
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"regexp"
 	"time"
 
 	"github.com/stretchr/testify/require"
@@ -94,7 +92,7 @@ func DoWithRetryContext(t testing.TestingT, ctx context.Context, actionDescripti
 
 // DoWithRetryE runs the specified action. If it returns a string, return that string. If it returns a FatalError, return that error
 // immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up to a maximum of
-// maxRetries retries. If maxRetries is exceeded, return a MaxRetriesExceeded error.
+// maxRetries retries. If maxRetries is exceeded, return a RetryError that wraps a MaxRetriesExceeded error.
 //
 // Deprecated: Use [DoWithRetryContextE] instead.
 func DoWithRetryE(t testing.TestingT, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) (string, error) {
@@ -103,8 +101,8 @@ func DoWithRetryE(t testing.TestingT, actionDescription string, maxRetries int,
 
 // DoWithRetryContextE runs the specified action. If it returns a string, return that string. If it returns a FatalError,
 // return that error immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up
-// to a maximum of maxRetries retries. If maxRetries is exceeded, return a MaxRetriesExceeded error. The ctx parameter
-// supports cancellation and timeouts.
+// to a maximum of maxRetries retries. If maxRetries is exceeded, return a RetryError that wraps a MaxRetriesExceeded
+// error. The ctx parameter supports cancellation and timeouts.
 func DoWithRetryContextE(t testing.TestingT, ctx context.Context, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) (string, error) {
 	out, err := DoWithRetryInterfaceContextE(t, ctx, actionDescription, maxRetries, sleepBetweenRetries, func() (any, error) { return action() })
 
@@ -135,7 +133,7 @@ func DoWithRetryInterfaceContext(t testing.TestingT, ctx context.Context, action
 
 // DoWithRetryInterfaceE runs the specified action. If it returns a value, return that value. If it returns a FatalError, return that error
 // immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up to a maximum of
-// maxRetries retries. If maxRetries is exceeded, return a MaxRetriesExceeded error.
+// maxRetries retries. If maxRetries is exceeded, return a RetryError that wraps a MaxRetriesExceeded error.
 //
 // Deprecated: Use [DoWithRetryInterfaceContextE] instead.
 func DoWithRetryInterfaceE(t testing.TestingT, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (any, error)) (any, error) {
@@ -144,43 +142,10 @@ func DoWithRetryInterfaceE(t testing.TestingT, actionDescription string, maxRetr
 
 // DoWithRetryInterfaceContextE runs the specified action. If it returns a value, return that value. If it returns a
 // FatalError, return that error immediately. If it returns any other type of error, sleep for sleepBetweenRetries and
-// try again, up to a maximum of maxRetries retries. If maxRetries is exceeded, return a MaxRetriesExceeded error. The
-// ctx parameter supports cancellation and timeouts.
+// try again, up to a maximum of maxRetries retries. If maxRetries is exceeded, return a RetryError that wraps a
+// MaxRetriesExceeded error. The ctx parameter supports cancellation and timeouts.
 func DoWithRetryInterfaceContextE(t testing.TestingT, ctx context.Context, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (any, error)) (any, error) {
-	var output any
-
-	var err error
-
-	for i := 0; i <= maxRetries; i++ {
-		if err := ctx.Err(); err != nil {
-			return output, err
-		}
-
-		logger.Default.Logf(t, "%s", actionDescription)
-
-		output, err = action()
-		if err == nil {
-			return output, nil
-		}
-
-		var fatalErr FatalError
-		if errors.As(err, &fatalErr) {
-			logger.Default.Logf(t, "Returning due to fatal error: %v", err)
-
-			return output, err
-		}
-
-		logger.Default.Logf(t, "%s returned an error: %s. Sleeping for %s and will try again.", actionDescription, err.Error(), sleepBetweenRetries)
-
-		select {
-		case <-time.After(sleepBetweenRetries):
-			// Continue to next retry
-		case <-ctx.Done():
-			return output, ctx.Err()
-		}
-	}
-
-	return output, MaxRetriesExceeded{Description: actionDescription, MaxRetries: maxRetries}
+	return DoWithPolicyInterfaceContextE(t, ctx, actionDescription, ConstantPolicy(maxRetries, sleepBetweenRetries), action)
 }
 
 // DoWithRetryableErrors runs the specified action. If it returns a value, return that value. If it returns an error,
@@ -211,7 +176,8 @@ func DoWithRetryableErrorsContext(t testing.TestingT, ctx context.Context, actio
 // check if error message or the string output from the action (which is often stdout/stderr from running some command)
 // matches any of the regular expressions in the specified retryableErrors map. If there is a match, sleep for
 // sleepBetweenRetries, and retry the specified action, up to a maximum of maxRetries retries. If there is no match,
-// return that error immediately, wrapped in a FatalError. If maxRetries is exceeded, return a MaxRetriesExceeded error.
+// return that error immediately, wrapped in a FatalError. If maxRetries is exceeded, return a RetryError that wraps a
+// MaxRetriesExceeded error.
 //
 // Deprecated: Use [DoWithRetryableErrorsContextE] instead.
 func DoWithRetryableErrorsE(t testing.TestingT, actionDescription string, retryableErrors map[string]string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) (string, error) {
@@ -222,35 +188,10 @@ func DoWithRetryableErrorsE(t testing.TestingT, actionDescription string, retrya
 // error, check if error message or the string output from the action (which is often stdout/stderr from running some
 // command) matches any of the regular expressions in the specified retryableErrors map. If there is a match, sleep for
 // sleepBetweenRetries, and retry the specified action, up to a maximum of maxRetries retries. If there is no match,
-// return that error immediately, wrapped in a FatalError. If maxRetries is exceeded, return a MaxRetriesExceeded error.
-// The ctx parameter supports cancellation and timeouts.
+// return that error immediately, wrapped in a FatalError. If maxRetries is exceeded, return a RetryError that wraps a
+// MaxRetriesExceeded error. The ctx parameter supports cancellation and timeouts.
 func DoWithRetryableErrorsContextE(t testing.TestingT, ctx context.Context, actionDescription string, retryableErrors map[string]string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) (string, error) {
-	retryableErrorsRegexp := map[*regexp.Regexp]string{}
-
-	for errorStr, errorMessage := range retryableErrors {
-		errorRegex, err := regexp.Compile(errorStr)
-		if err != nil {
-			return "", FatalError{Underlying: err}
-		}
-
-		retryableErrorsRegexp[errorRegex] = errorMessage
-	}
-
-	return DoWithRetryContextE(t, ctx, actionDescription, maxRetries, sleepBetweenRetries, func() (string, error) {
-		output, err := action()
-		if err == nil {
-			return output, nil
-		}
-
-		for errorRegexp, errorMessage := range retryableErrorsRegexp {
-			if errorRegexp.MatchString(output) || errorRegexp.MatchString(err.Error()) {
-				logger.Default.Logf(t, "'%s' failed with the error '%s' but this error was expected and warrants a retry. Further details: %s\n", actionDescription, err.Error(), errorMessage)
-				return output, err
-			}
-		}
-
-		return output, FatalError{Underlying: err}
-	})
+	return DoWithRetryableErrorsPolicyContextE(t, ctx, actionDescription, retryableErrors, ConstantPolicy(maxRetries, sleepBetweenRetries), action)
 }
 
 // Done can be stopped.
@@ -321,7 +262,12 @@ func (err TimeoutExceeded) Error() string {
 	return fmt.Sprintf("'%s' did not complete before timeout of %s", err.Description, err.Timeout)
 }
 
-// MaxRetriesExceeded is an error that occurs when the maximum amount of retries is exceeded.
+// MaxRetriesExceeded is an error that occurs when the maximum amount of retries is exceeded. The retry functions return
+// it wrapped in a RetryError with all the attempts, so use errors.As to check for it.
+//
+// Breaking change: the retry functions used to return a bare MaxRetriesExceeded. Code that checks for it with a type
+// assertion, such as err.(retry.MaxRetriesExceeded), or compares it with ==, no longer matches and must use errors.As or
+// errors.Is instead.
 type MaxRetriesExceeded struct {
 	Description string
 	MaxRetries  int
@@ -57,7 +57,7 @@ func TestDoWithRetry(t *testing.T) {
 			assert.Equal(t, expectedOutput, actualOutput)
 
 			if testCase.expectedError != nil {
-				assert.Equal(t, testCase.expectedError, err)
+				assert.ErrorIs(t, err, testCase.expectedError)
 			} else {
 				require.NoError(t, err)
 				assert.Equal(t, expectedOutput, actualOutput)
@@ -268,7 +268,7 @@ func TestDoWithRetryableErrors(t *testing.T) {
 			assert.Equal(t, expectedOutput, actualOutput)
 
 			if testCase.expectedError != nil {
-				assert.Equal(t, testCase.expectedError, err)
+				assert.ErrorIs(t, err, testCase.expectedError)
 			} else {
 				require.NoError(t, err)
 				assert.Equal(t, expectedOutput, actualOutput)
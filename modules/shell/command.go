@@ -26,6 +26,7 @@ type Command struct {
 	Command    string            // The command to run
 	WorkingDir string            // The working directory
 	Args       []string          // The args to pass to the command
+	PTY        *PTYOptions       // If set, run the command under a pseudo-terminal instead of with pipes
 }
 
 // RunCommand runs a shell command and redirects its stdout and stderr to the stdout of the atomic script itself. If
@@ -189,14 +190,19 @@ type ErrWithCmdOutput struct {
 }
 
 func (e *ErrWithCmdOutput) Error() string {
-	return fmt.Sprintf("error while running command: %v; %s", e.Underlying, e.Output.Stderr())
+	return logger.Redact(fmt.Sprintf("error while running command: %v; %s", e.Underlying, e.Output.Stderr()))
 }
 
 // runCommand runs a shell command and stores each line from stdout and stderr in Output. Depending on the logger, the
 // stdout and stderr of that command will also be printed to the stdout and stderr of this Go program to make debugging
 // easier.
 func runCommand(t testing.TestingT, ctx context.Context, command *Command) (*output, error) {
-	command.Logger.Logf(t, "Running command %s with args %s", command.Command, command.Args)
+	log := command.Logger.WithFields(logger.Fields{"command": command.Command, "working_dir": command.WorkingDir})
+	log.Logf(t, "Running command %s with args %s", command.Command, command.Args)
+
+	if command.PTY != nil {
+		return runCommandWithPTY(t, ctx, command)
+	}
 
 	cmd := exec.CommandContext(ctx, command.Command, command.Args...)
 
@@ -224,7 +230,7 @@ func runCommand(t testing.TestingT, ctx context.Context, command *Command) (*out
 		return nil, err
 	}
 
-	output, err := readStdoutAndStderr(t, command.Logger, stdout, stderr)
+	output, err := readStdoutAndStderr(t, log, stdout, stderr)
 	if err != nil {
 		return output, err
 	}
@@ -248,13 +254,13 @@ func readStdoutAndStderr(t testing.TestingT, log *logger.Logger, stdout, stderr
 	go func() {
 		defer wg.Done()
 
-		stdoutErr = readData(t, log, stdoutReader, out.stdout)
+		stdoutErr = readData(t, log.WithFields(logger.Fields{"stream": "stdout"}), stdoutReader, out.stdout)
 	}()
 
 	go func() {
 		defer wg.Done()
 
-		stderrErr = readData(t, log, stderrReader, out.stderr)
+		stderrErr = readData(t, log.WithFields(logger.Fields{"stream": "stderr"}), stderrReader, out.stderr)
 	}()
 
 	wg.Wait()
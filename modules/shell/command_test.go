@@ -232,3 +232,24 @@ func TestRunCommandWithStdinAndGetOutput(t *testing.T) {
 	out := shell.RunCommandContextAndGetOutput(t, t.Context(), cmd)
 	assert.Equal(t, text, strings.TrimSpace(out))
 }
+
+func TestRunCommandRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	secret := "shell-secret-" + random.UniqueID()
+	logger.RegisterSecrets(secret)
+
+	var buffer bytes.Buffer
+
+	_, err := shell.RunCommandContextAndGetOutputE(t, t.Context(), &shell.Command{
+		Command: "bash",
+		Args:    []string{"-c", "echo token " + secret + " >&2; exit 1"},
+		Logger:  logger.New(logger.NewJSONLogger(&buffer)),
+	})
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "token "+logger.Redacted)
+	assert.NotContains(t, err.Error(), secret)
+	assert.Contains(t, buffer.String(), logger.Redacted)
+	assert.NotContains(t, buffer.String(), secret)
+}
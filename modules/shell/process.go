@@ -0,0 +1,504 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// ErrOutputTimeout is returned when a process does not print a matching line in time.
+	ErrOutputTimeout = errors.New("timed out waiting for process output")
+
+	// ErrProcessExited is returned when a process exits before printing a matching line.
+	ErrProcessExited = errors.New("process exited")
+
+	// ErrStdinNotPiped is returned when writing to the stdin of a process that was started with Command.Stdin set.
+	ErrStdinNotPiped = errors.New("process stdin is not a pipe because Command.Stdin was set")
+)
+
+// endOfTransmission is the character that signals end of input to a program reading from a terminal (Ctrl-D).
+const endOfTransmission = "\x04"
+
+// Process is a handle to a command started in the background by StartCommandContextE. Its stdout and stderr are read
+// line by line while it runs, logged with Command.Logger, and kept so they can be waited for and inspected.
+type Process struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	output   *output
+	stdout   *lineStream
+	stderr   *lineStream
+	combined *lineStream
+	text     *terminalText
+	terminal *os.File
+	done     chan struct{}
+	waitErr  error
+
+	// expectMutex guards expected, the index in text after the last ExpectE match
+	expectMutex sync.Mutex
+	expected    int
+}
+
+// StartCommandContextE starts the given command in the background and returns a handle to the running process. Unless
+// Command.Stdin is set, the process reads its stdin from a pipe, or from its terminal if Command.PTY is set, that can be
+// written to with WriteStdinE. If t supports Cleanup, such as *testing.T, the process is killed when the test finishes
+// if it is still running. Canceling ctx kills the process.
+func StartCommandContextE(t testing.TestingT, ctx context.Context, command *Command) (*Process, error) {
+	command.Logger.WithFields(logger.Fields{"command": command.Command, "working_dir": command.WorkingDir}).Logf(t, "Starting command %s with args %s", command.Command, command.Args)
+
+	cmd := exec.CommandContext(ctx, command.Command, command.Args...)
+	cmd.Dir = command.WorkingDir
+	cmd.Env = formatEnvVars(command)
+
+	process := &Process{
+		cmd:      cmd,
+		output:   newOutput(),
+		stdout:   newLineStream(),
+		stderr:   newLineStream(),
+		combined: newLineStream(),
+		text:     newTerminalText(),
+		done:     make(chan struct{}),
+	}
+
+	var err error
+	if command.PTY != nil {
+		err = process.startWithPTY(t, command)
+	} else {
+		err = process.startWithPipes(t, command)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c, ok := t.(testing.Cleaner); ok {
+		c.Cleanup(func() {
+			select {
+			case <-process.done:
+			default:
+				command.Logger.Logf(t, "Killing command %s that is still running", command.Command)
+				_ = cmd.Process.Kill()
+				<-process.done
+			}
+		})
+	}
+
+	return process, nil
+}
+
+// StartCommandContext starts the given command in the background and returns a handle to the running process. If t
+// supports Cleanup, the process is killed when the test finishes if it is still running. This will fail the test if
+// the command can't be started. Canceling ctx kills the process.
+func StartCommandContext(t testing.TestingT, ctx context.Context, command *Command) *Process {
+	t.Helper()
+
+	process, err := StartCommandContextE(t, ctx, command)
+	require.NoError(t, err)
+
+	return process
+}
+
+// Pid returns the process ID of the process.
+func (process *Process) Pid() int {
+	return process.cmd.Process.Pid
+}
+
+// StdoutLines returns a channel that receives every line the process prints to stdout, starting from the first one,
+// and is closed when the process exits. Each call returns a new channel. The channel must be drained to release its
+// resources, but the process never blocks on it.
+func (process *Process) StdoutLines() <-chan string {
+	return process.stdout.channel()
+}
+
+// StderrLines returns a channel that receives every line the process prints to stderr, starting from the first one,
+// and is closed when the process exits. Each call returns a new channel. The channel must be drained to release its
+// resources, but the process never blocks on it.
+func (process *Process) StderrLines() <-chan string {
+	return process.stderr.channel()
+}
+
+// Output returns the stdout and stderr the process has printed so far, interleaved in the order the lines were read.
+func (process *Process) Output() string {
+	lines, _, _ := process.combined.linesFrom(0)
+
+	return strings.Join(lines, "\n")
+}
+
+// WaitForOutputE waits until the process prints a line to stdout or stderr that matches the given regex and returns
+// the line. Lines printed before the call are matched too. The returned error matches ErrOutputTimeout if no line
+// matches within the timeout and ErrProcessExited if the process exits first.
+func (process *Process) WaitForOutputE(regex *regexp.Regexp, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for next := 0; ; {
+		lines, changed, closed := process.combined.linesFrom(next)
+		for _, line := range lines {
+			if regex.MatchString(line) {
+				return line, nil
+			}
+		}
+
+		next += len(lines)
+
+		if closed {
+			return "", fmt.Errorf("%w before printing a line matching %s", ErrProcessExited, regex)
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return "", fmt.Errorf("%w: no line matching %s after %s", ErrOutputTimeout, regex, timeout)
+		}
+	}
+}
+
+// WaitForOutput waits until the process prints a line to stdout or stderr that matches the given regex and returns
+// the line. This will fail the test if no line matches within the timeout or the process exits first.
+func (process *Process) WaitForOutput(t testing.TestingT, regex *regexp.Regexp, timeout time.Duration) string {
+	t.Helper()
+
+	line, err := process.WaitForOutputE(regex, timeout)
+	require.NoError(t, err)
+
+	return line
+}
+
+// ExpectE waits until the output of the process since the previous ExpectE match matches the given regex, and returns
+// the matched text. Unlike WaitForOutputE, the match can span lines and, when running under a PTY, include prompts
+// that do not end with a newline, such as "Enter a value: ". Escape sequences are removed before matching. The
+// returned error matches ErrOutputTimeout if there is no match within the timeout and ErrProcessExited if the process
+// exits first.
+func (process *Process) ExpectE(regex *regexp.Regexp, timeout time.Duration) (string, error) {
+	process.expectMutex.Lock()
+	defer process.expectMutex.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		text, changed, closed := process.text.from(process.expected)
+		if loc := regex.FindStringIndex(text); loc != nil {
+			process.expected += loc[1]
+			return text[loc[0]:loc[1]], nil
+		}
+
+		if closed {
+			return "", fmt.Errorf("%w before printing output matching %s", ErrProcessExited, regex)
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return "", fmt.Errorf("%w: no output matching %s after %s", ErrOutputTimeout, regex, timeout)
+		}
+	}
+}
+
+// Expect waits until the output of the process since the previous Expect match matches the given regex, and returns
+// the matched text. This will fail the test if there is no match within the timeout or the process exits first.
+func (process *Process) Expect(t testing.TestingT, regex *regexp.Regexp, timeout time.Duration) string {
+	t.Helper()
+
+	match, err := process.ExpectE(regex, timeout)
+	require.NoError(t, err)
+
+	return match
+}
+
+// ResizeE changes the window size of the terminal of a process running under a PTY. The returned error matches
+// ErrNotPTY if Command.PTY was not set.
+func (process *Process) ResizeE(rows uint16, cols uint16) error {
+	if process.terminal == nil {
+		return ErrNotPTY
+	}
+
+	return pty.Setsize(process.terminal, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+// Resize changes the window size of the terminal of a process running under a PTY. This will fail the test if there
+// are any errors.
+func (process *Process) Resize(t testing.TestingT, rows uint16, cols uint16) {
+	t.Helper()
+	require.NoError(t, process.ResizeE(rows, cols))
+}
+
+// WriteStdinE writes the given text to the stdin of the process. Include a trailing newline to answer line-based
+// prompts. The returned error matches ErrStdinNotPiped if Command.Stdin was set.
+func (process *Process) WriteStdinE(text string) error {
+	if process.stdin == nil {
+		return ErrStdinNotPiped
+	}
+
+	_, err := io.WriteString(process.stdin, text)
+
+	return err
+}
+
+// WriteStdin writes the given text to the stdin of the process. This will fail the test if the write fails.
+func (process *Process) WriteStdin(t testing.TestingT, text string) {
+	t.Helper()
+	require.NoError(t, process.WriteStdinE(text))
+}
+
+// CloseStdinE closes the stdin of the process, which signals end of input to commands that read until EOF. Under a
+// PTY, this types the end-of-file character instead, which only takes effect at the start of a line. The returned error
+// matches ErrStdinNotPiped if Command.Stdin was set.
+func (process *Process) CloseStdinE() error {
+	if process.stdin == nil {
+		return ErrStdinNotPiped
+	}
+
+	if process.terminal != nil {
+		return process.WriteStdinE(endOfTransmission)
+	}
+
+	return process.stdin.Close()
+}
+
+// CloseStdin closes the stdin of the process. This will fail the test if there are any errors.
+func (process *Process) CloseStdin(t testing.TestingT) {
+	t.Helper()
+	require.NoError(t, process.CloseStdinE())
+}
+
+// SignalE sends the given signal, such as syscall.SIGTERM, to the process. The returned error matches
+// os.ErrProcessDone if the process has already exited. Only os.Kill is supported on Windows.
+func (process *Process) SignalE(signal os.Signal) error {
+	return process.cmd.Process.Signal(signal)
+}
+
+// Signal sends the given signal to the process. This will fail the test if the signal can't be sent.
+func (process *Process) Signal(t testing.TestingT, signal os.Signal) {
+	t.Helper()
+	require.NoError(t, process.SignalE(signal))
+}
+
+// Done returns a channel that is closed when the process has exited and all its output has been read.
+func (process *Process) Done() <-chan struct{} {
+	return process.done
+}
+
+// WaitE waits for the process to exit and returns its exit code, which is -1 if it was killed by a signal. If the
+// process did not exit successfully, the returned error is of type ErrWithCmdOutput, containing the output streams and
+// the underlying error.
+func (process *Process) WaitE() (int, error) {
+	<-process.done
+
+	exitCode := process.cmd.ProcessState.ExitCode()
+
+	if process.waitErr != nil {
+		return exitCode, &ErrWithCmdOutput{process.waitErr, process.output}
+	}
+
+	return exitCode, nil
+}
+
+// Wait waits for the process to exit. This will fail the test if the process did not exit successfully.
+func (process *Process) Wait(t testing.TestingT) {
+	t.Helper()
+
+	_, err := process.WaitE()
+	require.NoError(t, err)
+}
+
+// startWithPipes starts the process with its stdout and stderr read line by line from pipes.
+func (process *Process) startWithPipes(t testing.TestingT, command *Command) error {
+	cmd := process.cmd
+
+	if command.Stdin != nil {
+		cmd.Stdin = command.Stdin
+	} else {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+
+		process.stdin = stdin
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		wg := &sync.WaitGroup{}
+
+		wg.Add(2) //nolint:mnd // 2 goroutines: one for stdout, one for stderr
+
+		var stdoutErr, stderrErr error
+
+		go func() {
+			defer wg.Done()
+
+			stdoutErr = readData(t, command.Logger.WithFields(logger.Fields{"command": command.Command, "stream": "stdout"}), bufio.NewReader(stdout), &processStreamWriter{process.output.stdout, process.stdout, process.combined, process.text})
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			stderrErr = readData(t, command.Logger.WithFields(logger.Fields{"command": command.Command, "stream": "stderr"}), bufio.NewReader(stderr), &processStreamWriter{process.output.stderr, process.stderr, process.combined, process.text})
+		}()
+
+		wg.Wait()
+
+		process.finish(stdoutErr, stderrErr)
+	}()
+
+	return nil
+}
+
+// startWithPTY starts the process under a pseudo-terminal, with its terminal output read as stdout.
+func (process *Process) startWithPTY(t testing.TestingT, command *Command) error {
+	terminal, err := startPTY(process.cmd, command.PTY)
+	if err != nil {
+		return err
+	}
+
+	process.terminal = terminal
+
+	if command.Stdin != nil {
+		go func() { _, _ = io.Copy(terminal, command.Stdin) }()
+	} else {
+		process.stdin = terminal
+	}
+
+	go func() {
+		// The raw terminal output is added to process.text by readTerminal itself, so prompts without a trailing
+		// newline can be matched
+		writer := &processStreamWriter{process.output.stdout, process.stdout, process.combined, nil}
+		readErr := readTerminal(t, command.Logger, terminal, command.PTY, writer, process.text)
+
+		process.finish(readErr)
+		_ = terminal.Close()
+	}()
+
+	return nil
+}
+
+// finish waits for the process to exit once all its output has been read, and wakes up everything waiting on it.
+func (process *Process) finish(readErrs ...error) {
+	process.waitErr = errors.Join(append([]error{process.cmd.Wait()}, readErrs...)...)
+
+	process.stdout.close()
+	process.stderr.close()
+	process.combined.close()
+	process.text.close()
+	close(process.done)
+}
+
+// processStreamWriter stores each line of a process output stream in the buffered output, the stream's own lines, the
+// combined lines and, if set, the text that ExpectE matches against.
+type processStreamWriter struct {
+	output   *outputStream
+	stream   *lineStream
+	combined *lineStream
+	text     *terminalText
+}
+
+func (writer *processStreamWriter) WriteString(s string) (int, error) {
+	writer.stream.append(s)
+	writer.combined.append(s)
+
+	if writer.text != nil {
+		writer.text.write(s + "\n")
+	}
+
+	return writer.output.WriteString(s)
+}
+
+// lineStream is an append-only list of lines that can be waited on. The changed channel is closed and replaced every
+// time the stream changes, so any number of readers can wait for new lines without the writer blocking.
+type lineStream struct {
+	changed chan struct{}
+	lines   []string
+	mutex   sync.Mutex
+	closed  bool
+}
+
+func newLineStream() *lineStream {
+	return &lineStream{changed: make(chan struct{})}
+}
+
+func (stream *lineStream) append(line string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	stream.lines = append(stream.lines, line)
+	stream.notify()
+}
+
+func (stream *lineStream) close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	stream.closed = true
+	stream.notify()
+}
+
+// notify wakes up all the readers waiting on the stream. The mutex must be held.
+func (stream *lineStream) notify() {
+	close(stream.changed)
+	stream.changed = make(chan struct{})
+}
+
+// linesFrom returns the lines starting at the given index, a channel that is closed when the stream changes, and
+// whether the stream is closed.
+func (stream *lineStream) linesFrom(index int) ([]string, <-chan struct{}, bool) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	return stream.lines[index:len(stream.lines):len(stream.lines)], stream.changed, stream.closed
+}
+
+// channel returns a channel that receives every line of the stream and is closed when the stream is closed.
+func (stream *lineStream) channel() <-chan string {
+	lines := make(chan string)
+
+	go func() {
+		defer close(lines)
+
+		for next := 0; ; {
+			newLines, changed, closed := stream.linesFrom(next)
+			for _, line := range newLines {
+				lines <- line
+			}
+
+			next += len(newLines)
+
+			if len(newLines) == 0 {
+				if closed {
+					return
+				}
+
+				<-changed
+			}
+		}
+	}()
+
+	return lines
+}
@@ -0,0 +1,110 @@
+package shell_test
+
+import (
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+)
+
+func TestStartCommandInteractive(t *testing.T) {
+	t.Parallel()
+
+	bashCode := `
+trap 'echo "shutting down"; exit 3' TERM
+echo "What is your name?"
+read -r name
+echo "Hello, $name" >&2
+while true; do sleep 0.1; done
+`
+	cmd := &shell.Command{
+		Command: "bash",
+		Args:    []string{"-c", bashCode},
+		Logger:  logger.Discard,
+	}
+
+	process := shell.StartCommandContext(t, t.Context(), cmd)
+	stdoutLines := process.StdoutLines()
+	stderrLines := process.StderrLines()
+
+	process.WaitForOutput(t, regexp.MustCompile(`name\?$`), 10*time.Second)
+	process.WriteStdin(t, "Terratest\n")
+
+	line := process.WaitForOutput(t, regexp.MustCompile(`^Hello`), 10*time.Second)
+	assert.Equal(t, "Hello, Terratest", line)
+
+	process.Signal(t, syscall.SIGTERM)
+
+	exitCode, err := process.WaitE()
+	require.Error(t, err)
+	assert.Equal(t, 3, exitCode)
+
+	var stdout, stderr []string
+	for line := range stdoutLines {
+		stdout = append(stdout, line)
+	}
+
+	for line := range stderrLines {
+		stderr = append(stderr, line)
+	}
+
+	assert.Equal(t, []string{"What is your name?", "shutting down"}, stdout)
+	assert.Equal(t, []string{"Hello, Terratest"}, stderr)
+	assert.Equal(t, "What is your name?\nHello, Terratest\nshutting down", process.Output())
+}
+
+func TestStartCommandWaitForOutputErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Timeout", func(t *testing.T) {
+		t.Parallel()
+
+		process := shell.StartCommandContext(t, t.Context(), &shell.Command{
+			Command: "sleep",
+			Args:    []string{"10"},
+			Logger:  logger.Discard,
+		})
+
+		_, err := process.WaitForOutputE(regexp.MustCompile("never"), 100*time.Millisecond)
+		require.ErrorIs(t, err, shell.ErrOutputTimeout)
+	})
+
+	t.Run("Exited", func(t *testing.T) {
+		t.Parallel()
+
+		process := shell.StartCommandContext(t, t.Context(), &shell.Command{
+			Command: "echo",
+			Args:    []string{"done"},
+			Logger:  logger.Discard,
+		})
+
+		_, err := process.WaitForOutputE(regexp.MustCompile("never"), 10*time.Second)
+		require.ErrorIs(t, err, shell.ErrProcessExited)
+
+		exitCode, err := process.WaitE()
+		require.NoError(t, err)
+		assert.Equal(t, 0, exitCode)
+	})
+}
+
+func TestStartCommandCloseStdin(t *testing.T) {
+	t.Parallel()
+
+	process := shell.StartCommandContext(t, t.Context(), &shell.Command{
+		Command: "cat",
+		Logger:  logger.Discard,
+	})
+
+	process.WriteStdin(t, "first\n")
+	process.WriteStdin(t, "second\n")
+	process.CloseStdin(t)
+	process.Wait(t)
+
+	assert.Equal(t, "first\nsecond", process.Output())
+}
@@ -0,0 +1,268 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/creack/pty"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// DefaultPTYRows is the default number of rows of the pseudo-terminal window.
+	DefaultPTYRows = 24
+
+	// DefaultPTYCols is the default number of columns of the pseudo-terminal window.
+	DefaultPTYCols = 80
+
+	// maxIncompleteEscapeSequence is how many bytes at the end of the terminal stream are held back if they may be the
+	// start of an escape sequence that continues in the next read.
+	maxIncompleteEscapeSequence = 64
+
+	// terminalReadBufferSize is the size of a single read from the pseudo-terminal.
+	terminalReadBufferSize = 4096
+)
+
+// ErrNotPTY is returned when using a pseudo-terminal feature on a process that does not run under one.
+var ErrNotPTY = errors.New("process is not running under a pseudo-terminal")
+
+// ansiEscapeRegex matches ANSI CSI sequences (colors, cursor movement), OSC sequences (window titles, hyperlinks) and
+// other two-character escape sequences.
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[ -/]*[0-~]`)
+
+// PTYOptions defines options to run a command under a pseudo-terminal, for tools that behave differently when they are
+// not attached to a terminal, such as prompting for input. The terminal merges stdout and stderr into a single stream,
+// which is returned as stdout. Pseudo-terminals are not supported on Windows.
+type PTYOptions struct {
+	// Responses answer prompts automatically. Every time the terminal output matches the prompt of a response, the
+	// response is typed into the terminal.
+	Responses []PromptResponse
+
+	// Number of rows of the terminal window. Defaults to DefaultPTYRows.
+	Rows uint16
+
+	// Number of columns of the terminal window. Defaults to DefaultPTYCols.
+	Cols uint16
+
+	// If set to true, ANSI escape sequences such as colors are removed from the captured output. Prompts are always
+	// matched against the output without escape sequences.
+	StripANSI bool
+}
+
+// PromptResponse is an answer to a prompt printed by a command running under a pseudo-terminal.
+type PromptResponse struct {
+	// Prompt to answer, such as regexp.MustCompile(`Enter a value: $`)
+	Prompt *regexp.Regexp
+
+	// Response to type when the prompt is printed. Include a trailing newline to submit it.
+	Response string
+}
+
+// StripANSI removes ANSI escape sequences, such as colors and cursor movement, from the given text.
+func StripANSI(text string) string {
+	return ansiEscapeRegex.ReplaceAllString(text, "")
+}
+
+// startPTY starts the given command under a new pseudo-terminal and returns the terminal it is attached to.
+func startPTY(cmd *exec.Cmd, options *PTYOptions) (*os.File, error) {
+	size := &pty.Winsize{Rows: options.Rows, Cols: options.Cols}
+	if size.Rows == 0 {
+		size.Rows = DefaultPTYRows
+	}
+
+	if size.Cols == 0 {
+		size.Cols = DefaultPTYCols
+	}
+
+	return pty.StartWithSize(cmd, size)
+}
+
+// runCommandWithPTY is like runCommand, but runs the command under a pseudo-terminal and stores its terminal output
+// as stdout.
+func runCommandWithPTY(t testing.TestingT, ctx context.Context, command *Command) (*output, error) {
+	cmd := exec.CommandContext(ctx, command.Command, command.Args...)
+	cmd.Dir = command.WorkingDir
+	cmd.Env = formatEnvVars(command)
+
+	terminal, err := startPTY(cmd, command.PTY)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = terminal.Close() }()
+
+	if command.Stdin != nil {
+		go func() { _, _ = io.Copy(terminal, command.Stdin) }()
+	}
+
+	output := newOutput()
+	readErr := readTerminal(t, command.Logger, terminal, command.PTY, output.stdout, newTerminalText())
+
+	return output, errors.Join(cmd.Wait(), readErr)
+}
+
+// readTerminal reads the output of a command running under a pseudo-terminal until the command exits. It stores each
+// line in writer, adds the raw output to text, and answers the prompts in options.Responses as they appear.
+func readTerminal(t testing.TestingT, log *logger.Logger, terminal io.ReadWriter, options *PTYOptions, writer io.StringWriter, text *terminalText) error {
+	buffer := make([]byte, terminalReadBufferSize)
+
+	var (
+		partialLine string
+		answered    int
+	)
+
+	for {
+		n, readErr := terminal.Read(buffer)
+		if n > 0 {
+			chunk := string(buffer[:n])
+			text.write(chunk)
+
+			partialLine += chunk
+			for {
+				line, rest, found := strings.Cut(partialLine, "\n")
+				if !found {
+					break
+				}
+
+				if err := writeTerminalLine(t, log, line, options, writer); err != nil {
+					return err
+				}
+
+				partialLine = rest
+			}
+
+			var err error
+			if answered, err = answerPrompts(terminal, options.Responses, text, answered); err != nil {
+				return err
+			}
+		}
+
+		if readErr != nil {
+			if partialLine != "" {
+				if err := writeTerminalLine(t, log, partialLine, options, writer); err != nil {
+					return err
+				}
+			}
+
+			// Linux returns EIO when reading from a terminal that has no processes attached anymore
+			if errors.Is(readErr, io.EOF) || errors.Is(readErr, syscall.EIO) {
+				return nil
+			}
+
+			return readErr
+		}
+	}
+}
+
+// writeTerminalLine logs a single line of terminal output and stores it in writer.
+func writeTerminalLine(t testing.TestingT, log *logger.Logger, line string, options *PTYOptions, writer io.StringWriter) error {
+	// Terminals translate newlines to CRLF
+	line = strings.TrimSuffix(line, "\r")
+	if options.StripANSI {
+		line = StripANSI(line)
+	}
+
+	log.Logf(t, "%s", line)
+
+	_, err := writer.WriteString(line)
+
+	return err
+}
+
+// answerPrompts types the response of each prompt that appears in text after the given index, and returns the index
+// after the last answered prompt.
+func answerPrompts(terminal io.Writer, responses []PromptResponse, text *terminalText, answered int) (int, error) {
+	for {
+		pending, _, _ := text.from(answered)
+
+		var (
+			match    []int
+			response string
+		)
+
+		for _, candidate := range responses {
+			loc := candidate.Prompt.FindStringIndex(pending)
+			if loc != nil && (match == nil || loc[0] < match[0]) {
+				match, response = loc, candidate.Response
+			}
+		}
+
+		// Ignore prompts that match the empty string, as they would be answered forever
+		if match == nil || match[1] == 0 {
+			return answered, nil
+		}
+
+		if _, err := io.WriteString(terminal, response); err != nil {
+			return answered, err
+		}
+
+		answered += match[1]
+	}
+}
+
+// terminalText is the text a process printed, with escape sequences removed, which prompts are matched against. The
+// changed channel is closed and replaced every time the text changes, so any number of readers can wait for new text
+// without the writer blocking.
+type terminalText struct {
+	changed chan struct{}
+	// incomplete holds the end of the raw output if it may be an escape sequence that continues in the next write.
+	incomplete string
+	text       strings.Builder
+	mutex      sync.Mutex
+	closed     bool
+}
+
+func newTerminalText() *terminalText {
+	return &terminalText{changed: make(chan struct{})}
+}
+
+func (text *terminalText) write(raw string) {
+	text.mutex.Lock()
+	defer text.mutex.Unlock()
+
+	raw = text.incomplete + raw
+	text.incomplete = ""
+
+	if i := strings.LastIndexByte(raw, '\x1b'); i >= 0 && len(raw)-i < maxIncompleteEscapeSequence {
+		if loc := ansiEscapeRegex.FindStringIndex(raw[i:]); loc == nil || loc[0] != 0 {
+			raw, text.incomplete = raw[:i], raw[i:]
+		}
+	}
+
+	text.text.WriteString(StripANSI(raw))
+	text.notify()
+}
+
+func (text *terminalText) close() {
+	text.mutex.Lock()
+	defer text.mutex.Unlock()
+
+	text.text.WriteString(text.incomplete)
+	text.incomplete = ""
+	text.closed = true
+	text.notify()
+}
+
+// notify wakes up all the readers waiting on the text. The mutex must be held.
+func (text *terminalText) notify() {
+	close(text.changed)
+	text.changed = make(chan struct{})
+}
+
+// from returns the text starting at the given index, a channel that is closed when the text changes, and whether the
+// text is closed.
+func (text *terminalText) from(index int) (string, <-chan struct{}, bool) {
+	text.mutex.Lock()
+	defer text.mutex.Unlock()
+
+	return text.text.String()[index:], text.changed, text.closed
+}
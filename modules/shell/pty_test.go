@@ -0,0 +1,111 @@
+package shell_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+)
+
+func TestRunCommandWithPTY(t *testing.T) {
+	t.Parallel()
+
+	cmd := &shell.Command{
+		Command: "bash",
+		Args:    []string{"-c", `[ -t 1 ] && echo "is a terminal"; stty size; printf "\033[31mred\033[0m\n"`},
+		Logger:  logger.Discard,
+		PTY:     &shell.PTYOptions{Rows: 40, Cols: 120, StripANSI: true},
+	}
+
+	out := shell.RunCommandContextAndGetOutput(t, t.Context(), cmd)
+	assert.Equal(t, "is a terminal\n40 120\nred", out)
+}
+
+func TestRunCommandWithPTYKeepsANSI(t *testing.T) {
+	t.Parallel()
+
+	cmd := &shell.Command{
+		Command: "bash",
+		Args:    []string{"-c", `printf "\033[31mred\033[0m\n"`},
+		Logger:  logger.Discard,
+		PTY:     &shell.PTYOptions{},
+	}
+
+	out := shell.RunCommandContextAndGetOutput(t, t.Context(), cmd)
+	assert.Equal(t, "\x1b[31mred\x1b[0m", out)
+	assert.Equal(t, "red", shell.StripANSI(out))
+}
+
+func TestRunCommandWithPTYAnswersPrompts(t *testing.T) {
+	t.Parallel()
+
+	bashCode := `
+read -r -p "Enter a value: " value
+read -r -p "Are you sure? [y/N] " sure
+echo "value=$value sure=$sure"
+`
+	cmd := &shell.Command{
+		Command: "bash",
+		Args:    []string{"-c", bashCode},
+		Logger:  logger.Discard,
+		PTY: &shell.PTYOptions{
+			StripANSI: true,
+			Responses: []shell.PromptResponse{
+				{Prompt: regexp.MustCompile(`Enter a value: $`), Response: "yes\n"},
+				{Prompt: regexp.MustCompile(`\[y/N\] $`), Response: "y\n"},
+			},
+		},
+	}
+
+	out := shell.RunCommandContextAndGetOutput(t, t.Context(), cmd)
+	assert.True(t, strings.HasSuffix(out, "value=yes sure=y"), out)
+}
+
+func TestStartCommandWithPTY(t *testing.T) {
+	t.Parallel()
+
+	bashCode := `
+read -r -p "Name: " name
+echo "Hello, $name"
+trap 'stty size' WINCH
+read -r -p "Resize me" _
+`
+	process := shell.StartCommandContext(t, t.Context(), &shell.Command{
+		Command: "bash",
+		Args:    []string{"-c", bashCode},
+		Logger:  logger.Discard,
+		PTY:     &shell.PTYOptions{},
+	})
+
+	process.Expect(t, regexp.MustCompile(`Name: `), 10*time.Second)
+	process.WriteStdin(t, "Terratest\n")
+	require.Equal(t, "Hello, Terratest", process.Expect(t, regexp.MustCompile(`Hello, \w+`), 10*time.Second))
+
+	process.Expect(t, regexp.MustCompile(`Resize me`), 10*time.Second)
+	process.Resize(t, 50, 100)
+	process.WriteStdin(t, "\n")
+	process.Wait(t)
+
+	assert.Contains(t, process.Output(), "50 100")
+
+	_, err := process.ExpectE(regexp.MustCompile(`never`), time.Second)
+	require.ErrorIs(t, err, shell.ErrProcessExited)
+}
+
+func TestProcessResizeWithoutPTY(t *testing.T) {
+	t.Parallel()
+
+	process := shell.StartCommandContext(t, t.Context(), &shell.Command{
+		Command: "echo",
+		Logger:  logger.Discard,
+	})
+	process.Wait(t)
+
+	require.ErrorIs(t, process.ResizeE(24, 80), shell.ErrNotPTY)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package ssh
+
+import "io/fs"
+
+// localFileOwner returns the numeric owner and group of a local file, which files don't have on this platform.
+func localFileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
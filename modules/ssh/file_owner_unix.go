@@ -0,0 +1,18 @@
+//go:build unix
+
+package ssh
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// localFileOwner returns the numeric owner and group of a local file.
+func localFileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(stat.Uid), int(stat.Gid), true
+}
@@ -0,0 +1,465 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// sudoPrompt is the password prompt that sudo is told to print, so that it can be recognized and answered.
+	sudoPrompt = "[terratest] sudo password: "
+
+	// sudoAuthenticatedMarker is printed to stderr by commands run with sudo before anything else, which tells that
+	// sudo has authenticated, so that no more prompts are expected and stdin belongs to the command.
+	sudoAuthenticatedMarker = "[terratest] sudo authenticated"
+)
+
+// ErrSessionClosed is returned when running a command on a [Session] that has been closed.
+var ErrSessionClosed = errors.New("ssh session is closed")
+
+// SessionOptions are the options for opening a [Session].
+type SessionOptions struct {
+	// Logger is used to log the commands and their output. Uses logger.Default if nil.
+	Logger *logger.Logger
+	// JumpHosts are the hosts to tunnel the connection through. The first jump host is connected to directly, and each
+	// following jump host, and finally the target host, is connected to from the previous one.
+	JumpHosts []*Host
+}
+
+// Session is a persistent SSH connection to a host, optionally through a chain of jump hosts, that runs any number of
+// commands without connecting again for each one. Open it with NewSessionContextE.
+type Session struct {
+	host   *Host
+	logger *logger.Logger
+	// sftp is the SFTP client of the session, started on first use by SFTPClient
+	sftp *sftp.Client
+	// clients are the clients of the jump hosts, in order, followed by the client of the host
+	clients []*ssh.Client
+	mutex   sync.Mutex
+}
+
+// Command is a command to run on the remote host with [Session.RunContextE].
+type Command struct {
+	// Stdin is the input of the command. The command reads an empty input if nil.
+	Stdin io.Reader
+	// Stdout receives the stdout of the command while it runs, if set.
+	Stdout io.Writer
+	// Stderr receives the stderr of the command while it runs, if set.
+	Stderr io.Writer
+	// Env are additional environment variables to set for the command.
+	Env map[string]string
+	// Command is the command line to run. It is run by the login shell of the user, or by sh if Env or UseSudo is set.
+	Command string
+	// SudoPassword is the password to answer sudo with. The password of the host is used if blank.
+	SudoPassword string
+	// UseSudo runs the command as root with sudo. If no password is set, sudo fails if it asks for one.
+	UseSudo bool
+}
+
+// CommandResult is the output of a command run with [Session.RunContextE].
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// CommandError is returned when a command run with [Session.RunContextE] exits with a non-zero exit code.
+type CommandError struct {
+	Command  string
+	Stderr   string
+	ExitCode int
+}
+
+func (err *CommandError) Error() string {
+	return logger.Redact(fmt.Sprintf("command %s exited with code %d: %s", err.Command, err.ExitCode, err.Stderr))
+}
+
+// NewSessionContext connects to the given host via SSH, through the jump hosts of the options if any, and returns a
+// session that runs commands on it over that connection. options may be nil. If t supports Cleanup, such as
+// *testing.T, the session is closed when the test finishes, otherwise make sure to call Close when you're done! This
+// will fail the test if the connection fails. The ctx parameter supports cancellation and timeouts.
+func NewSessionContext(t testing.TestingT, ctx context.Context, host *Host, options *SessionOptions) *Session {
+	session, err := NewSessionContextE(t, ctx, host, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return session
+}
+
+// NewSessionContextE connects to the given host via SSH, through the jump hosts of the options if any, and returns a
+// session that runs commands on it over that connection. options may be nil. If t supports Cleanup, such as
+// *testing.T, the session is closed when the test finishes, otherwise make sure to call Close when you're done! The ctx
+// parameter supports cancellation and timeouts.
+func NewSessionContextE(t testing.TestingT, ctx context.Context, host *Host, options *SessionOptions) (*Session, error) {
+	if options == nil {
+		options = &SessionOptions{}
+	}
+
+	session := &Session{host: host, logger: options.Logger}
+
+	for _, hop := range append(slices.Clone(options.JumpHosts), host) {
+		if err := session.connect(t, ctx, hop); err != nil {
+			session.Close(t)
+			return nil, err
+		}
+	}
+
+	if c, ok := t.(testing.Cleaner); ok {
+		c.Cleanup(func() { session.Close(t) })
+	}
+
+	return session, nil
+}
+
+// connect connects to the given host, from the last host connected to if any, and adds its client to the session.
+func (session *Session) connect(t testing.TestingT, ctx context.Context, host *Host) error {
+	authMethods, err := createAuthMethodsForHost(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	options := &SSHConnectionOptions{
+		Username:    host.SshUserName,
+		Address:     host.Hostname,
+		Port:        host.GetPort(),
+		AuthMethods: authMethods,
+	}
+
+	if len(session.clients) == 0 {
+		session.logger.Logf(t, "Connecting to %s@%s via SSH", host.SshUserName, host.Hostname)
+
+		client, err := createSSHClient(ctx, options)
+		if err != nil {
+			return err
+		}
+
+		session.clients = append(session.clients, client)
+
+		return nil
+	}
+
+	jumpHost := session.clients[len(session.clients)-1]
+
+	session.logger.Logf(t, "Connecting to %s@%s via SSH through %s", host.SshUserName, host.Hostname, jumpHost.RemoteAddr())
+
+	conn, err := jumpHost.DialContext(ctx, "tcp", options.ConnectionString())
+	if err != nil {
+		return fmt.Errorf("error connecting to %s through jump host %s: %w", options.ConnectionString(), jumpHost.RemoteAddr(), err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, options.ConnectionString(), createSSHClientConfig(options))
+	if err != nil {
+		_ = conn.Close()
+
+		return err
+	}
+
+	session.clients = append(session.clients, ssh.NewClient(c, chans, reqs))
+
+	return nil
+}
+
+// Client returns the SSH client connected to the host, to use other features of the connection, such as opening TCP
+// connections from the host with its Dial method. Returns nil if the session is closed.
+func (session *Session) Client() *ssh.Client {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if len(session.clients) == 0 {
+		return nil
+	}
+
+	return session.clients[len(session.clients)-1]
+}
+
+// Close closes the connection to the host and to the jump hosts. Closing a closed session does nothing.
+func (session *Session) Close(t testing.TestingT) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	Close(t, session.sftp, io.EOF.Error())
+	session.sftp = nil
+
+	// Close the connections from the host back to the first jump host, as each one is tunneled through the previous one
+	for _, client := range slices.Backward(session.clients) {
+		Close(t, client, io.EOF.Error())
+	}
+
+	session.clients = nil
+}
+
+// RunContext runs the command on the host and returns its stdout, stderr and exit code. The output is logged line by
+// line, and written to Command.Stdout and Command.Stderr if set, while the command runs. This will fail the test if
+// the command can't be run or exits with a non-zero exit code. Canceling ctx kills the command.
+func (session *Session) RunContext(t testing.TestingT, ctx context.Context, command *Command) *CommandResult {
+	result, err := session.RunContextE(t, ctx, command)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+// RunContextE runs the command on the host and returns its stdout, stderr and exit code. The output is logged line by
+// line, and written to Command.Stdout and Command.Stderr if set, while the command runs. If the command exits with a
+// non-zero exit code, the result is returned along with a *CommandError. Canceling ctx kills the command.
+func (session *Session) RunContextE(t testing.TestingT, ctx context.Context, command *Command) (*CommandResult, error) {
+	client := session.Client()
+	if client == nil {
+		return nil, ErrSessionClosed
+	}
+
+	sshSession, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = sshSession.Close() }()
+
+	log := session.logger.WithFields(logger.Fields{"command": command.Command, "host": session.host.Hostname})
+	log.Logf(t, "Running command %s on %s@%s", command.Command, session.host.SshUserName, session.host.Hostname)
+
+	var stdout, stderr bytes.Buffer
+
+	stdoutLog := &lineLogger{t: t, log: log.WithFields(logger.Fields{"stream": "stdout"})}
+	stderrLog := &lineLogger{t: t, log: log.WithFields(logger.Fields{"stream": "stderr"})}
+
+	sshSession.Stdout = multiWriter(&stdout, stdoutLog, command.Stdout)
+	sshSession.Stderr = multiWriter(&stderr, stderrLog, command.Stderr)
+
+	stdin, err := sshSession.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	var prompter *sudoPrompter
+
+	if command.UseSudo {
+		password := command.SudoPassword
+		if password == "" {
+			password = session.host.Password
+		}
+
+		prompter = &sudoPrompter{stderr: sshSession.Stderr, stdin: stdin, password: password, authenticated: make(chan struct{})}
+		sshSession.Stderr = prompter
+	}
+
+	if err := sshSession.Start(remoteCommandLine(command, prompter)); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer func() { _ = stdin.Close() }()
+
+		// sudo reads the password from stdin too, so the input of the command is only sent once sudo is done with it
+		if prompter != nil {
+			select {
+			case <-prompter.authenticated:
+			case <-done:
+				return
+			}
+		}
+
+		if command.Stdin != nil {
+			_, _ = io.Copy(stdin, command.Stdin)
+		}
+	}()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = sshSession.Signal(ssh.SIGKILL)
+			_ = sshSession.Close()
+		case <-done:
+		}
+	}()
+
+	err = sshSession.Wait()
+	close(done)
+
+	if prompter != nil {
+		prompter.flush()
+	}
+
+	stdoutLog.flush()
+	stderrLog.flush()
+
+	result := &CommandResult{Stdout: stdout.String(), Stderr: stderr.String()}
+
+	var exitErr *ssh.ExitError
+
+	switch {
+	case err == nil:
+		return result, nil
+	case ctx.Err() != nil:
+		result.ExitCode = -1
+		return result, ctx.Err()
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+		return result, &CommandError{Command: command.Command, Stderr: result.Stderr, ExitCode: result.ExitCode}
+	default:
+		result.ExitCode = -1
+		return result, err
+	}
+}
+
+// remoteCommandLine returns the command line to send to the host for the command. Environment variables are set with
+// env rather than with SSH requests, as most servers only accept a few variables from clients, and so that they are
+// set for commands run with sudo too.
+func remoteCommandLine(command *Command, prompter *sudoPrompter) string {
+	if len(command.Env) == 0 && prompter == nil {
+		return command.Command
+	}
+
+	var args []string
+
+	script := command.Command
+
+	if prompter != nil {
+		// -S reads the password from stdin rather than from a terminal, and -n fails rather than prompt if there is no
+		// password to answer with
+		mode := "-S"
+		if prompter.password == "" {
+			mode = "-n"
+		}
+
+		args = append(args, "sudo", mode, "-p", shellQuote(sudoPrompt))
+		script = fmt.Sprintf("echo %s >&2; %s", shellQuote(sudoAuthenticatedMarker), script)
+	}
+
+	if len(command.Env) > 0 {
+		args = append(args, "env")
+
+		for _, key := range slices.Sorted(maps.Keys(command.Env)) {
+			args = append(args, shellQuote(key+"="+command.Env[key]))
+		}
+	}
+
+	args = append(args, "sh", "-c", shellQuote(script))
+
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes the value for a POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// multiWriter returns a writer that writes to all the given writers that are not nil.
+func multiWriter(writers ...io.Writer) io.Writer {
+	return io.MultiWriter(slices.DeleteFunc(writers, func(writer io.Writer) bool { return writer == nil })...)
+}
+
+// lineLogger is a writer that logs each line written to it.
+type lineLogger struct {
+	t       testing.TestingT
+	log     *logger.Logger
+	pending []byte
+}
+
+func (writer *lineLogger) Write(data []byte) (int, error) {
+	writer.pending = append(writer.pending, data...)
+
+	for {
+		index := bytes.IndexByte(writer.pending, '\n')
+		if index < 0 {
+			break
+		}
+
+		writer.log.Logf(writer.t, "%s", writer.pending[:index])
+		writer.pending = writer.pending[index+1:]
+	}
+
+	return len(data), nil
+}
+
+// flush logs the last line if it did not end with a newline.
+func (writer *lineLogger) flush() {
+	if len(writer.pending) > 0 {
+		writer.log.Logf(writer.t, "%s", writer.pending)
+		writer.pending = nil
+	}
+}
+
+// sudoPrompter is the stderr of a command run with sudo. It answers the password prompts of sudo, and removes them and
+// the marker that the command prints once sudo has authenticated from the output written to stderr.
+type sudoPrompter struct {
+	stderr        io.Writer
+	stdin         io.Writer
+	authenticated chan struct{}
+	password      string
+	pending       []byte
+	done          bool
+}
+
+func (prompter *sudoPrompter) Write(data []byte) (int, error) {
+	if prompter.done {
+		return prompter.stderr.Write(data)
+	}
+
+	prompter.pending = append(prompter.pending, data...)
+
+	for {
+		if index := bytes.Index(prompter.pending, []byte(sudoPrompt)); index >= 0 {
+			if _, err := prompter.stderr.Write(prompter.pending[:index]); err != nil {
+				return 0, err
+			}
+
+			if _, err := io.WriteString(prompter.stdin, prompter.password+"\n"); err != nil {
+				return 0, err
+			}
+
+			prompter.pending = prompter.pending[index+len(sudoPrompt):]
+
+			continue
+		}
+
+		if index := bytes.Index(prompter.pending, []byte(sudoAuthenticatedMarker+"\n")); index >= 0 {
+			output := append(prompter.pending[:index:index], prompter.pending[index+len(sudoAuthenticatedMarker)+1:]...)
+
+			prompter.pending = nil
+			prompter.done = true
+			close(prompter.authenticated)
+
+			if _, err := prompter.stderr.Write(output); err != nil {
+				return 0, err
+			}
+
+			return len(data), nil
+		}
+
+		// Pass the complete lines through, such as errors of sudo. Prompts and the marker are always on the last line.
+		if index := bytes.LastIndexByte(prompter.pending, '\n'); index >= 0 {
+			if _, err := prompter.stderr.Write(prompter.pending[:index+1]); err != nil {
+				return 0, err
+			}
+
+			prompter.pending = prompter.pending[index+1:]
+		}
+
+		return len(data), nil
+	}
+}
+
+// flush writes the output that was held back in case it was the start of a prompt.
+func (prompter *sudoPrompter) flush() {
+	if len(prompter.pending) > 0 {
+		_, _ = prompter.stderr.Write(prompter.pending)
+		prompter.pending = nil
+	}
+}
@@ -0,0 +1,367 @@
+package ssh_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	testSSHUser     = "terratest"
+	testSSHPassword = "ssh-password"
+)
+
+// fakeSudo is a stand-in for sudo that asks for the password "sudo-password" on stderr with the prompt given with -p,
+// and reads it from stdin, like sudo -S does.
+const fakeSudo = `#!/bin/sh
+prompt="Password: "
+mode=""
+while [ $# -gt 0 ]; do
+  case "$1" in
+    -S|-n) mode="$1"; shift ;;
+    -p) prompt="$2"; shift 2 ;;
+    *) break ;;
+  esac
+done
+if [ "$mode" = "-n" ]; then
+  echo "sudo: a password is required" >&2
+  exit 1
+fi
+printf '%s' "$prompt" >&2
+read -r password
+if [ "$password" != "sudo-password" ]; then
+  echo "sudo: incorrect password" >&2
+  exit 1
+fi
+exec "$@"
+`
+
+// testSSHServer is an SSH server that runs commands with the local sh, serves the local file system over SFTP and
+// forwards TCP connections, to test sessions without a remote host.
+type testSSHServer struct {
+	host     *ssh.Host
+	config   *gossh.ServerConfig
+	env      []string
+	forwards atomic.Int32
+}
+
+func startTestSSHServer(t *testing.T, env ...string) *testSSHServer {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := gossh.NewSignerFromKey(privateKey)
+	require.NoError(t, err)
+
+	server := &testSSHServer{
+		config: &gossh.ServerConfig{
+			PasswordCallback: func(conn gossh.ConnMetadata, password []byte) (*gossh.Permissions, error) {
+				if conn.User() != testSSHUser || string(password) != testSSHPassword {
+					return nil, errors.New("access denied")
+				}
+
+				return nil, nil
+			},
+		},
+		env: append(os.Environ(), env...),
+	}
+	server.config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go server.serve(conn)
+		}
+	}()
+
+	server.host = &ssh.Host{
+		Hostname:    "127.0.0.1",
+		CustomPort:  listener.Addr().(*net.TCPAddr).Port,
+		SshUserName: testSSHUser,
+		Password:    testSSHPassword,
+	}
+
+	return server
+}
+
+func (server *testSSHServer) serve(conn net.Conn) {
+	serverConn, channels, requests, err := gossh.NewServerConn(conn, server.config)
+	if err != nil {
+		return
+	}
+
+	defer func() { _ = serverConn.Close() }()
+
+	go gossh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		switch newChannel.ChannelType() {
+		case "session":
+			go server.serveSession(newChannel)
+		case "direct-tcpip":
+			go server.forward(newChannel)
+		default:
+			_ = newChannel.Reject(gossh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func (server *testSSHServer) serveSession(newChannel gossh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+
+	for request := range requests {
+		if request.Type == "subsystem" {
+			server.serveSubsystem(channel, request)
+			continue
+		}
+
+		if request.Type != "exec" {
+			_ = request.Reply(false, nil)
+			continue
+		}
+
+		var payload struct{ Command string }
+		if err := gossh.Unmarshal(request.Payload, &payload); err != nil {
+			_ = request.Reply(false, nil)
+			continue
+		}
+
+		_ = request.Reply(true, nil)
+
+		go server.run(channel, payload.Command)
+	}
+}
+
+func (server *testSSHServer) serveSubsystem(channel gossh.Channel, request *gossh.Request) {
+	var payload struct{ Name string }
+	if err := gossh.Unmarshal(request.Payload, &payload); err != nil || payload.Name != "sftp" {
+		_ = request.Reply(false, nil)
+		return
+	}
+
+	_ = request.Reply(true, nil)
+
+	sftpServer, err := sftp.NewServer(channel)
+	if err != nil {
+		_ = channel.Close()
+		return
+	}
+
+	go func() {
+		_ = sftpServer.Serve()
+		_ = sftpServer.Close()
+	}()
+}
+
+func (server *testSSHServer) run(channel gossh.Channel, command string) {
+	defer func() { _ = channel.Close() }()
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = server.env
+	cmd.Stdout = channel
+	cmd.Stderr = channel.Stderr()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return
+	}
+
+	go func() {
+		_, _ = io.Copy(stdin, channel)
+		_ = stdin.Close()
+	}()
+
+	status := 0
+
+	if err := cmd.Run(); err != nil {
+		status = 255
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			status = exitErr.ExitCode()
+		}
+	}
+
+	_, _ = channel.SendRequest("exit-status", false, gossh.Marshal(struct{ Status uint32 }{uint32(status)}))
+}
+
+func (server *testSSHServer) forward(newChannel gossh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+
+	if err := gossh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = newChannel.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	server.forwards.Add(1)
+
+	go gossh.DiscardRequests(requests)
+
+	go func() {
+		_, _ = io.Copy(channel, conn)
+		_ = channel.Close()
+	}()
+
+	_, _ = io.Copy(conn, channel)
+	_ = conn.Close()
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(data []byte) (int, error) {
+	return f(data)
+}
+
+func TestSessionRunsCommands(t *testing.T) {
+	t.Parallel()
+
+	server := startTestSSHServer(t)
+	session := ssh.NewSessionContext(t, t.Context(), server.host, &ssh.SessionOptions{Logger: logger.Discard})
+
+	result, err := session.RunContextE(t, t.Context(), &ssh.Command{Command: "echo out; echo err >&2; exit 3"})
+
+	var commandErr *ssh.CommandError
+	require.ErrorAs(t, err, &commandErr)
+	assert.Equal(t, 3, commandErr.ExitCode)
+	assert.Equal(t, &ssh.CommandResult{Stdout: "out\n", Stderr: "err\n", ExitCode: 3}, result)
+
+	result = session.RunContext(t, t.Context(), &ssh.Command{
+		Command: `echo "$GREETING, $NAME"; cat`,
+		Env:     map[string]string{"GREETING": "Hello", "NAME": "it's me"},
+		Stdin:   strings.NewReader("input"),
+	})
+	assert.Equal(t, "Hello, it's me\ninput", result.Stdout)
+	assert.Empty(t, result.Stderr)
+	assert.Equal(t, 0, result.ExitCode)
+
+	require.NotNil(t, session.Client())
+
+	session.Close(t)
+
+	_, err = session.RunContextE(t, t.Context(), &ssh.Command{Command: "true"})
+	require.ErrorIs(t, err, ssh.ErrSessionClosed)
+}
+
+func TestSessionStreamsOutput(t *testing.T) {
+	t.Parallel()
+
+	server := startTestSSHServer(t)
+	session := ssh.NewSessionContext(t, t.Context(), server.host, &ssh.SessionOptions{Logger: logger.Discard})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+
+	// The command only finishes once its stdin is closed, which only happens if its output is streamed while it runs
+	stdinReader, stdinWriter := io.Pipe()
+	stdout := writerFunc(func(data []byte) (int, error) {
+		if strings.Contains(string(data), "ready") {
+			_ = stdinWriter.Close()
+		}
+
+		return len(data), nil
+	})
+
+	result := session.RunContext(t, ctx, &ssh.Command{Command: "echo ready; cat; echo done", Stdin: stdinReader, Stdout: stdout})
+	assert.Equal(t, "ready\ndone\n", result.Stdout)
+}
+
+func TestSessionThroughJumpHosts(t *testing.T) {
+	t.Parallel()
+
+	firstJumpHost := startTestSSHServer(t)
+	secondJumpHost := startTestSSHServer(t)
+	target := startTestSSHServer(t, "SERVER_NAME=target")
+
+	session := ssh.NewSessionContext(t, t.Context(), target.host, &ssh.SessionOptions{
+		Logger:    logger.Discard,
+		JumpHosts: []*ssh.Host{firstJumpHost.host, secondJumpHost.host},
+	})
+
+	for range 2 {
+		result := session.RunContext(t, t.Context(), &ssh.Command{Command: "echo $SERVER_NAME"})
+		assert.Equal(t, "target\n", result.Stdout)
+	}
+
+	// Both commands ran over the same connection, tunneled through each jump host once
+	assert.Equal(t, int32(1), firstJumpHost.forwards.Load())
+	assert.Equal(t, int32(1), secondJumpHost.forwards.Load())
+	assert.Equal(t, int32(0), target.forwards.Load())
+}
+
+func TestSessionUseSudo(t *testing.T) {
+	t.Parallel()
+
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "sudo"), []byte(fakeSudo), 0o755)) //nolint:gosec // the fake sudo must be executable
+
+	server := startTestSSHServer(t, "PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	session := ssh.NewSessionContext(t, t.Context(), server.host, &ssh.SessionOptions{Logger: logger.Discard})
+
+	result := session.RunContext(t, t.Context(), &ssh.Command{
+		Command:      `echo "$GREETING"; cat`,
+		Env:          map[string]string{"GREETING": "Hello"},
+		Stdin:        strings.NewReader("input"),
+		UseSudo:      true,
+		SudoPassword: "sudo-password",
+	})
+	assert.Equal(t, "Hello\ninput", result.Stdout)
+	assert.Empty(t, result.Stderr)
+
+	result, err := session.RunContextE(t, t.Context(), &ssh.Command{Command: "true", UseSudo: true})
+	require.Error(t, err)
+	assert.Equal(t, "sudo: incorrect password\n", result.Stderr)
+
+	server.host.Password = ""
+
+	result, err = session.RunContextE(t, t.Context(), &ssh.Command{Command: "true", UseSudo: true})
+	require.Error(t, err)
+	assert.Equal(t, "sudo: a password is required\n", result.Stderr)
+}
@@ -0,0 +1,188 @@
+package ssh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/pkg/sftp"
+)
+
+// RemoteFileMismatchError is returned when a file on a host does not have the expected mode, owner, checksum or
+// contents.
+type RemoteFileMismatchError struct {
+	Host     string
+	Path     string
+	Property string
+	Expected string
+	Actual   string
+}
+
+func (err *RemoteFileMismatchError) Error() string {
+	return fmt.Sprintf("%s of remote file %s on %s: expected %s, got %s", err.Property, err.Path, err.Host, err.Expected, err.Actual)
+}
+
+// RequireRemoteFileExistsContext checks, using SFTP, that a file or directory exists at remotePath on the host of the
+// session. This will fail the test if it does not exist. The ctx parameter supports cancellation.
+func RequireRemoteFileExistsContext(t testing.TestingT, ctx context.Context, session *Session, remotePath string) {
+	if err := RequireRemoteFileExistsContextE(t, ctx, session, remotePath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RequireRemoteFileExistsContextE checks, using SFTP, that a file or directory exists at remotePath on the host of the
+// session, and returns an error wrapping fs.ErrNotExist if it does not. The ctx parameter supports cancellation.
+func RequireRemoteFileExistsContextE(t testing.TestingT, ctx context.Context, session *Session, remotePath string) error {
+	_, err := statRemoteFile(ctx, session, remotePath)
+
+	return err
+}
+
+// RequireRemoteFileModeContext checks, using SFTP, that the permissions of the file at remotePath on the host of the
+// session, including the setuid, setgid and sticky bits, are the given mode. This will fail the test if they are not.
+// The ctx parameter supports cancellation.
+func RequireRemoteFileModeContext(t testing.TestingT, ctx context.Context, session *Session, remotePath string, mode fs.FileMode) {
+	if err := RequireRemoteFileModeContextE(t, ctx, session, remotePath, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RequireRemoteFileModeContextE checks, using SFTP, that the permissions of the file at remotePath on the host of the
+// session, including the setuid, setgid and sticky bits, are the given mode, and returns a *RemoteFileMismatchError if
+// they are not. The ctx parameter supports cancellation.
+func RequireRemoteFileModeContextE(t testing.TestingT, ctx context.Context, session *Session, remotePath string, mode fs.FileMode) error {
+	info, err := statRemoteFile(ctx, session, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if actual := info.Mode() & preservedModeBits; actual != mode&preservedModeBits {
+		return session.mismatch(remotePath, "mode", (mode & preservedModeBits).String(), actual.String())
+	}
+
+	return nil
+}
+
+// RequireRemoteFileOwnerContext checks, using SFTP, that the file at remotePath on the host of the session is owned by
+// the user and group with the given numeric ids. This will fail the test if it is not. The ctx parameter supports
+// cancellation.
+func RequireRemoteFileOwnerContext(t testing.TestingT, ctx context.Context, session *Session, remotePath string, uid, gid int) {
+	if err := RequireRemoteFileOwnerContextE(t, ctx, session, remotePath, uid, gid); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RequireRemoteFileOwnerContextE checks, using SFTP, that the file at remotePath on the host of the session is owned by
+// the user and group with the given numeric ids, and returns a *RemoteFileMismatchError if it is not. The ctx parameter
+// supports cancellation.
+func RequireRemoteFileOwnerContextE(t testing.TestingT, ctx context.Context, session *Session, remotePath string, uid, gid int) error {
+	info, err := statRemoteFile(ctx, session, remotePath)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*sftp.FileStat)
+	if !ok {
+		return fmt.Errorf("the owner of remote file %s is unknown", remotePath)
+	}
+
+	expected := strconv.Itoa(uid) + ":" + strconv.Itoa(gid)
+	if actual := fmt.Sprintf("%d:%d", stat.UID, stat.GID); actual != expected {
+		return session.mismatch(remotePath, "owner", expected, actual)
+	}
+
+	return nil
+}
+
+// RequireRemoteFileChecksumContext checks, using SFTP, that the SHA-256 checksum of the file at remotePath on the host
+// of the session is the given hex encoded checksum, as printed by sha256sum. This will fail the test if it is not. The
+// ctx parameter supports cancellation.
+func RequireRemoteFileChecksumContext(t testing.TestingT, ctx context.Context, session *Session, remotePath string, sha256Sum string) {
+	if err := RequireRemoteFileChecksumContextE(t, ctx, session, remotePath, sha256Sum); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RequireRemoteFileChecksumContextE checks, using SFTP, that the SHA-256 checksum of the file at remotePath on the host
+// of the session is the given hex encoded checksum, as printed by sha256sum, and returns a *RemoteFileMismatchError if
+// it is not. The ctx parameter supports cancellation.
+func RequireRemoteFileChecksumContextE(t testing.TestingT, ctx context.Context, session *Session, remotePath string, sha256Sum string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	client, err := session.SFTPClient()
+	if err != nil {
+		return err
+	}
+
+	file, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("error opening remote file %s: %w", remotePath, err)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("error reading remote file %s: %w", remotePath, err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, sha256Sum) {
+		return session.mismatch(remotePath, "sha256 checksum", sha256Sum, actual)
+	}
+
+	return nil
+}
+
+// RequireRemoteFileContainsContext checks, using SFTP, that the file at remotePath on the host of the session contains
+// the given text. This will fail the test if it does not. The ctx parameter supports cancellation.
+func RequireRemoteFileContainsContext(t testing.TestingT, ctx context.Context, session *Session, remotePath string, text string) {
+	if err := RequireRemoteFileContainsContextE(t, ctx, session, remotePath, text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RequireRemoteFileContainsContextE checks, using SFTP, that the file at remotePath on the host of the session contains
+// the given text, and returns a *RemoteFileMismatchError if it does not. The ctx parameter supports cancellation.
+func RequireRemoteFileContainsContextE(t testing.TestingT, ctx context.Context, session *Session, remotePath string, text string) error {
+	contents, err := session.ReadFileContextE(t, ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(string(contents), text) {
+		return session.mismatch(remotePath, "contents", fmt.Sprintf("text containing %q", text), fmt.Sprintf("%d bytes without it", len(contents)))
+	}
+
+	return nil
+}
+
+// statRemoteFile returns the info of the file at remotePath on the host of the session.
+func statRemoteFile(ctx context.Context, session *Session, remotePath string) (fs.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := session.SFTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := client.Stat(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading remote file %s on %s: %w", remotePath, session.host.Hostname, err)
+	}
+
+	return info, nil
+}
+
+func (session *Session) mismatch(remotePath, property, expected, actual string) *RemoteFileMismatchError {
+	return &RemoteFileMismatchError{Host: session.host.Hostname, Path: remotePath, Property: property, Expected: expected, Actual: actual}
+}
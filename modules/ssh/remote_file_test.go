@@ -0,0 +1,57 @@
+package ssh_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteFileAssertions(t *testing.T) {
+	t.Parallel()
+
+	server := startTestSSHServer(t)
+	session := ssh.NewSessionContext(t, t.Context(), server.host, &ssh.SessionOptions{Logger: logger.Discard})
+
+	remotePath := filepath.Join(t.TempDir(), "app.conf")
+	contents := []byte("listen_port = 8080\n")
+	checksum := sha256.Sum256(contents)
+
+	session.WriteFileContext(t, t.Context(), remotePath, contents, 0o640)
+	assert.Equal(t, contents, session.ReadFileContext(t, t.Context(), remotePath))
+
+	ssh.RequireRemoteFileExistsContext(t, t.Context(), session, remotePath)
+	ssh.RequireRemoteFileModeContext(t, t.Context(), session, remotePath, 0o640)
+	ssh.RequireRemoteFileOwnerContext(t, t.Context(), session, remotePath, os.Getuid(), os.Getgid())
+	ssh.RequireRemoteFileChecksumContext(t, t.Context(), session, remotePath, hex.EncodeToString(checksum[:]))
+	ssh.RequireRemoteFileContainsContext(t, t.Context(), session, remotePath, "listen_port = 8080")
+
+	err := ssh.RequireRemoteFileExistsContextE(t, t.Context(), session, remotePath+".missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	var mismatch *ssh.RemoteFileMismatchError
+
+	err = ssh.RequireRemoteFileModeContextE(t, t.Context(), session, remotePath, 0o600)
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "mode", mismatch.Property)
+	assert.Equal(t, "-rw-r-----", mismatch.Actual)
+
+	err = ssh.RequireRemoteFileOwnerContextE(t, t.Context(), session, remotePath, os.Getuid()+1, os.Getgid())
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "owner", mismatch.Property)
+
+	err = ssh.RequireRemoteFileChecksumContextE(t, t.Context(), session, remotePath, hex.EncodeToString(make([]byte, sha256.Size)))
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, hex.EncodeToString(checksum[:]), mismatch.Actual)
+
+	err = ssh.RequireRemoteFileContainsContextE(t, t.Context(), session, remotePath, "listen_port = 443")
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "contents", mismatch.Property)
+}
@@ -0,0 +1,438 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/pkg/sftp"
+)
+
+// preservedModeBits are the bits of the file modes that are copied along with the files.
+const preservedModeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// TransferOptions are the options for copying files and directories between the local machine and a host with
+// [Session.UploadContextE] and [Session.DownloadContextE]. The permissions of the files and directories are preserved.
+type TransferOptions struct {
+	// Source is the file or directory to copy. Directories are copied recursively.
+	Source string
+	// Destination is the path of the copy of Source. Parent directories are created as needed.
+	Destination string
+	// FileNameFilters are glob patterns, as supported by path.Match, such as *.log. If set, only the files whose name or
+	// path relative to Source matches one of the patterns are copied, and only the directories that contain them.
+	FileNameFilters []string
+	// MaxFileSizeMB is the maximum file size in megabytes to copy. Larger files are skipped. No limit if 0.
+	MaxFileSizeMB int
+	// PreserveOwnership sets the numeric owner and group of the copies to those of the originals, which usually
+	// requires root on the destination. Only supported on Unix.
+	PreserveOwnership bool
+}
+
+// copiedDir is a directory that was copied, whose attributes are set once all the files in it are copied, so that a
+// read-only directory can be filled first.
+type copiedDir struct {
+	info fs.FileInfo
+	path string
+}
+
+// SFTPClient returns the SFTP client of the session, starting the SFTP subsystem on the host on the first call. The
+// client is closed along with the session.
+func (session *Session) SFTPClient() (*sftp.Client, error) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if len(session.clients) == 0 {
+		return nil, ErrSessionClosed
+	}
+
+	if session.sftp == nil {
+		client, err := sftp.NewClient(session.clients[len(session.clients)-1])
+		if err != nil {
+			return nil, fmt.Errorf("error starting sftp on %s: %w", session.host.Hostname, err)
+		}
+
+		session.sftp = client
+	}
+
+	return session.sftp, nil
+}
+
+// WriteFileContext writes the contents to the file at remotePath on the host using SFTP, creating it with the given
+// mode if it does not exist, and setting the mode otherwise. This will fail the test if the file can't be written. The
+// ctx parameter supports cancellation.
+func (session *Session) WriteFileContext(t testing.TestingT, ctx context.Context, remotePath string, contents []byte, mode os.FileMode) {
+	if err := session.WriteFileContextE(t, ctx, remotePath, contents, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WriteFileContextE writes the contents to the file at remotePath on the host using SFTP, creating it with the given
+// mode if it does not exist, and setting the mode otherwise. The ctx parameter supports cancellation.
+func (session *Session) WriteFileContextE(t testing.TestingT, ctx context.Context, remotePath string, contents []byte, mode os.FileMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	client, err := session.SFTPClient()
+	if err != nil {
+		return err
+	}
+
+	session.logger.Logf(t, "Writing remote file %s on %s", remotePath, session.host.Hostname)
+
+	file, err := client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("error opening remote file %s: %w", remotePath, err)
+	}
+
+	if _, err := file.Write(contents); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("error writing remote file %s: %w", remotePath, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing remote file %s: %w", remotePath, err)
+	}
+
+	return client.Chmod(remotePath, mode&preservedModeBits)
+}
+
+// ReadFileContext returns the contents of the file at remotePath on the host, read using SFTP. This will fail the test
+// if the file can't be read. The ctx parameter supports cancellation.
+func (session *Session) ReadFileContext(t testing.TestingT, ctx context.Context, remotePath string) []byte {
+	contents, err := session.ReadFileContextE(t, ctx, remotePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return contents
+}
+
+// ReadFileContextE returns the contents of the file at remotePath on the host, read using SFTP. The ctx parameter
+// supports cancellation.
+func (session *Session) ReadFileContextE(t testing.TestingT, ctx context.Context, remotePath string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := session.SFTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := client.Open(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening remote file %s: %w", remotePath, err)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	return io.ReadAll(file)
+}
+
+// UploadContext copies the local file or directory options.Source to options.Destination on the host using SFTP, which
+// works on hosts without scp. This will fail the test if any file can't be copied. The ctx parameter supports
+// cancellation between files.
+func (session *Session) UploadContext(t testing.TestingT, ctx context.Context, options *TransferOptions) {
+	if err := session.UploadContextE(t, ctx, options); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// UploadContextE copies the local file or directory options.Source to options.Destination on the host using SFTP,
+// which works on hosts without scp. The ctx parameter supports cancellation between files.
+func (session *Session) UploadContextE(t testing.TestingT, ctx context.Context, options *TransferOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	client, err := session.SFTPClient()
+	if err != nil {
+		return err
+	}
+
+	var dirs []copiedDir
+
+	err = filepath.WalkDir(options.Source, func(localPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(options.Source, localPath)
+		if err != nil {
+			return err
+		}
+
+		relative = filepath.ToSlash(relative)
+		remotePath := path.Join(options.Destination, relative)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			if len(options.FileNameFilters) == 0 {
+				if err := client.MkdirAll(remotePath); err != nil {
+					return fmt.Errorf("error creating remote directory %s: %w", remotePath, err)
+				}
+			}
+
+			dirs = append(dirs, copiedDir{info: info, path: remotePath})
+
+			return nil
+		case !info.Mode().IsRegular():
+			session.logger.Logf(t, "Skipping %s, which is not a regular file", localPath)
+			return nil
+		case !options.shouldCopy(relative, info):
+			return nil
+		}
+
+		session.logger.Logf(t, "Copying local file %s to remote path %s on %s", localPath, remotePath, session.host.Hostname)
+
+		if err := client.MkdirAll(path.Dir(remotePath)); err != nil {
+			return fmt.Errorf("error creating remote directory %s: %w", path.Dir(remotePath), err)
+		}
+
+		if err := uploadFile(client, localPath, remotePath); err != nil {
+			return err
+		}
+
+		return setRemoteAttributes(client, remotePath, info, options)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range slices.Backward(dirs) {
+		if _, err := client.Stat(dir.path); err != nil {
+			// The directory has no files that match the filters, so it was not created
+			continue
+		}
+
+		if err := setRemoteAttributes(client, dir.path, dir.info, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DownloadContext copies the file or directory options.Source on the host to the local path options.Destination using
+// SFTP, which works on hosts without scp. This will fail the test if any file can't be copied. The ctx parameter
+// supports cancellation between files.
+func (session *Session) DownloadContext(t testing.TestingT, ctx context.Context, options *TransferOptions) {
+	if err := session.DownloadContextE(t, ctx, options); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// DownloadContextE copies the file or directory options.Source on the host to the local path options.Destination using
+// SFTP, which works on hosts without scp. The ctx parameter supports cancellation between files.
+func (session *Session) DownloadContextE(t testing.TestingT, ctx context.Context, options *TransferOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	client, err := session.SFTPClient()
+	if err != nil {
+		return err
+	}
+
+	source := path.Clean(options.Source)
+
+	var dirs []copiedDir
+
+	walker := client.Walk(source)
+
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		remotePath, info := walker.Path(), walker.Stat()
+
+		relative := strings.TrimPrefix(strings.TrimPrefix(remotePath, source), "/")
+		if relative == "" {
+			relative = "."
+		}
+
+		localPath := filepath.Join(options.Destination, filepath.FromSlash(relative))
+
+		switch {
+		case info.IsDir():
+			if len(options.FileNameFilters) == 0 {
+				if err := os.MkdirAll(localPath, defaultDirPermissions); err != nil {
+					return err
+				}
+			}
+
+			dirs = append(dirs, copiedDir{info: info, path: localPath})
+
+			continue
+		case !info.Mode().IsRegular():
+			session.logger.Logf(t, "Skipping remote path %s, which is not a regular file", remotePath)
+			continue
+		case !options.shouldCopy(relative, info):
+			continue
+		}
+
+		session.logger.Logf(t, "Copying remote file %s on %s to local path %s", remotePath, session.host.Hostname, localPath)
+
+		if err := os.MkdirAll(filepath.Dir(localPath), defaultDirPermissions); err != nil {
+			return err
+		}
+
+		if err := downloadFile(client, remotePath, localPath); err != nil {
+			return err
+		}
+
+		if err := setLocalAttributes(localPath, info, options); err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range slices.Backward(dirs) {
+		if _, err := os.Stat(dir.path); err != nil {
+			// The directory has no files that match the filters, so it was not created
+			continue
+		}
+
+		if err := setLocalAttributes(dir.path, dir.info, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validate returns an error if a file name filter is not a valid pattern.
+func (options *TransferOptions) validate() error {
+	for _, filter := range options.FileNameFilters {
+		if _, err := path.Match(filter, ""); err != nil {
+			return fmt.Errorf("invalid file name filter %q: %w", filter, err)
+		}
+	}
+
+	return nil
+}
+
+// shouldCopy returns whether the file at the given path relative to the source matches the filters and size limit.
+func (options *TransferOptions) shouldCopy(relative string, info fs.FileInfo) bool {
+	if options.MaxFileSizeMB > 0 && info.Size() > int64(options.MaxFileSizeMB)<<20 {
+		return false
+	}
+
+	if len(options.FileNameFilters) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(options.FileNameFilters, func(filter string) bool {
+		nameMatches, _ := path.Match(filter, info.Name())
+		pathMatches, _ := path.Match(filter, relative)
+
+		return nameMatches || pathMatches
+	})
+}
+
+func uploadFile(client *sftp.Client, localPath, remotePath string) error {
+	local, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = local.Close() }()
+
+	remote, err := client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("error opening remote file %s: %w", remotePath, err)
+	}
+
+	if _, err := remote.ReadFrom(local); err != nil {
+		_ = remote.Close()
+
+		return fmt.Errorf("error writing remote file %s: %w", remotePath, err)
+	}
+
+	return remote.Close()
+}
+
+func downloadFile(client *sftp.Client, remotePath, localPath string) error {
+	remote, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("error opening remote file %s: %w", remotePath, err)
+	}
+
+	defer func() { _ = remote.Close() }()
+
+	local, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:mnd // the mode of the remote file is set once it is written
+	if err != nil {
+		return err
+	}
+
+	if _, err := remote.WriteTo(local); err != nil {
+		_ = local.Close()
+
+		return fmt.Errorf("error reading remote file %s: %w", remotePath, err)
+	}
+
+	return local.Close()
+}
+
+// setRemoteAttributes sets the mode, and the owner if requested, of the remote copy of the local file.
+func setRemoteAttributes(client *sftp.Client, remotePath string, info fs.FileInfo, options *TransferOptions) error {
+	if err := client.Chmod(remotePath, info.Mode()&preservedModeBits); err != nil {
+		return fmt.Errorf("error setting the mode of remote file %s: %w", remotePath, err)
+	}
+
+	if !options.PreserveOwnership {
+		return nil
+	}
+
+	uid, gid, ok := localFileOwner(info)
+	if !ok {
+		return fmt.Errorf("can't preserve the owner of %s: %w", info.Name(), errors.ErrUnsupported)
+	}
+
+	if err := client.Chown(remotePath, uid, gid); err != nil {
+		return fmt.Errorf("error setting the owner of remote file %s: %w", remotePath, err)
+	}
+
+	return nil
+}
+
+// setLocalAttributes sets the mode, and the owner if requested, of the local copy of the remote file.
+func setLocalAttributes(localPath string, info fs.FileInfo, options *TransferOptions) error {
+	if err := os.Chmod(localPath, info.Mode()&preservedModeBits); err != nil {
+		return err
+	}
+
+	if !options.PreserveOwnership {
+		return nil
+	}
+
+	stat, ok := info.Sys().(*sftp.FileStat)
+	if !ok {
+		return fmt.Errorf("can't preserve the owner of remote file %s: %w", info.Name(), errors.ErrUnsupported)
+	}
+
+	return os.Lchown(localPath, int(stat.UID), int(stat.GID))
+}
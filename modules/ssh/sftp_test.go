@@ -0,0 +1,77 @@
+package ssh_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestTree creates files with the given modes, and their parent directories, under dir.
+func writeTestTree(t *testing.T, dir string, files map[string]fs.FileMode) {
+	t.Helper()
+
+	for name, mode := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("contents of "+name), mode))
+		require.NoError(t, os.Chmod(path, mode))
+	}
+}
+
+func TestSessionUploadAndDownload(t *testing.T) {
+	t.Parallel()
+
+	server := startTestSSHServer(t)
+	session := ssh.NewSessionContext(t, t.Context(), server.host, &ssh.SessionOptions{Logger: logger.Discard})
+
+	source := t.TempDir()
+	writeTestTree(t, source, map[string]fs.FileMode{
+		"config.txt":          0o640,
+		"logs/app.log":        0o600,
+		"logs/data.bin":       0o644,
+		"scripts/deep/run.sh": 0o755,
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(source, "empty"), 0o755))
+	require.NoError(t, os.Chmod(filepath.Join(source, "logs"), 0o750))
+
+	remoteDir := filepath.Join(t.TempDir(), "remote")
+	session.UploadContext(t, t.Context(), &ssh.TransferOptions{Source: source, Destination: remoteDir})
+
+	ssh.RequireRemoteFileModeContext(t, t.Context(), session, remoteDir+"/config.txt", 0o640)
+	ssh.RequireRemoteFileModeContext(t, t.Context(), session, remoteDir+"/logs", 0o750|fs.ModeDir)
+	ssh.RequireRemoteFileModeContext(t, t.Context(), session, remoteDir+"/scripts/deep/run.sh", 0o755)
+	ssh.RequireRemoteFileContainsContext(t, t.Context(), session, remoteDir+"/logs/app.log", "contents of logs/app.log")
+	ssh.RequireRemoteFileExistsContext(t, t.Context(), session, remoteDir+"/empty")
+
+	localDir := filepath.Join(t.TempDir(), "local")
+	session.DownloadContext(t, t.Context(), &ssh.TransferOptions{
+		Source:          remoteDir,
+		Destination:     localDir,
+		FileNameFilters: []string{"*.log", "scripts/*/*.sh"},
+	})
+
+	contents, err := os.ReadFile(filepath.Join(localDir, "logs", "app.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "contents of logs/app.log", string(contents))
+
+	for name, mode := range map[string]fs.FileMode{"logs/app.log": 0o600, "logs": 0o750 | fs.ModeDir, "scripts/deep/run.sh": 0o755} {
+		info, err := os.Stat(filepath.Join(localDir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		assert.Equal(t, mode, info.Mode(), name)
+	}
+
+	// Only the files that match the filters, and the directories that contain them, are copied
+	for _, name := range []string{"config.txt", "logs/data.bin", "empty"} {
+		assert.NoFileExists(t, filepath.Join(localDir, filepath.FromSlash(name)))
+		assert.NoDirExists(t, filepath.Join(localDir, filepath.FromSlash(name)))
+	}
+
+	err = session.UploadContextE(t, t.Context(), &ssh.TransferOptions{Source: source, Destination: remoteDir, FileNameFilters: []string{"["}})
+	require.Error(t, err)
+}
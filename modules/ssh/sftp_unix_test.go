@@ -0,0 +1,45 @@
+//go:build unix
+
+package ssh_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTransferPreservesOwnership(t *testing.T) {
+	t.Parallel()
+
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of files requires root")
+	}
+
+	server := startTestSSHServer(t)
+	session := ssh.NewSessionContext(t, t.Context(), server.host, &ssh.SessionOptions{Logger: logger.Discard})
+
+	source := filepath.Join(t.TempDir(), "owned.txt")
+	require.NoError(t, os.WriteFile(source, []byte("owned"), 0o644))
+	require.NoError(t, os.Chown(source, 1234, 5678))
+
+	remotePath := filepath.Join(t.TempDir(), "owned.txt")
+	session.UploadContext(t, t.Context(), &ssh.TransferOptions{Source: source, Destination: remotePath, PreserveOwnership: true})
+	ssh.RequireRemoteFileOwnerContext(t, t.Context(), session, remotePath, 1234, 5678)
+
+	localPath := filepath.Join(t.TempDir(), "owned.txt")
+	session.DownloadContext(t, t.Context(), &ssh.TransferOptions{Source: remotePath, Destination: localPath, PreserveOwnership: true})
+
+	info, err := os.Stat(localPath)
+	require.NoError(t, err)
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	require.True(t, ok)
+	assert.Equal(t, uint32(1234), stat.Uid)
+	assert.Equal(t, uint32(5678), stat.Gid)
+}
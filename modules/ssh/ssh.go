@@ -105,7 +105,7 @@ func SCPFileToContext(t testing.TestingT, ctx context.Context, host *Host, mode
 }
 
 // SCPFileToContextE uploads the contents using SCP to the given host and returns an error if the process fails.
-// The ctx parameter supports cancellation and timeouts.
+// The ctx parameter supports cancellation and timeouts. On hosts without scp, use [Session.WriteFileContextE] instead.
 func SCPFileToContextE(t testing.TestingT, ctx context.Context, host *Host, mode os.FileMode, remotePath, contents string) error {
 	authMethods, err := createAuthMethodsForHost(ctx, host)
 	if err != nil {
@@ -222,7 +222,8 @@ func SCPDirFromContext(t testing.TestingT, ctx context.Context, options *SCPDown
 // SCPDirFromContextE downloads all the files from remotePath on the given host using SCP
 // and returns an error if the process fails. Only files within remotePath will
 // be downloaded. This function will not recursively download subdirectories or follow
-// symlinks.
+// symlinks. To download recursively, preserving permissions, or from hosts without scp, use
+// [Session.DownloadContextE] instead.
 // The ctx parameter supports cancellation and timeouts.
 func SCPDirFromContextE(t testing.TestingT, ctx context.Context, options *SCPDownloadOptions, useSudo bool) error {
 	authMethods, err := createAuthMethodsForHost(ctx, &options.RemoteHost)
@@ -568,6 +569,38 @@ func CheckPrivateSSHConnectionContextE(t testing.TestingT, ctx context.Context,
 	return runSSHCommand(ctx, t, sshSession)
 }
 
+// NewClientContext connects to the given host via SSH and returns the client. Use the Dial method of the client to open
+// TCP connections from the host, such as to reach private services through a bastion host. Make sure to call the
+// Close() method on the client when you're done! The ctx parameter supports cancellation and timeouts. This will fail
+// the test if the connection fails.
+func NewClientContext(t testing.TestingT, ctx context.Context, host *Host) *ssh.Client {
+	client, err := NewClientContextE(t, ctx, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// NewClientContextE connects to the given host via SSH and returns the client. Use the Dial method of the client to
+// open TCP connections from the host, such as to reach private services through a bastion host. Make sure to call the
+// Close() method on the client when you're done! The ctx parameter supports cancellation and timeouts.
+func NewClientContextE(t testing.TestingT, ctx context.Context, host *Host) (*ssh.Client, error) {
+	authMethods, err := createAuthMethodsForHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Default.Logf(t, "Connecting to %s@%s via SSH", host.SshUserName, host.Hostname)
+
+	return createSSHClient(ctx, &SSHConnectionOptions{
+		Username:    host.SshUserName,
+		Address:     host.Hostname,
+		Port:        host.GetPort(),
+		AuthMethods: authMethods,
+	})
+}
+
 // FetchContentsOfFiles connects to the given host via SSH and fetches the contents of the files at the given filePaths.
 // If useSudo is true, then the contents will be retrieved using sudo. Returns a map from file path to contents.
 // This will fail the test if the connection fails.
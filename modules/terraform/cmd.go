@@ -8,6 +8,7 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/testing"
@@ -65,6 +66,8 @@ func GetCommonOptions(options *Options, args ...string) (*Options, []string) {
 		options.EnvVars["SSH_AUTH_SOCK"] = options.SshAgent.SocketFile()
 	}
 
+	registerSensitiveVars(options)
+
 	return options, args
 }
 
@@ -85,7 +88,7 @@ func RunTerraformCommandContextE(t testing.TestingT, ctx context.Context, additi
 	cmd := generateCommand(options, args...)
 	description := fmt.Sprintf("%s %v", options.TerraformBinary, args)
 
-	return retry.DoWithRetryableErrorsContextE(t, ctx, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
+	return retry.DoWithRetryableErrorsPolicyContextE(t, ctx, description, options.RetryableTerraformErrors, options.retryPolicy(), func() (string, error) {
 		s, err := shell.RunCommandContextAndGetOutputE(t, ctx, &cmd)
 		if err != nil {
 			return s, err
@@ -165,7 +168,7 @@ func RunTerraformCommandAndGetStdOutErrCodeContextE(t testing.TestingT, ctx cont
 
 	exit = DefaultErrorExitCode
 
-	_, err = retry.DoWithRetryableErrorsContextE(t, ctx, description, options.RetryableTerraformErrors, options.MaxRetries, options.TimeBetweenRetries, func() (string, error) {
+	_, err = retry.DoWithRetryableErrorsPolicyContextE(t, ctx, description, options.RetryableTerraformErrors, options.retryPolicy(), func() (string, error) {
 		stdout, stderr, err = shell.RunCommandContextAndGetStdOutErrE(t, ctx, &cmd)
 		if err != nil {
 			exitCode, getExitCodeErr := shell.GetExitCodeForRunCommandError(err)
@@ -218,7 +221,7 @@ func GetExitCodeForTerraformCommandContext(t testing.TestingT, ctx context.Conte
 func GetExitCodeForTerraformCommandContextE(t testing.TestingT, ctx context.Context, additionalOptions *Options, additionalArgs ...string) (int, error) {
 	options, args := GetCommonOptions(additionalOptions, additionalArgs...)
 
-	additionalOptions.Logger.Logf(t, "Running %s with args %v", options.TerraformBinary, args)
+	additionalOptions.Logger.WithFields(logger.Fields{"command": options.TerraformBinary, "working_dir": options.TerraformDir}).Logf(t, "Running %s with args %v", options.TerraformBinary, args)
 
 	cmd := generateCommand(options, args...)
 
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/ssh"
 	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/jinzhu/copier"
@@ -57,6 +58,7 @@ type Options struct {
 	Vars map[string]any
 
 	RetryableTerraformErrors map[string]string // If Terraform apply fails with one of these (transient) errors, retry. The keys are a regexp to match against the error and the message is what to display to a user if that error is matched.
+	RetryPolicy              *retry.Policy     // If set, decides when to retry errors matching RetryableTerraformErrors instead of MaxRetries and TimeBetweenRetries.
 	SshAgent                 *ssh.SSHAgent     //nolint:revive,staticcheck // preserving deprecated field name. Overrides local SSH agent with the given in-process agent
 	TerraformBinary          string            // Name of the binary that will be used
 	TerraformDir             string            // The path to the folder where the Terraform code is defined.
@@ -135,6 +137,9 @@ func (options *Options) Clone() (*Options, error) {
 
 	newOptions.MixedVars = append(newOptions.MixedVars, options.MixedVars...)
 
+	// Policies are not modified while retrying, so they can be shared.
+	newOptions.RetryPolicy = options.RetryPolicy
+
 	return newOptions, nil
 }
 
@@ -161,3 +166,12 @@ func WithDefaultRetryableErrors(t testing.TestingT, originalOptions *Options) *O
 
 	return newOptions
 }
+
+// retryPolicy returns the policy to retry errors matching RetryableTerraformErrors with.
+func (options *Options) retryPolicy() *retry.Policy {
+	if options.RetryPolicy != nil {
+		return options.RetryPolicy
+	}
+
+	return retry.ConstantPolicy(options.MaxRetries, options.TimeBetweenRetries)
+}
@@ -346,20 +346,49 @@ func OutputJSONContext(t testing.TestingT, ctx context.Context, options *Options
 // If key is an empty string, it will return all the output variables. The context argument can be used for
 // cancellation or timeout control.
 func OutputJSONContextE(t testing.TestingT, ctx context.Context, options *Options, key string) (string, error) {
-	args := []string{"output", "-no-color", "-json"}
+	// Read all the outputs without logging them first, so that the values of sensitive outputs are redacted from the
+	// logs
+	allOutputs, err := registerSensitiveOutputs(t, ctx, options)
+	if err != nil {
+		return allOutputs, err
+	}
 
-	args = append(args, options.ExtraArgs.Output...)
+	if key == "" {
+		logOutputJSON(t, options, allOutputs)
 
-	if key != "" {
-		args = append(args, key)
+		return allOutputs, nil
 	}
 
-	rawJSON, err := RunTerraformCommandAndGetStdoutContextE(t, ctx, options, args...)
+	// Pick the output out of the ones read above rather than running terraform output a second time
+	outputs := map[string]struct {
+		Value any `json:"value"`
+	}{}
+
+	if err := json.Unmarshal([]byte(allOutputs), &outputs); err != nil {
+		return "", err
+	}
+
+	output, ok := outputs[key]
+	if !ok {
+		return "", OutputKeyNotFound(key)
+	}
+
+	value, err := json.MarshalIndent(output.Value, "", "  ")
 	if err != nil {
-		return rawJSON, err
+		return "", err
 	}
 
-	return cleanJSON(rawJSON)
+	logOutputJSON(t, options, string(value))
+
+	return string(value), nil
+}
+
+// logOutputJSON logs the given JSON read from terraform output line by line, so that the values of sensitive outputs
+// are redacted.
+func logOutputJSON(t testing.TestingT, options *Options, outputJSON string) {
+	for line := range strings.SplitSeq(outputJSON, "\n") {
+		options.Logger.Logf(t, "%s", line)
+	}
 }
 
 // OutputStructContext calls terraform output for the given variable and stores the result in the value
@@ -0,0 +1,176 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+var (
+	variableBlockSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "variable", LabelNames: []string{"name"}}},
+	}
+	sensitiveAttributeSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "sensitive"}},
+	}
+)
+
+// registerSensitiveVars registers the values of the variables that the Terraform code in options.TerraformDir declares
+// as sensitive, set through Vars or TF_VAR_ environment variables, as secrets with the logger, so that they are
+// redacted from the logs of the command line and the output of Terraform. Secrets are registered globally, for the rest
+// of the test run.
+func registerSensitiveVars(options *Options) {
+	if options.TerraformDir == "" || (len(options.Vars) == 0 && len(options.EnvVars) == 0) {
+		return
+	}
+
+	for _, name := range sensitiveVariableNames(options.TerraformDir) {
+		if value, ok := options.Vars[name]; ok {
+			logger.RegisterSecrets(secretStrings(value)...)
+		}
+
+		if value, ok := options.EnvVars["TF_VAR_"+name]; ok {
+			logger.RegisterSecrets(value)
+		}
+	}
+}
+
+// sensitiveVariableNames returns the names of the variables declared with `sensitive = true` in the .tf files of the
+// given folder. Files that can't be parsed are skipped, as Terraform itself reports those errors.
+func sensitiveVariableNames(dir string) []string {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil
+	}
+
+	parser := hclparse.NewParser()
+
+	var names []string
+
+	for _, path := range paths {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			continue
+		}
+
+		content, _, _ := file.Body.PartialContent(variableBlockSchema)
+
+		for _, block := range content.Blocks {
+			attributes, _, _ := block.Body.PartialContent(sensitiveAttributeSchema)
+
+			attribute, ok := attributes.Attributes["sensitive"]
+			if !ok {
+				continue
+			}
+
+			value, diags := attribute.Expr.Value(nil)
+			if !diags.HasErrors() && value.Type() == cty.Bool && value.IsKnown() && !value.IsNull() && value.True() {
+				names = append(names, block.Labels[0])
+			}
+		}
+	}
+
+	return names
+}
+
+// registerSensitiveOutputs reads all the outputs without logging them, and registers the values of the sensitive ones
+// as secrets with the logger. Returns the cleaned JSON of all the outputs.
+func registerSensitiveOutputs(t testing.TestingT, ctx context.Context, options *Options) (string, error) {
+	silentOptions := *options
+	silentOptions.Logger = logger.Discard
+
+	args := append([]string{"output", "-no-color", "-json"}, options.ExtraArgs.Output...)
+
+	rawJSON, err := RunTerraformCommandAndGetStdoutContextE(t, ctx, &silentOptions, args...)
+	if err != nil {
+		return rawJSON, err
+	}
+
+	cleaned, err := cleanJSON(rawJSON)
+	if err != nil {
+		return "", err
+	}
+
+	outputs := map[string]struct {
+		Value     any  `json:"value"`
+		Sensitive bool `json:"sensitive"`
+	}{}
+
+	if err := json.Unmarshal([]byte(cleaned), &outputs); err != nil {
+		return "", err
+	}
+
+	for _, output := range outputs {
+		if output.Sensitive {
+			logger.RegisterSecrets(secretStrings(output.Value)...)
+		}
+	}
+
+	return cleaned, nil
+}
+
+// minNestedSecretLength is the minimum length of the strings nested in a sensitive list or map that are redacted. As the
+// logger redacts registered secrets globally, from the logs of every test, the shorter strings, such as the "true" or
+// "us-east-1" that a sensitive map may contain alongside the actual secrets, would make unrelated logs unreadable.
+const minNestedSecretLength = 10
+
+// secretStrings returns the strings to redact for a variable or output value: the value itself for strings, or the
+// strings nested in lists and maps that are at least minNestedSecretLength long.
+func secretStrings(value any) []string {
+	if value, ok := value.(string); ok {
+		return []string{value}
+	}
+
+	var values []string
+
+	for _, item := range nestedStrings(value) {
+		if len(item) >= minNestedSecretLength {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
+
+// nestedStrings returns all the strings nested in the given lists and maps.
+func nestedStrings(value any) []string {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{value}
+	case []any:
+		var values []string
+		for _, item := range value {
+			values = append(values, nestedStrings(item)...)
+		}
+
+		return values
+	case map[string]any:
+		var values []string
+		for _, item := range value {
+			values = append(values, nestedStrings(item)...)
+		}
+
+		return values
+	case []string:
+		return value
+	case map[string]string:
+		var values []string
+		for _, item := range value {
+			values = append(values, item)
+		}
+
+		return values
+	default:
+		// Numbers and booleans are not redacted, as replacing them everywhere would make the logs unreadable
+		return nil
+	}
+}
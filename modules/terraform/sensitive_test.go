@@ -0,0 +1,66 @@
+package terraform_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensitiveVarsAreRedacted(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	code := `
+variable "db_password" {
+  type      = string
+  sensitive = true
+}
+
+variable "api_keys" {
+  type      = map(string)
+  sensitive = true
+}
+
+variable "pin" {
+  type      = string
+  sensitive = true
+}
+
+variable "env_token" {
+  sensitive = true
+}
+
+variable "region" {
+  type = string
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(code), 0644))
+
+	unique := random.UniqueID()
+	password, apiKey, token, region := "password-"+unique, "api-key-"+unique, "token-"+unique, "region-"+unique
+	pin := "p" + unique[:5]
+
+	terraform.GetCommonOptions(&terraform.Options{
+		TerraformDir: dir,
+		Vars: map[string]any{
+			"db_password": password,
+			"api_keys":    map[string]any{"primary": apiKey, "region": "us-east-1"},
+			"pin":         pin,
+			"region":      region,
+		},
+		EnvVars: map[string]string{"TF_VAR_env_token": token},
+	})
+
+	assert.Equal(t, logger.Redacted, logger.Redact(password))
+	assert.Equal(t, logger.Redacted, logger.Redact(apiKey))
+	assert.Equal(t, logger.Redacted, logger.Redact(token))
+	assert.Equal(t, logger.Redacted, logger.Redact(pin))
+	assert.Equal(t, region, logger.Redact(region))
+	assert.Equal(t, "us-east-1", logger.Redact("us-east-1"))
+}
@@ -59,13 +59,12 @@ func executeTerragruntCommand(t testing.TestingT, ctx context.Context, opts *Opt
 	commandDescription := fmt.Sprintf("%s %v", opts.TerragruntBinary, finalArgs)
 
 	// Execute the command with retry logic and error handling
-	return retry.DoWithRetryableErrorsContextE(
+	return retry.DoWithRetryableErrorsPolicyContextE(
 		t,
 		ctx,
 		commandDescription,
 		opts.RetryableTerraformErrors,
-		opts.MaxRetries,
-		opts.TimeBetweenRetries,
+		retryPolicy(opts),
 		func() (string, error) {
 			output, err := shell.RunCommandContextAndGetOutputE(t, ctx, &execCommand)
 			if err != nil {
@@ -82,6 +81,15 @@ func executeTerragruntCommand(t testing.TestingT, ctx context.Context, opts *Opt
 	)
 }
 
+// retryPolicy returns the policy to retry errors matching opts.RetryableTerraformErrors with.
+func retryPolicy(opts *Options) *retry.Policy {
+	if opts.RetryPolicy != nil {
+		return opts.RetryPolicy
+	}
+
+	return retry.ConstantPolicy(opts.MaxRetries, opts.TimeBetweenRetries)
+}
+
 // HasWarning checks if the command output contains any warnings that should be treated as errors.
 // It uses regex patterns defined in opts.WarningsAsErrors to match warning messages.
 func HasWarning(opts *Options, commandOutput string) error {
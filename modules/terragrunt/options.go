@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
 )
 
 // Key concepts:
@@ -66,7 +67,8 @@ type Options struct {
 	WarningsAsErrors         map[string]string // Warnings to treat as errors
 
 	// Test framework configuration (NOT passed to tg command line)
-	Logger *logger.Logger // Logger for command output
+	Logger      *logger.Logger // Logger for command output
+	RetryPolicy *retry.Policy  // Retry policy for RetryableTerraformErrors, overrides MaxRetries and TimeBetweenRetries if set
 
 	// Complex configuration that requires special formatting (NOT raw command-line args)
 	BackendConfig map[string]interface{} // Backend configuration (formatted specially)
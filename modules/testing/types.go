@@ -30,3 +30,11 @@ type TestingT interface {
 	// When printing file and line information, that function will be skipped.
 	Helper()
 }
+
+// Cleaner is implemented by testing objects that can register functions to run when the test finishes, like testing.T
+// does. Terratest functions that start long-lived resources check whether their TestingT is also a Cleaner to clean
+// those resources up automatically.
+type Cleaner interface {
+	// Cleanup registers a function to be called when the test and all its subtests complete.
+	Cleanup(f func())
+}